@@ -8,6 +8,7 @@ import (
 	"github.com/fabric8-services/fabric8-auth/goasupport"
 	"github.com/fabric8-services/fabric8-auth/log"
 	"github.com/fabric8-services/fabric8-toggles-service/auth/client"
+	"github.com/fabric8-services/fabric8-toggles-service/tracing"
 	goaclient "github.com/goadesign/goa/client"
 	goajwt "github.com/goadesign/goa/middleware/security/jwt"
 )
@@ -43,7 +44,8 @@ func NewClient(ctx context.Context, authURL string, options ...ClientConfigOptio
 	for _, configure := range options {
 		configure(&clientConfig)
 	}
-	c := client.New(goaclient.HTTPClientDoer(clientConfig.httpClient))
+	// propagate the trace context to the auth service, along with the request ID
+	c := client.New(goaclient.HTTPClientDoer(tracing.WrapClient(clientConfig.httpClient)))
 	c.Host = u.Host
 	c.Scheme = u.Scheme
 	// allow requests with no JWT in the context
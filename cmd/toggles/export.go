@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+)
+
+// runExport writes all the features of the repository, with their strategies and parameters, to a versioned JSON or YAML document
+func runExport(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var src source
+	src.addFlags(flags)
+	format := flags.String("format", "json", "output format: 'json' or 'yaml'")
+	output := flags.String("o", "", "path to the output file (default: the standard output)")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	exportFormat, err := featuretoggles.ParseExportFormat(*format)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	features, err := src.load(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: unable to create the output file: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := featuretoggles.WriteExport(w, featuretoggles.NewExport(features), exportFormat); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunExportImport(t *testing.T) {
+	// given
+	path := writeFeaturesFile(t, `{"version":1,"features":[
+		{"name":"planner","enabled":true,"strategies":[{"name":"enableByLevel","parameters":{"level":"beta"}}]},
+		{"name":"deploy","enabled":false,"strategies":[{"name":"default"}]}]}`)
+	defer os.Remove(path)
+	dir, err := ioutil.TempDir("", "export")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	exportPath := filepath.Join(dir, "features.yaml")
+
+	t.Run("export", func(t *testing.T) {
+		// given
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"export", "-file", path, "-format", "yaml", "-o", exportPath}, stdout, stderr)
+		// then
+		require.Equal(t, exitOK, code, stderr.String())
+		f, err := os.Open(exportPath)
+		require.NoError(t, err)
+		defer f.Close()
+		export, err := featuretoggles.ReadExport(f, featuretoggles.YAMLFormat)
+		require.NoError(t, err)
+		require.Len(t, export.Features, 2)
+		assert.Equal(t, "deploy", export.Features[0].Name)
+		assert.Equal(t, "planner", export.Features[1].Name)
+	})
+
+	t.Run("import in a features file", func(t *testing.T) {
+		// given
+		out := filepath.Join(dir, "features.json")
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"import", "-in", exportPath, "-out", out}, stdout, stderr)
+		// then
+		require.Equal(t, exitOK, code, stderr.String())
+		f, err := os.Open(out)
+		require.NoError(t, err)
+		defer f.Close()
+		s, err := featuretoggles.ReadSnapshot(f)
+		require.NoError(t, err)
+		assert.Len(t, s.Features(), 2)
+	})
+
+	t.Run("import in the Unleash server", func(t *testing.T) {
+		// given
+		requests := []string{}
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			if req.Method == http.MethodGet {
+				json.NewEncoder(rw).Encode(map[string]interface{}{
+					"features": []map[string]interface{}{
+						{"name": "deploy", "enabled": false, "strategies": []map[string]interface{}{{"name": "default"}}},
+					},
+				})
+			}
+		}))
+		defer server.Close()
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"import", "-in", exportPath, "-url", server.URL + "/api", "-token", "secret", "-apply"}, stdout, stderr)
+		// then
+		require.Equal(t, exitOK, code, stderr.String())
+		assert.Contains(t, stdout.String(), "1 to create, 0 to update, 0 to archive")
+		assert.Equal(t, []string{"GET /api/admin/features", "POST /api/admin/features"}, requests)
+	})
+
+	t.Run("missing target", func(t *testing.T) {
+		// given
+		defer setEnv(t, "F8_TOGGLES_URL", "")()
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"import", "-in", exportPath}, stdout, stderr)
+		// then
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stderr.String(), "missing features file or Unleash API URL")
+	})
+}
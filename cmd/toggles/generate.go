@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuregen"
+)
+
+// runGenerate generates a Go package with typed constants for the features of the repository. With `-check`, only
+// verifies that the given file is up-to-date (e.g., in a CI pipeline, to catch the removed features) and exits with `1` otherwise.
+func runGenerate(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("generate", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var src source
+	src.addFlags(flags)
+	pkg := flags.String("pkg", "features", "name of the generated package")
+	output := flags.String("o", "", "path to the generated file (default: the standard output)")
+	check := flags.Bool("check", false, "check that the generated file is up-to-date instead of writing it")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if *check && *output == "" {
+		fmt.Fprintln(stderr, "error: missing generated file to check")
+		return exitError
+	}
+	features, err := src.load(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	var buf bytes.Buffer
+	if err := featuregen.Generate(&buf, *pkg, features); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	switch {
+	case *check:
+		current, err := ioutil.ReadFile(*output)
+		if err != nil && !os.IsNotExist(err) {
+			fmt.Fprintf(stderr, "error: unable to read the generated file: %v\n", err)
+			return exitError
+		}
+		if !bytes.Equal(current, buf.Bytes()) {
+			fmt.Fprintf(stdout, "'%s' is out of date: run 'toggles generate' again\n", *output)
+			return exitIssue
+		}
+	case *output != "":
+		if err := ioutil.WriteFile(*output, buf.Bytes(), 0644); err != nil {
+			fmt.Fprintf(stderr, "error: unable to write the generated file: %v\n", err)
+			return exitError
+		}
+	default:
+		stdout.Write(buf.Bytes())
+	}
+	return exitOK
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunGenerate(t *testing.T) {
+	// given
+	path := writeFeaturesFile(t, `{"version":1,"features":[
+		{"name":"planner.board","description":"The new planner board","enabled":true,"strategies":[{"name":"default"}]}]}`)
+	defer os.Remove(path)
+	dir, err := ioutil.TempDir("", "generate")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "features.go")
+
+	t.Run("generate", func(t *testing.T) {
+		// given
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"generate", "-file", path, "-o", output}, stdout, stderr)
+		// then
+		require.Equal(t, exitOK, code, stderr.String())
+		source, err := ioutil.ReadFile(output)
+		require.NoError(t, err)
+		assert.Contains(t, string(source), `PlannerBoard Name = "planner.board"`)
+	})
+
+	t.Run("check up-to-date", func(t *testing.T) {
+		// given
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"generate", "-file", path, "-o", output, "-check"}, stdout, stderr)
+		// then
+		assert.Equal(t, exitOK, code, stderr.String())
+	})
+
+	t.Run("check out of date", func(t *testing.T) {
+		// given the feature was removed
+		removed := writeFeaturesFile(t, `{"version":1,"features":[]}`)
+		defer os.Remove(removed)
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"generate", "-file", removed, "-o", output, "-check"}, stdout, stderr)
+		// then
+		assert.Equal(t, exitIssue, code, stderr.String())
+		assert.Contains(t, stdout.String(), "is out of date")
+	})
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/gitops"
+	errs "github.com/pkg/errors"
+)
+
+// importOptions the flags of the import command
+type importOptions struct {
+	in     string
+	format string
+	out    string
+	url    string
+	token  string
+	apply  bool
+	prune  bool
+}
+
+// runImport imports an exported feature repository in an Unleash server (shows the changes, or applies them), or writes it
+// in a features file which can be used as a snapshot or as the backup file of the unleash client
+func runImport(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	opts := importOptions{}
+	flags.StringVar(&opts.in, "in", "", "path to the exported repository ('-' for the standard input) (required)")
+	flags.StringVar(&opts.format, "format", "", "format of the exported repository: 'json' or 'yaml' (default: from the file extension, or 'json')")
+	flags.StringVar(&opts.out, "out", "", "path to the features file to write, instead of importing in the Unleash server")
+	flags.StringVar(&opts.url, "url", os.Getenv("F8_TOGGLES_URL"), "URL of the Unleash API (default: $F8_TOGGLES_URL)")
+	flags.StringVar(&opts.token, "token", os.Getenv("F8_TOGGLES_ADMIN_TOKEN"), "admin API token of the Unleash server (default: $F8_TOGGLES_ADMIN_TOKEN)")
+	flags.BoolVar(&opts.apply, "apply", false, "apply the changes in the Unleash server (dry-run otherwise)")
+	flags.BoolVar(&opts.prune, "prune", false, "archive the features which are not in the exported repository")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if opts.in == "" {
+		fmt.Fprintln(stderr, "error: missing exported repository")
+		return exitError
+	}
+	if opts.out == "" && opts.url == "" {
+		fmt.Fprintln(stderr, "error: missing features file or Unleash API URL")
+		return exitError
+	}
+	export, err := readExportFile(opts.in, opts.format)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	if opts.out != "" {
+		if err := saveFeaturesFile(opts.out, export); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(stdout, "%d feature(s) written in '%s'\n", len(export.Features), opts.out)
+		return exitOK
+	}
+	desired := make([]gitops.Feature, len(export.Features))
+	for i, f := range export.Features {
+		desired[i] = gitops.FromUnleashFeature(f)
+	}
+	ctx := context.Background()
+	client := gitops.NewAdminClient(opts.url, gitops.WithAdminToken(opts.token))
+	current, err := client.Features(ctx)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	plan := gitops.ComputeFeaturePlan(desired, current, opts.prune)
+	fmt.Fprint(stdout, plan.String())
+	if opts.apply && !plan.IsEmpty() {
+		if err := client.Apply(ctx, plan); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintln(stdout, "applied")
+	}
+	return exitOK
+}
+
+// readExportFile reads the exported repository at the given path, in the given format or in the format matching the file extension
+func readExportFile(path, format string) (featuretoggles.Export, error) {
+	if format == "" {
+		format = string(featuretoggles.JSONFormat)
+		if ext := strings.ToLower(filepath.Ext(path)); ext == ".yaml" || ext == ".yml" {
+			format = string(featuretoggles.YAMLFormat)
+		}
+	}
+	exportFormat, err := featuretoggles.ParseExportFormat(format)
+	if err != nil {
+		return featuretoggles.Export{}, err
+	}
+	if path == "-" {
+		return featuretoggles.ReadExport(os.Stdin, exportFormat)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return featuretoggles.Export{}, errs.Wrapf(err, "unable to open the exported repository '%s'", path)
+	}
+	defer f.Close()
+	return featuretoggles.ReadExport(f, exportFormat)
+}
+
+// saveFeaturesFile writes the features in the JSON format, which is also the format of the backup file of the unleash client
+func saveFeaturesFile(path string, export featuretoggles.Export) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return errs.Wrapf(err, "unable to create the features file '%s'", path)
+	}
+	defer f.Close()
+	return featuretoggles.WriteExport(f, export, featuretoggles.JSONFormat)
+}
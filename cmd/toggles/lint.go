@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/fabric8-services/fabric8-toggles-service/lint"
+)
+
+// runLint reports the problems in the feature definitions. Exits with `1` if any problem was found.
+func runLint(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var src source
+	src.addFlags(flags)
+	format := flags.String("format", "human", "output format: 'human' or 'json'")
+	domains := flags.String("internal-domains", os.Getenv("F8_INTERNAL_EMAIL_DOMAINS"), "comma-separated domains of the email addresses of the internal users (default: redhat.com)")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if *format != "human" && *format != "json" {
+		fmt.Fprintf(stderr, "invalid format '%s' (expected 'human' or 'json')\n", *format)
+		return exitError
+	}
+	features, err := src.load(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	options := lint.Options{}
+	for _, domain := range strings.Split(*domains, ",") {
+		if domain = strings.TrimSpace(domain); domain != "" {
+			options.InternalEmailDomains = append(options.InternalEmailDomains, domain)
+		}
+	}
+	issues := lint.Lint(features, options)
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+	default:
+		for _, issue := range issues {
+			fmt.Fprintln(stdout, issue.String())
+		}
+		fmt.Fprintf(stdout, "%d feature(s) checked, %d problem(s) found\n", len(features), len(issues))
+	}
+	if len(issues) > 0 {
+		return exitIssue
+	}
+	return exitOK
+}
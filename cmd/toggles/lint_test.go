@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-toggles-service/lint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFeaturesFile(t *testing.T, content string) string {
+	f, err := ioutil.TempFile("", "features")
+	require.NoError(t, err)
+	_, err = f.WriteString(content)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	return f.Name()
+}
+
+func TestRunLint(t *testing.T) {
+
+	t.Run("no issue", func(t *testing.T) {
+		// given
+		path := writeFeaturesFile(t, `{"version":1,"features":[
+			{"name":"planner","enabled":true,"strategies":[{"name":"enableByLevel","parameters":{"level":"beta"}}]}]}`)
+		defer os.Remove(path)
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"lint", "-file", path}, stdout, stderr)
+		// then
+		assert.Equal(t, exitOK, code)
+		assert.Equal(t, "1 feature(s) checked, 0 problem(s) found\n", stdout.String())
+	})
+
+	t.Run("issues as json", func(t *testing.T) {
+		// given
+		path := writeFeaturesFile(t, `{"version":1,"features":[
+			{"name":"planner","enabled":true,"strategies":[{"name":"enableByLevel","parameters":{"level":"beat"}}]}]}`)
+		defer os.Remove(path)
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"lint", "-file", path, "-format", "json"}, stdout, stderr)
+		// then
+		assert.Equal(t, exitIssue, code)
+		var issues []lint.Issue
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &issues))
+		require.Len(t, issues, 1)
+		assert.Equal(t, "planner", issues[0].Feature)
+		assert.Equal(t, lint.StrategyRule, issues[0].Rule)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+
+		t.Run("missing source", func(t *testing.T) {
+			// given
+			defer setEnv(t, "F8_TOGGLES_URL", "")()
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			// when
+			code := run([]string{"lint"}, stdout, stderr)
+			// then
+			assert.Equal(t, exitError, code)
+			assert.Contains(t, stderr.String(), "missing features file or Unleash API URL")
+		})
+
+		t.Run("unknown command", func(t *testing.T) {
+			// given
+			stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+			// when
+			code := run([]string{"fix"}, stdout, stderr)
+			// then
+			assert.Equal(t, exitError, code)
+			assert.Contains(t, stderr.String(), "unknown command 'fix'")
+		})
+	})
+}
+
+func setEnv(t *testing.T, key, value string) func() {
+	previous, found := os.LookupEnv(key)
+	require.NoError(t, os.Setenv(key, value))
+	return func() {
+		if found {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
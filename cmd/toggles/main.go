@@ -0,0 +1,87 @@
+// Command toggles provides the tools to manage the feature definitions of the Unleash server used by the toggles service.
+//
+// Usage:
+//
+//	toggles <command> [flags]
+//
+// Run `toggles <command> -h` for the flags of a command.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// command a subcommand of the CLI, which returns the exit code of the process
+type command struct {
+	description string
+	run         func(args []string, stdout, stderr io.Writer) int
+}
+
+// commands the subcommands of the CLI, by name
+var commands = map[string]command{
+	"export": {
+		description: "export all the features to a versioned JSON or YAML document",
+		run:         runExport,
+	},
+	"generate": {
+		description: "generate a Go package with typed constants for the features",
+		run:         runGenerate,
+	},
+	"import": {
+		description: "import an exported feature repository in the Unleash server or in a features file",
+		run:         runImport,
+	},
+	"lint": {
+		description: "report the problems in the feature definitions",
+		run:         runLint,
+	},
+	"stale": {
+		description: "report the stale features and notify their owners",
+		run:         runStale,
+	},
+	"sync": {
+		description: "synchronize the features of the Unleash server with the feature files",
+		run:         runSync,
+	},
+}
+
+// exit codes
+const (
+	exitOK    = 0
+	exitIssue = 1
+	exitError = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitError
+	}
+	cmd, found := commands[args[0]]
+	if !found {
+		fmt.Fprintf(stderr, "unknown command '%s'\n", args[0])
+		usage(stderr)
+		return exitError
+	}
+	return cmd.run(args[1:], stdout, stderr)
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: toggles <command> [flags]")
+	fmt.Fprintln(w, "commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-10s %s\n", name, commands[name].description)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	errs "github.com/pkg/errors"
+)
+
+// source the location of the feature repository: a file in the format of the `/api/client/features` endpoint of the
+// Unleash server (or its backup file), or the URL of the Unleash API
+type source struct {
+	file  string
+	url   string
+	token string
+}
+
+// addFlags registers the flags of the source on the given flag set
+func (s *source) addFlags(flags *flag.FlagSet) {
+	flags.StringVar(&s.file, "file", "", "path to the features file ('-' for the standard input)")
+	flags.StringVar(&s.url, "url", os.Getenv("F8_TOGGLES_URL"), "URL of the Unleash API, if no file is given (default: $F8_TOGGLES_URL)")
+	flags.StringVar(&s.token, "token", os.Getenv("F8_TOGGLES_API_TOKEN"), "API token of the Unleash server (default: $F8_TOGGLES_API_TOKEN)")
+}
+
+// load returns the features of the repository
+func (s *source) load(stdin io.Reader) ([]unleashapi.Feature, error) {
+	switch {
+	case s.file == "-":
+		return readFeatures(stdin)
+	case s.file != "":
+		f, err := os.Open(s.file)
+		if err != nil {
+			return nil, errs.Wrapf(err, "unable to open the features file '%s'", s.file)
+		}
+		defer f.Close()
+		return readFeatures(f)
+	case s.url != "":
+		return s.fetch()
+	default:
+		return nil, errs.New("missing features file or Unleash API URL")
+	}
+}
+
+func (s *source) fetch() ([]unleashapi.Feature, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(s.url, "/")+"/client/features", nil)
+	if err != nil {
+		return nil, errs.Wrapf(err, "invalid Unleash API URL '%s'", s.url)
+	}
+	if s.token != "" {
+		req.Header.Set("Authorization", s.token)
+	}
+	client := http.Client{Timeout: 30 * time.Second}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, errs.Wrap(err, "unable to fetch the features")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errs.Errorf("unable to fetch the features: unexpected status '%s'", res.Status)
+	}
+	return readFeatures(res.Body)
+}
+
+func readFeatures(r io.Reader) ([]unleashapi.Feature, error) {
+	snapshot, err := featuretoggles.ReadSnapshot(r)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Features(), nil
+}
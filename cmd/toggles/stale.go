@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/gitops"
+	"github.com/fabric8-services/fabric8-toggles-service/stale"
+	errs "github.com/pkg/errors"
+)
+
+// runStale reports the stale features, and optionally notifies their owners. Exits with `1` if any stale feature was found.
+func runStale(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("stale", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	var src source
+	src.addFlags(flags)
+	dir := flags.String("dir", "", "directory of the feature files with the metadata of the features (owner, type, creation and expiry dates)")
+	metricsURL := flags.String("metrics-url", "", "URL of the metrics endpoint of the toggles service, to report the features which are not evaluated (e.g. 'http://toggles:8080/metrics')")
+	releasedDays := flags.Int("released-days", 90, "number of days after which a feature released to everyone is stale (0 to disable)")
+	unusedDays := flags.Int("unused-days", 30, "number of days after which a feature which was not evaluated is stale (0 to disable)")
+	format := flags.String("format", "human", "output format: 'human' or 'json'")
+	notifyURL := flags.String("notify-url", "", "webhook URL to which a notification is posted for each owner of stale features")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if *format != "human" && *format != "json" {
+		fmt.Fprintf(stderr, "invalid format '%s' (expected 'human' or 'json')\n", *format)
+		return exitError
+	}
+	features, err := src.load(os.Stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	metadata := map[string]featuretoggles.Metadata{}
+	if *dir != "" {
+		if metadata, err = gitops.LoadMetadata(*dir); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+	}
+	options := stale.Options{
+		ReleasedFor: time.Duration(*releasedDays) * 24 * time.Hour,
+	}
+	if *metricsURL != "" {
+		if options.LastEvaluations, options.EvaluationsSince, err = fetchEvaluations(*metricsURL); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		options.UnusedFor = time.Duration(*unusedDays) * 24 * time.Hour
+	}
+	issues := stale.Report(features, metadata, options)
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(issues); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+	default:
+		for _, issue := range issues {
+			fmt.Fprintln(stdout, issue.String())
+		}
+		fmt.Fprintf(stdout, "%d feature(s) checked, %d stale feature issue(s) found\n", len(features), len(issues))
+	}
+	if *notifyURL != "" && len(issues) > 0 {
+		owners, err := stale.NewNotifier(*notifyURL).Notify(context.Background(), issues)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintf(stderr, "%d owner(s) notified\n", len(owners))
+	}
+	if len(issues) > 0 {
+		return exitIssue
+	}
+	return exitOK
+}
+
+var (
+	lastEvaluationPattern = regexp.MustCompile(`^toggles_feature_last_evaluation_timestamp_seconds\{feature="((?:[^"\\]|\\.)*)"\} (\S+)`)
+	startTimePattern      = regexp.MustCompile(`^process_start_time_seconds (\S+)`)
+)
+
+// fetchEvaluations returns the time of the last evaluation of the features and the start time of the toggles service,
+// as reported by its metrics endpoint (in the Prometheus text format)
+func fetchEvaluations(url string) (map[string]time.Time, time.Time, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+	res, err := client.Get(url)
+	if err != nil {
+		return nil, time.Time{}, errs.Wrap(err, "unable to fetch the metrics")
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, time.Time{}, errs.Errorf("unable to fetch the metrics: unexpected status '%s'", res.Status)
+	}
+	evaluations := map[string]time.Time{}
+	var since time.Time
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if m := lastEvaluationPattern.FindStringSubmatch(line); m != nil {
+			feature, err := strconv.Unquote(`"` + m[1] + `"`)
+			if err != nil {
+				continue
+			}
+			if t, ok := parseTimestamp(m[2]); ok {
+				evaluations[feature] = t
+			}
+		} else if m := startTimePattern.FindStringSubmatch(line); m != nil {
+			if t, ok := parseTimestamp(m[1]); ok {
+				since = t
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, time.Time{}, errs.Wrap(err, "unable to read the metrics")
+	}
+	if since.IsZero() {
+		return nil, time.Time{}, errs.New("unable to read the metrics: missing start time of the toggles service")
+	}
+	return evaluations, since, nil
+}
+
+func parseTimestamp(value string) (time.Time, bool) {
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(seconds), 0), true
+}
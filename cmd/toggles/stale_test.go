@@ -0,0 +1,79 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-toggles-service/stale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunStale(t *testing.T) {
+	// given
+	path := writeFeaturesFile(t, `{"version":1,"features":[
+		{"name":"planner","enabled":true,"strategies":[{"name":"enableByLevel","parameters":{"level":"beta"}}]},
+		{"name":"analyze","enabled":true,"strategies":[{"name":"enableByLevel","parameters":{"level":"beta"}}]}]}`)
+	defer os.Remove(path)
+	dir, err := ioutil.TempDir("", "features")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "features.yaml"), []byte(`
+features:
+- name: planner
+  level: beta
+  owner: planner-team@foo.com
+  expires: 2018-01-01
+`), 0644)
+	require.NoError(t, err)
+	now := time.Now()
+	metrics := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		fmt.Fprintf(rw, "# TYPE toggles_feature_last_evaluation_timestamp_seconds gauge\n")
+		fmt.Fprintf(rw, "toggles_feature_last_evaluation_timestamp_seconds{feature=\"planner\"} %d\n", now.Unix())
+		fmt.Fprintf(rw, "toggles_feature_last_evaluation_timestamp_seconds{feature=\"analyze\"} %e\n", float64(now.Add(-40*24*time.Hour).Unix()))
+		fmt.Fprintf(rw, "process_start_time_seconds %e\n", float64(now.Add(-60*24*time.Hour).Unix()))
+	}))
+	defer metrics.Close()
+
+	t.Run("report and notify", func(t *testing.T) {
+		// given
+		notified := []string{}
+		webhook := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+			var n stale.Notification
+			require.NoError(t, json.NewDecoder(req.Body).Decode(&n))
+			notified = append(notified, n.Owner)
+		}))
+		defer webhook.Close()
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"stale", "-file", path, "-dir", dir, "-metrics-url", metrics.URL, "-notify-url", webhook.URL, "-format", "json"}, stdout, stderr)
+		// then
+		assert.Equal(t, exitIssue, code, stderr.String())
+		var issues []stale.Issue
+		require.NoError(t, json.Unmarshal(stdout.Bytes(), &issues))
+		require.Len(t, issues, 2)
+		assert.Equal(t, "analyze", issues[0].Feature)
+		assert.Equal(t, stale.UnusedRule, issues[0].Rule)
+		assert.Equal(t, "planner", issues[1].Feature)
+		assert.Equal(t, stale.ExpiredRule, issues[1].Rule)
+		assert.Equal(t, []string{"planner-team@foo.com"}, notified)
+	})
+
+	t.Run("no stale feature", func(t *testing.T) {
+		// given no metadata nor metrics
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		// when
+		code := run([]string{"stale", "-file", path}, stdout, stderr)
+		// then
+		assert.Equal(t, exitOK, code, stderr.String())
+		assert.Equal(t, "2 feature(s) checked, 0 stale feature issue(s) found\n", stdout.String())
+	})
+}
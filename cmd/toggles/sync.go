@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/fabric8-services/fabric8-toggles-service/gitops"
+)
+
+// syncOptions the flags of the sync command
+type syncOptions struct {
+	dir              string
+	url              string
+	token            string
+	apply            bool
+	prune            bool
+	format           string
+	interval         time.Duration
+	detailedExitCode bool
+	stateFile        string
+}
+
+// runSync computes the plan to synchronize the features of the Unleash server with the feature files and shows it
+// (dry-run), or applies it. With an interval, runs in the background until interrupted and reports the drift on each run,
+// i.e., the features changed in Unleash since they were last applied (as opposed to the definitions not applied yet).
+func runSync(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("sync", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	opts := syncOptions{}
+	flags.StringVar(&opts.dir, "dir", "", "directory of the feature files (required)")
+	flags.StringVar(&opts.url, "url", os.Getenv("F8_TOGGLES_URL"), "URL of the Unleash API (default: $F8_TOGGLES_URL)")
+	flags.StringVar(&opts.token, "token", os.Getenv("F8_TOGGLES_ADMIN_TOKEN"), "admin API token of the Unleash server (default: $F8_TOGGLES_ADMIN_TOKEN)")
+	flags.BoolVar(&opts.apply, "apply", false, "apply the plan (dry-run otherwise)")
+	flags.BoolVar(&opts.prune, "prune", false, "archive the features which are not defined in the feature files")
+	flags.StringVar(&opts.format, "format", "human", "output format: 'human' or 'json'")
+	flags.DurationVar(&opts.interval, "interval", 0, "run in the background and synchronize at the given interval (e.g. '1m')")
+	flags.BoolVar(&opts.detailedExitCode, "detailed-exitcode", false, "exit with 1 if the plan has changes (dry-run only)")
+	flags.StringVar(&opts.stateFile, "state-file", "", "file in which the state of the features last applied is kept, to report the drift across runs (in memory if empty)")
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if opts.dir == "" || opts.url == "" {
+		fmt.Fprintln(stderr, "error: missing feature files directory or Unleash API URL")
+		return exitError
+	}
+	if opts.format != "human" && opts.format != "json" {
+		fmt.Fprintf(stderr, "invalid format '%s' (expected 'human' or 'json')\n", opts.format)
+		return exitError
+	}
+	client := gitops.NewAdminClient(opts.url, gitops.WithAdminToken(opts.token))
+	state := gitops.AppliedState{}
+	if opts.stateFile != "" {
+		var err error
+		if state, err = gitops.LoadAppliedState(opts.stateFile); err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+	}
+	if opts.interval <= 0 {
+		plan, _, err := syncOnce(context.Background(), client, opts, state, stdout)
+		if err != nil {
+			fmt.Fprintf(stderr, "error: %v\n", err)
+			return exitError
+		}
+		if opts.detailedExitCode && !opts.apply && !plan.IsEmpty() {
+			return exitIssue
+		}
+		return exitOK
+	}
+	// background mode
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	defer signal.Stop(signals)
+	ticker := time.NewTicker(opts.interval)
+	defer ticker.Stop()
+	for {
+		_, drift, err := syncOnce(context.Background(), client, opts, state, stdout)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s error: %v\n", time.Now().Format(time.RFC3339), err)
+		}
+		for _, c := range drift {
+			fmt.Fprintf(stderr, "%s drift: feature '%s' was changed in Unleash since it was last applied\n", time.Now().Format(time.RFC3339), c.Name)
+		}
+		select {
+		case <-signals:
+			return exitOK
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncOnce computes the plan, prints it and applies it if requested. Returns the plan along with the drift, which is
+// computed against the given state before the state is updated with the current features (and the applied plan).
+func syncOnce(ctx context.Context, client *gitops.AdminClient, opts syncOptions, state gitops.AppliedState, stdout io.Writer) (gitops.Plan, []gitops.Change, error) {
+	definitions, err := gitops.LoadDefinitions(opts.dir)
+	if err != nil {
+		return gitops.Plan{}, nil, err
+	}
+	current, err := client.Features(ctx)
+	if err != nil {
+		return gitops.Plan{}, nil, err
+	}
+	plan := gitops.ComputePlan(definitions, current, opts.prune)
+	drift := plan.Drift(state)
+	if opts.format == "json" {
+		if err := json.NewEncoder(stdout).Encode(plan); err != nil {
+			return plan, drift, err
+		}
+	} else {
+		fmt.Fprint(stdout, plan.String())
+	}
+	applied := false
+	if opts.apply && !plan.IsEmpty() {
+		if err := client.Apply(ctx, plan); err != nil {
+			return plan, drift, err
+		}
+		applied = true
+		if opts.format != "json" {
+			fmt.Fprintln(stdout, "applied")
+		}
+	}
+	state.Record(plan, current, applied)
+	if opts.stateFile != "" {
+		if err := state.Save(opts.stateFile); err != nil {
+			return plan, drift, err
+		}
+	}
+	return plan, drift, nil
+}
@@ -0,0 +1,284 @@
+package configuration_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-toggles-service/configuration"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, content string) string {
+	dir, err := ioutil.TempDir("", "config")
+	require.NoError(t, err)
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func setEnv(t *testing.T, key, value string) func() {
+	previous, found := os.LookupEnv(key)
+	require.NoError(t, os.Setenv(key, value))
+	return func() {
+		if found {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	}
+}
+
+func TestNewData(t *testing.T) {
+
+	t.Run("from config file", func(t *testing.T) {
+		// given
+		path := writeConfigFile(t, `
+toggles.url: http://toggles/api
+auth.url: https://auth
+log.level: debug
+features.cachecontrol: private,max-age=60
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+		// when
+		config, err := configuration.NewData(path)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "http://toggles/api", config.GetTogglesURL())
+		assert.Equal(t, "https://auth", config.GetAuthServiceURL())
+		assert.Equal(t, "debug", config.GetLogLevel())
+		assert.Equal(t, "private,max-age=60", config.GetFeaturesCacheControl())
+		// default value
+		assert.Equal(t, "0.0.0.0:8080", config.GetHTTPAddress())
+	})
+
+	t.Run("env vars override config file", func(t *testing.T) {
+		// given
+		path := writeConfigFile(t, `
+toggles.url: http://toggles/api
+auth.url: https://auth
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+		defer setEnv(t, "F8_AUTH_URL", "https://other-auth")()
+		// when
+		config, err := configuration.NewData(path)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "http://toggles/api", config.GetTogglesURL())
+		assert.Equal(t, "https://other-auth", config.GetAuthServiceURL())
+	})
+
+	t.Run("from env var path", func(t *testing.T) {
+		// given
+		path := writeConfigFile(t, `
+toggles.url: http://toggles/api
+auth.url: https://auth
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+		defer setEnv(t, "F8_CONFIG_FILE_PATH", path)()
+		// when
+		config, err := configuration.GetData()
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "http://toggles/api", config.GetTogglesURL())
+	})
+
+	t.Run("missing config file", func(t *testing.T) {
+		// when
+		_, err := configuration.NewData("/path/to/unknown/config.yaml")
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to read the config file '/path/to/unknown/config.yaml'")
+	})
+}
+
+func TestTogglesSettings(t *testing.T) {
+
+	t.Run("from config file", func(t *testing.T) {
+		// given
+		path := writeConfigFile(t, `
+toggles.url: http://toggles/api
+auth.url: https://auth
+toggles.api.token: secret
+toggles.custom.headers:
+  X-Tenant: fabric8
+toggles.proxy.url: http://proxy:3128
+toggles.timeout: 5s
+toggles.instance.id: toggles-1
+toggles.admin.token: admin-secret
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+		// when
+		config, err := configuration.NewData(path)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, "secret", config.GetTogglesAPIToken())
+		assert.Equal(t, map[string]string{"x-tenant": "fabric8"}, config.GetTogglesCustomHeaders())
+		assert.Equal(t, "http://proxy:3128", config.GetTogglesProxyURL())
+		assert.Equal(t, 5*time.Second, config.GetTogglesTimeout())
+		assert.Equal(t, "toggles-1", config.GetTogglesInstanceID())
+		assert.Equal(t, "admin-secret", config.GetTogglesAdminToken())
+		// the tokens must not be printed
+		assert.NotContains(t, config.String(), "secret")
+	})
+
+	t.Run("from env vars and token file", func(t *testing.T) {
+		// given
+		path := writeConfigFile(t, `
+toggles.url: http://toggles/api
+auth.url: https://auth
+toggles.api.token: secret
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+		tokenFile := filepath.Join(filepath.Dir(path), "token")
+		require.NoError(t, ioutil.WriteFile(tokenFile, []byte("mounted-secret\n"), 0600))
+		defer setEnv(t, "F8_TOGGLES_API_TOKENFILE", tokenFile)()
+		defer setEnv(t, "F8_TOGGLES_CUSTOM_HEADERS", "X-Tenant=fabric8, X-Env=prod")()
+		config, err := configuration.NewData(path)
+		require.NoError(t, err)
+		// when
+		token := config.GetTogglesAPIToken()
+		// then
+		assert.Equal(t, "mounted-secret", token)
+		assert.Equal(t, map[string]string{"X-Tenant": "fabric8", "X-Env": "prod"}, config.GetTogglesCustomHeaders())
+		assert.Equal(t, 10*time.Second, config.GetTogglesTimeout())
+
+		t.Run("rotated token", func(t *testing.T) {
+			// given
+			require.NoError(t, ioutil.WriteFile(tokenFile, []byte("rotated-secret"), 0600))
+			// when
+			token := config.GetTogglesAPIToken()
+			// then
+			assert.Equal(t, "rotated-secret", token)
+		})
+	})
+
+	t.Run("invalid settings", func(t *testing.T) {
+		// given
+		path := writeConfigFile(t, `
+toggles.url: http://toggles/api
+auth.url: https://auth
+toggles.api.tokenfile: /path/to/unknown/token
+toggles.proxy.url: proxy:3128
+toggles.timeout: 0s
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+		defer setEnv(t, "F8_TOGGLES_CUSTOM_HEADERS", "X-Tenant")()
+		// when
+		_, err := configuration.NewData(path)
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "'toggles.api.tokenfile': unable to read the file '/path/to/unknown/token'")
+		assert.Contains(t, err.Error(), "'toggles.proxy.url': invalid URL 'proxy:3128'")
+		assert.Contains(t, err.Error(), "'toggles.timeout': invalid timeout")
+		assert.Contains(t, err.Error(), "'toggles.custom.headers': invalid entry 'X-Tenant'")
+	})
+}
+
+func TestValidate(t *testing.T) {
+
+	t.Run("missing URLs", func(t *testing.T) {
+		// given
+		path := writeConfigFile(t, `
+log.level: info
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+		// when
+		_, err := configuration.NewData(path)
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "'toggles.url': missing value")
+		assert.Contains(t, err.Error(), "'auth.url': missing value")
+	})
+
+	t.Run("malformed URLs and invalid log level", func(t *testing.T) {
+		// given
+		path := writeConfigFile(t, `
+toggles.url: toggles/api
+auth.url: ftp://auth
+log.level: verbose
+`)
+		defer os.RemoveAll(filepath.Dir(path))
+		// when
+		_, err := configuration.NewData(path)
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "'toggles.url': invalid URL 'toggles/api'")
+		assert.Contains(t, err.Error(), "'auth.url': invalid URL 'ftp://auth'")
+		assert.Contains(t, err.Error(), "'log.level': invalid log level 'verbose'")
+	})
+}
+
+func TestReload(t *testing.T) {
+	// given
+	path := writeConfigFile(t, `
+toggles.url: http://toggles/api
+auth.url: https://auth
+log.level: info
+features.cachecontrol: private,max-age=0
+`)
+	defer os.RemoveAll(filepath.Dir(path))
+	config, err := configuration.NewData(path)
+	require.NoError(t, err)
+	reloaded := 0
+	config.OnReload(func(*configuration.Data) {
+		reloaded++
+	})
+
+	t.Run("reloadable settings", func(t *testing.T) {
+		// given
+		require.NoError(t, ioutil.WriteFile(path, []byte(`
+toggles.url: http://toggles/api
+auth.url: https://auth
+log.level: debug
+features.cachecontrol: private,max-age=60
+internal.email.domains: redhat.com, ibm.com
+`), 0644))
+		// when
+		err := config.Reload()
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, reloaded)
+		assert.Equal(t, "debug", config.GetLogLevel())
+		assert.Equal(t, "private,max-age=60", config.GetFeaturesCacheControl())
+		assert.Equal(t, []string{"redhat.com", "ibm.com"}, config.GetInternalEmailDomains())
+	})
+
+	t.Run("settings requiring a restart", func(t *testing.T) {
+		// given
+		require.NoError(t, ioutil.WriteFile(path, []byte(`
+toggles.url: http://other-toggles/api
+auth.url: https://auth
+log.level: debug
+features.cachecontrol: private,max-age=60
+internal.email.domains: redhat.com, ibm.com
+`), 0644))
+		// when
+		err := config.Reload()
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, 1, reloaded)
+		assert.Equal(t, "http://toggles/api", config.GetTogglesURL())
+	})
+
+	t.Run("invalid configuration", func(t *testing.T) {
+		// given
+		require.NoError(t, ioutil.WriteFile(path, []byte(`
+toggles.url: http://toggles/api
+auth.url: https://auth
+log.level: verbose
+features.cachecontrol: no-store
+`), 0644))
+		// when
+		err := config.Reload()
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "'log.level': invalid log level 'verbose'")
+		assert.Equal(t, 1, reloaded)
+		assert.Equal(t, "debug", config.GetLogLevel())
+		assert.Equal(t, "private,max-age=60", config.GetFeaturesCacheControl())
+	})
+}
@@ -0,0 +1,158 @@
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"syscall"
+
+	"github.com/fsnotify/fsnotify"
+	errs "github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// reloadableKeys the settings which are applied without restarting the service
+var reloadableKeys = map[string]bool{
+	varFeaturesCacheControl: true,
+	varLogLevel:             true,
+	varAdminEmails:          true,
+	varInternalEmailDomains: true,
+	varTLSServiceIdentities: true,
+}
+
+// OnReload registers a function to call after the configuration was reloaded
+func (c *Data) OnReload(listener func(*Data)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, listener)
+}
+
+// Reload reads the configuration file and the environment variables again, then applies the changes of the reloadable
+// settings and notifies the registered listeners. Changes of the other settings are ignored until the service is restarted.
+// An invalid configuration is rejected and the current configuration is retained.
+func (c *Data) Reload() error {
+	next, err := newViper(c.configFilePath)
+	if err != nil {
+		return errs.Wrap(err, "rejected configuration reload")
+	}
+	if err := validate(next); err != nil {
+		return errs.Wrap(err, "rejected configuration reload")
+	}
+	c.mu.Lock()
+	current := c.v
+	changes := map[string]interface{}{}
+	ignored := []string{}
+	for _, key := range allKeys(current, next) {
+		before, after := current.Get(key), next.Get(key)
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+		if !reloadableKeys[key] {
+			// retain the current value until the service is restarted
+			next.Set(key, before)
+			ignored = append(ignored, key)
+			continue
+		}
+		changes[key] = fmt.Sprintf("'%v' -> '%v'", before, after)
+	}
+	c.v = next
+	listeners := make([]func(*Data), len(c.listeners))
+	copy(listeners, c.listeners)
+	c.mu.Unlock()
+
+	if len(ignored) > 0 {
+		log.WithField("keys", ignored).Warn("ignored configuration changes which require a restart")
+	}
+	if len(changes) == 0 {
+		log.Info("reloaded configuration: no change")
+		return nil
+	}
+	log.WithFields(log.Fields(changes)).Info("reloaded configuration")
+	for _, listener := range listeners {
+		listener(c)
+	}
+	return nil
+}
+
+// allKeys returns the sorted keys of the given configurations
+func allKeys(configs ...*viper.Viper) []string {
+	keys := map[string]bool{}
+	for _, v := range configs {
+		for _, key := range v.AllKeys() {
+			keys[key] = true
+		}
+	}
+	result := make([]string, 0, len(keys))
+	for key := range keys {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Watch reloads the configuration when the process receives a `SIGHUP` signal or when the configuration file changes
+// (including when it is replaced, as with a Kubernetes ConfigMap), until the returned function is called
+func (c *Data) Watch() (func(), error) {
+	var events chan fsnotify.Event
+	var watchErrors chan error
+	var watcher *fsnotify.Watcher
+	if c.configFilePath != "" {
+		var err error
+		watcher, err = fsnotify.NewWatcher()
+		if err != nil {
+			return nil, errs.Wrap(err, "unable to watch the config file")
+		}
+		// watch the parent directory, since the file may be replaced rather than modified
+		if err := watcher.Add(filepath.Dir(c.configFilePath)); err != nil {
+			watcher.Close()
+			return nil, errs.Wrapf(err, "unable to watch the config file '%s'", c.configFilePath)
+		}
+		events = watcher.Events
+		watchErrors = watcher.Errors
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+				c.reloadOnEvent("SIGHUP")
+			case e := <-events:
+				if c.isConfigFileEvent(e) {
+					c.reloadOnEvent(e.String())
+				}
+			case err := <-watchErrors:
+				log.WithError(err).Error("error while watching the config file")
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(signals)
+		close(done)
+		if watcher != nil {
+			watcher.Close()
+		}
+	}, nil
+}
+
+// isConfigFileEvent returns `true` if the given event is about the config file or about the `..data` symlink
+// which Kubernetes swaps when a mounted ConfigMap changes
+func (c *Data) isConfigFileEvent(e fsnotify.Event) bool {
+	if e.Op&(fsnotify.Write|fsnotify.Create) == 0 {
+		return false
+	}
+	return filepath.Clean(e.Name) == filepath.Clean(c.configFilePath) || filepath.Base(e.Name) == "..data"
+}
+
+func (c *Data) reloadOnEvent(event string) {
+	if err := c.Reload(); err != nil {
+		log.WithError(err).WithField("event", event).Error("unable to reload the configuration")
+	}
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fabric8-services/fabric8-auth/log"
+	authclient "github.com/fabric8-services/fabric8-toggles-service/auth/client"
+	"github.com/fabric8-services/fabric8-toggles-service/tlsconfig"
+)
+
+// AdminConfiguration the configuration of the users who are allowed to use the admin features
+type AdminConfiguration interface {
+	GetAdminEmails() []string
+}
+
+// isAdmin returns `true` if the request was sent by a trusted service authenticated by its client certificate,
+// or if the given user has a verified email address which belongs to the configured admins
+func isAdmin(ctx context.Context, config AdminConfiguration, user *authclient.User) bool {
+	if identity, ok := tlsconfig.ContextServiceIdentity(ctx); ok {
+		log.Debug(ctx, map[string]interface{}{"service_identity": identity}, "granting admin access to service")
+		return true
+	}
+	if user == nil || user.Data == nil || user.Data.Attributes == nil {
+		return false
+	}
+	attrs := user.Data.Attributes
+	if attrs.Email == nil || attrs.EmailVerified == nil || !*attrs.EmailVerified {
+		return false
+	}
+	for _, email := range config.GetAdminEmails() {
+		if strings.EqualFold(email, *attrs.Email) {
+			return true
+		}
+	}
+	return false
+}
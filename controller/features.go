@@ -2,8 +2,11 @@ package controller
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/fabric8-services/fabric8-auth/goasupport"
 	"github.com/fabric8-services/fabric8-auth/log"
@@ -14,10 +17,15 @@ import (
 	authclient "github.com/fabric8-services/fabric8-toggles-service/auth/client"
 	"github.com/fabric8-services/fabric8-toggles-service/errors"
 	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/gitops"
 	"github.com/fabric8-services/fabric8-toggles-service/jsonapi"
+	"github.com/fabric8-services/fabric8-toggles-service/metrics"
+	"github.com/fabric8-services/fabric8-toggles-service/stale"
+	"github.com/fabric8-services/fabric8-toggles-service/tracing"
 	"github.com/goadesign/goa"
 	goajwt "github.com/goadesign/goa/middleware/security/jwt"
 	errs "github.com/pkg/errors"
+	"go.opencensus.io/trace"
 )
 
 // FeaturesController implements the features resource.
@@ -27,13 +35,20 @@ type FeaturesController struct {
 	togglesClient featuretoggles.Client
 	httpClient    *http.Client
 	tokenParser   token.Parser
+	metadata      featuretoggles.MetadataProvider
 }
 
+// FeaturesVersionHeader the response header with the version of the features against which the request was evaluated
+const FeaturesVersionHeader = "X-Features-Version"
+
 // FeaturesControllerConfig the configuration required for the FeaturesController
 type FeaturesControllerConfig interface {
 	featuretoggles.ToggleServiceConfiguration
+	featuretoggles.InternalUserConfiguration
+	AdminConfiguration
 	GetFeaturesCacheControl() string
 	GetAuthServiceURL() string
+	GetTogglesAdminToken() string
 }
 
 // NewFeaturesController creates a FeaturesController.
@@ -50,7 +65,8 @@ func NewFeaturesController(service *goa.Service, tokenParser token.Parser, confi
 		opt(&ctrl)
 	}
 	if ctrl.togglesClient == nil {
-		togglesClient, err := featuretoggles.NewDefaultClient("fabric8-toggle-service", config)
+		togglesClient, err := featuretoggles.NewDefaultClient("fabric8-toggle-service", config,
+			featuretoggles.WithRecorder(metrics.Recorder{}), featuretoggles.WithTracer(tracing.Tracer{}))
 		if err != nil {
 			log.Panic(nil, map[string]interface{}{
 				"err": err,
@@ -79,31 +95,62 @@ func WithTogglesClient(client featuretoggles.Client) FeaturesControllerOption {
 	}
 }
 
+// WithMetadataProvider configure the FeatureController with the provider of the metadata of the features
+// (e.g., their owner and expiry date, to report the stale features)
+func WithMetadataProvider(provider featuretoggles.MetadataProvider) FeaturesControllerOption {
+	return func(ctrl *FeaturesController) {
+		ctrl.metadata = provider
+	}
+}
+
 // List runs the list action.
 func (c *FeaturesController) List(ctx *app.ListFeaturesContext) error {
-	var user *authclient.User
-	jwtToken := goajwt.ContextJWT(ctx)
-	if jwtToken != nil {
-		_, err := c.tokenParser.Parse(ctx, jwtToken.Raw)
-		if err != nil {
-			log.Error(ctx, map[string]interface{}{"error": err.Error()}, "error while parsing the user's token")
-			return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("invalid token"))
+	user, err := c.getUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	evalCtx, user, preview, err := c.applyPreview(ctx, user, previewParams{
+		level:    ctx.PreviewLevel,
+		email:    ctx.PreviewEmail,
+		internal: ctx.PreviewInternal,
+		userID:   ctx.PreviewUser,
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	evalCtx = c.withSnapshot(evalCtx, ctx.ResponseData)
+	glob := ctx.Glob != nil && *ctx.Glob
+	for _, group := range ctx.Group {
+		if !featuretoggles.IsValidGroup(group, glob) {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("group", group).Expected("a dotted name, e.g. 'planner.board' (or a glob pattern with the 'glob' parameter)"))
 		}
-		if user, err = c.getUserProfile(ctx); err != nil {
-			return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	for _, level := range ctx.Level {
+		if !featuretoggles.IsKnownLevel(level) {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("level", level).Expected("internal|experimental|beta|released"))
 		}
-	} else {
-		log.Warn(ctx, map[string]interface{}{}, "No JWT found in the request.")
 	}
 	var features []featuretoggles.UserFeature
+	tags := featuretoggles.ParseTagQuery(ctx.Tag)
 	// look-up by pattern
-	if ctx.Group != nil {
-		features = c.togglesClient.GetFeaturesByPattern(ctx, *ctx.Group, user)
+	if len(ctx.Group) > 0 {
+		features = c.togglesClient.GetFeaturesByGroups(evalCtx, ctx.Group, glob, user)
 
 	} else if ctx.Names != nil {
-		features = c.togglesClient.GetFeaturesByName(ctx, ctx.Names, user)
+		features = c.togglesClient.GetFeaturesByName(evalCtx, ctx.Names, user)
 	} else if ctx.Strategy != nil { // all features with strategy enableByLevel
-		features = c.togglesClient.GetFeaturesByStrategy(ctx, *ctx.Strategy, user)
+		features = c.togglesClient.GetFeaturesByStrategy(evalCtx, *ctx.Strategy, user)
+	} else if len(tags) > 0 {
+		features = c.togglesClient.GetFeaturesByTags(evalCtx, tags, user)
+	} else if len(ctx.Level) > 0 {
+		features = c.togglesClient.GetFeaturesByLevel(evalCtx, ctx.Level, user)
+	}
+	if features != nil && len(tags) > 0 {
+		// the tags narrow down the features selected by the other parameters
+		features = tags.Filter(features)
+	}
+	if features != nil && len(ctx.Level) > 0 {
+		features = featuretoggles.FilterByLevel(features, ctx.Level)
 	}
 	if features == nil {
 		log.Info(ctx, nil, "missing query params in request")
@@ -115,6 +162,11 @@ func (c *FeaturesController) List(ctx *app.ListFeaturesContext) error {
 	// sort features by name to make sure that the same result list is returned between 2 calls (assuming nothing changed in the settings)
 	// so that ETag comparison works
 	sort.Sort(featuretoggles.ByName(features))
+	if preview {
+		// never cache a preview, especially not under the admin's own ETag
+		ctx.ResponseData.Header().Set(app.CacheControl, "no-store")
+		return ctx.OK(c.convertFeatures(ctx, features))
+	}
 	return ctx.ConditionalEntities(features, c.config.GetFeaturesCacheControl, func() error {
 		appFeatures := c.convertFeatures(ctx, features)
 		return ctx.OK(appFeatures)
@@ -123,28 +175,480 @@ func (c *FeaturesController) List(ctx *app.ListFeaturesContext) error {
 
 // Show runs the show action.
 func (c *FeaturesController) Show(ctx *app.ShowFeaturesContext) error {
-	jwtToken := goajwt.ContextJWT(ctx)
-	var user *authclient.User
-	if jwtToken != nil {
-		_, err := c.tokenParser.Parse(ctx, jwtToken.Raw)
-		if err != nil {
-			log.Error(ctx, map[string]interface{}{"error": err.Error()}, "error while parsing the user's token")
-			return jsonapi.JSONErrorResponse(ctx, errors.NewUnauthorizedError("invalid token"))
-		}
-		if user, err = c.getUserProfile(ctx); err != nil {
-			return jsonapi.JSONErrorResponse(ctx, err)
-		}
-	} else {
-		log.Warn(ctx, map[string]interface{}{}, "No JWT found in the request.")
+	user, err := c.getUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	evalCtx, user, preview, err := c.applyPreview(ctx, user, previewParams{
+		level:    ctx.PreviewLevel,
+		email:    ctx.PreviewEmail,
+		internal: ctx.PreviewInternal,
+		userID:   ctx.PreviewUser,
+	})
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
 	}
+	evalCtx = c.withSnapshot(evalCtx, ctx.ResponseData)
 	featureName := ctx.FeatureName
-	feature := c.togglesClient.GetFeature(ctx, featureName, user)
+	feature := c.togglesClient.GetFeature(evalCtx, featureName, user)
+	if preview {
+		// never cache a preview, especially not under the admin's own ETag
+		ctx.ResponseData.Header().Set(app.CacheControl, "no-store")
+		return ctx.OK(c.convertFeature(ctx, featureName, feature))
+	}
 	return ctx.ConditionalRequest(feature, c.config.GetFeaturesCacheControl, func() error {
 		appFeature := c.convertFeature(ctx, featureName, feature)
 		return ctx.OK(appFeature)
 	})
 }
 
+// Catalog runs the catalog action.
+func (c *FeaturesController) Catalog(ctx *app.CatalogFeaturesContext) error {
+	user, err := c.getUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	evalCtx := c.withSnapshot(ctx, ctx.ResponseData)
+	features := c.togglesClient.GetFeaturesByLevel(evalCtx, featuretoggles.Levels(), user)
+	catalog := featuretoggles.NewCatalog(features)
+	return ctx.ConditionalEntities(catalog, c.config.GetFeaturesCacheControl, func() error {
+		return ctx.OK(convertCatalog(catalog))
+	})
+}
+
+// convertCatalog converts the catalog levels, in which each feature requires the level which it belongs to
+func convertCatalog(catalog []featuretoggles.FeatureCatalogLevel) *app.FeatureCatalogLevelList {
+	result := make([]*app.FeatureCatalogLevel, len(catalog))
+	for i, l := range catalog {
+		entries := make([]*app.FeatureCatalogEntry, len(l.Features))
+		for j, f := range l.Features {
+			var userOverride *string
+			if f.Override != featuretoggles.NoEnrollment {
+				override := string(f.Override)
+				userOverride = &override
+			}
+			entries[j] = &app.FeatureCatalogEntry{
+				Name:          f.Name,
+				Description:   f.Description,
+				UserEnabled:   f.UserEnabled,
+				RequiredLevel: l.Level,
+				UserOverride:  userOverride,
+			}
+		}
+		result[i] = &app.FeatureCatalogLevel{
+			ID:   l.Level,
+			Type: "feature-catalog-levels",
+			Attributes: &app.FeatureCatalogLevelAttributes{
+				Level:    l.Level,
+				Features: entries,
+			},
+		}
+	}
+	return &app.FeatureCatalogLevelList{
+		Data: result,
+	}
+}
+
+// Tree runs the tree action.
+func (c *FeaturesController) Tree(ctx *app.TreeFeaturesContext) error {
+	user, err := c.getUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	evalCtx := c.withSnapshot(ctx, ctx.ResponseData)
+	var tree []*featuretoggles.FeatureTreeNode
+	if ctx.Group != nil {
+		if !featuretoggles.IsValidGroup(*ctx.Group, false) {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("group", *ctx.Group).Expected("a dotted name, e.g. 'planner.board'"))
+		}
+		features := c.togglesClient.GetFeaturesByGroups(evalCtx, []string{*ctx.Group}, false, user)
+		if node := featuretoggles.FindFeatureTreeNode(featuretoggles.NewFeatureTree(features), *ctx.Group); node != nil {
+			tree = append(tree, node)
+		}
+	} else {
+		// the '*' glob matches all the features
+		features := c.togglesClient.GetFeaturesByGroups(evalCtx, []string{"*"}, true, user)
+		tree = featuretoggles.NewFeatureTree(features)
+	}
+	nodes := make([]featuretoggles.FeatureTreeNode, len(tree))
+	for i, n := range tree {
+		nodes[i] = *n
+	}
+	return ctx.ConditionalEntities(nodes, c.config.GetFeaturesCacheControl, func() error {
+		return ctx.OK(&app.FeatureTreeNodeList{
+			Data: c.convertTreeNodes(ctx, tree),
+		})
+	})
+}
+
+func (c *FeaturesController) convertTreeNodes(ctx context.Context, nodes []*featuretoggles.FeatureTreeNode) []*app.FeatureTreeNode {
+	result := make([]*app.FeatureTreeNode, len(nodes))
+	for i, n := range nodes {
+		attributes := &app.FeatureTreeNodeAttributes{
+			Name:         n.Name,
+			FeatureCount: n.FeatureCount,
+			EnabledCount: n.EnabledCount,
+		}
+		if n.Feature != nil {
+			attributes.Feature = c.convertFeatureData(ctx, n.Feature.Name, *n.Feature).Attributes
+		}
+		result[i] = &app.FeatureTreeNode{
+			ID:         n.Path,
+			Type:       "feature-tree-nodes",
+			Attributes: attributes,
+		}
+		if len(n.Children) > 0 {
+			result[i].Children = c.convertTreeNodes(ctx, n.Children)
+		}
+	}
+	return result
+}
+
+// Problems runs the problems action.
+func (c *FeaturesController) Problems(ctx *app.ProblemsFeaturesContext) error {
+	user, err := c.getUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	if !isAdmin(ctx, c.config, user) {
+		log.Warn(ctx, map[string]interface{}{}, "non-admin user attempted to list the feature problems")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewForbiddenError("only admins can list the feature problems"))
+	}
+	problems := c.togglesClient.Problems()
+	result := make([]*app.FeatureProblem, len(problems))
+	for i, p := range problems {
+		result[i] = &app.FeatureProblem{
+			ID:   fmt.Sprintf("%s/%s", p.Feature, p.Strategy),
+			Type: "feature-problems",
+			Attributes: &app.FeatureProblemAttributes{
+				Feature:  p.Feature,
+				Strategy: p.Strategy,
+				Problem:  p.Message,
+			},
+		}
+	}
+	return ctx.OK(&app.FeatureProblemList{
+		Data: result,
+	})
+}
+
+// Stale runs the stale action.
+func (c *FeaturesController) Stale(ctx *app.StaleFeaturesContext) error {
+	user, err := c.getUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	if !isAdmin(ctx, c.config, user) {
+		log.Warn(ctx, map[string]interface{}{}, "non-admin user attempted to list the stale features")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewForbiddenError("only admins can list the stale features"))
+	}
+	releasedDays, unusedDays := 90, 30
+	if ctx.ReleasedDays != nil {
+		if *ctx.ReleasedDays < 0 {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("released-days", *ctx.ReleasedDays).Expected("a positive number of days"))
+		}
+		releasedDays = *ctx.ReleasedDays
+	}
+	if ctx.UnusedDays != nil {
+		if *ctx.UnusedDays < 0 {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("unused-days", *ctx.UnusedDays).Expected("a positive number of days"))
+		}
+		unusedDays = *ctx.UnusedDays
+	}
+	features := c.togglesClient.Features()
+	metadata := map[string]featuretoggles.Metadata{}
+	if c.metadata != nil {
+		for _, f := range features {
+			if m, found := c.metadata.GetMetadata(f.Name); found {
+				metadata[f.Name] = m
+			}
+		}
+	}
+	// the evaluations are recorded by each instance of the service since it started, so a feature is reported as unused
+	// if it was not looked up on this instance
+	issues := stale.Report(features, metadata, stale.Options{
+		ReleasedFor:      time.Duration(releasedDays) * 24 * time.Hour,
+		UnusedFor:        time.Duration(unusedDays) * 24 * time.Hour,
+		LastEvaluations:  metrics.LastEvaluations(),
+		EvaluationsSince: metrics.EvaluationsSince(),
+	})
+	result := make([]*app.StaleFeature, len(issues))
+	for i, issue := range issues {
+		attributes := &app.StaleFeatureAttributes{
+			Feature: issue.Feature,
+			Rule:    issue.Rule,
+			Message: issue.Message,
+		}
+		if issue.Owner != "" {
+			owner := issue.Owner
+			attributes.Owner = &owner
+		}
+		if issue.Type != "" {
+			featureType := issue.Type
+			attributes.FeatureType = &featureType
+		}
+		result[i] = &app.StaleFeature{
+			ID:         fmt.Sprintf("%s/%s", issue.Feature, issue.Rule),
+			Type:       "stale-features",
+			Attributes: attributes,
+		}
+	}
+	return ctx.OK(&app.StaleFeatureList{
+		Data: result,
+	})
+}
+
+// Export runs the export action.
+func (c *FeaturesController) Export(ctx *app.ExportFeaturesContext) error {
+	user, err := c.getUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	if !isAdmin(ctx, c.config, user) {
+		log.Warn(ctx, map[string]interface{}{}, "non-admin user attempted to export the features")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewForbiddenError("only admins can export the features"))
+	}
+	export := featuretoggles.NewExport(c.togglesClient.Features())
+	result := &app.FeatureExport{
+		Version:    export.Version,
+		ExportedAt: &export.ExportedAt,
+		Features:   make([]*app.ExportedFeature, len(export.Features)),
+	}
+	for i, f := range export.Features {
+		description := f.Description
+		feature := &app.ExportedFeature{
+			Name:        f.Name,
+			Description: &description,
+			Enabled:     f.Enabled,
+			Strategies:  make([]*app.ExportedStrategy, len(f.Strategies)),
+		}
+		for j, s := range f.Strategies {
+			feature.Strategies[j] = &app.ExportedStrategy{
+				Name:       s.Name,
+				Parameters: s.Parameters,
+			}
+		}
+		result.Features[i] = feature
+	}
+	log.Info(ctx, map[string]interface{}{"feature_count": len(result.Features)}, "exported features")
+	return ctx.OK(result)
+}
+
+// Import runs the import action.
+func (c *FeaturesController) Import(ctx *app.ImportFeaturesContext) error {
+	user, err := c.getUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	if !isAdmin(ctx, c.config, user) {
+		log.Warn(ctx, map[string]interface{}{}, "non-admin user attempted to import features")
+		return jsonapi.JSONErrorResponse(ctx, errors.NewForbiddenError("only admins can import features"))
+	}
+	if ctx.Payload.Version > featuretoggles.ExportVersion {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("version", ctx.Payload.Version).Expected(featuretoggles.ExportVersion))
+	}
+	token := c.config.GetTogglesAdminToken()
+	if token == "" {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewForbiddenError("importing features is disabled: no admin token is configured for the toggles server"))
+	}
+	desired := make([]gitops.Feature, len(ctx.Payload.Features))
+	for i, f := range ctx.Payload.Features {
+		if featuretoggles.IsReservedName(f.Name) {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("name", f.Name).Expected("not one of "+strings.Join(featuretoggles.ReservedNames, ", ")))
+		}
+		feature := gitops.Feature{
+			Name:       f.Name,
+			Enabled:    f.Enabled,
+			Strategies: make([]gitops.Strategy, len(f.Strategies)),
+		}
+		if f.Description != nil {
+			feature.Description = *f.Description
+		}
+		for j, s := range f.Strategies {
+			feature.Strategies[j] = gitops.Strategy{
+				Name:       s.Name,
+				Parameters: s.Parameters,
+			}
+		}
+		desired[i] = feature
+	}
+	httpClient, err := featuretoggles.NewHTTPClient(c.config)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	adminClient := gitops.NewAdminClient(c.config.GetTogglesURL(), gitops.WithAdminToken(token), gitops.WithAdminHTTPClient(httpClient))
+	current, err := adminClient.Features(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+	}
+	plan := gitops.ComputeFeaturePlan(desired, current, ctx.Prune != nil && *ctx.Prune)
+	applied := false
+	if ctx.Apply != nil && *ctx.Apply && !plan.IsEmpty() {
+		if err := adminClient.Apply(ctx, plan); err != nil {
+			return jsonapi.JSONErrorResponse(ctx, errors.NewInternalError(ctx, err))
+		}
+		applied = true
+		log.Info(ctx, map[string]interface{}{"change_count": len(plan.Changes)}, "imported features")
+	}
+	result := &app.FeatureImportPlan{
+		Changes:   make([]*app.FeatureChange, len(plan.Changes)),
+		Unmanaged: plan.Unmanaged,
+		Applied:   applied,
+	}
+	for i, change := range plan.Changes {
+		result.Changes[i] = &app.FeatureChange{
+			Action: string(change.Action),
+			Name:   change.Name,
+			Diff:   change.Diff,
+		}
+	}
+	return ctx.OK(result)
+}
+
+// UpdateLevel runs the updateLevel action.
+func (c *FeaturesController) UpdateLevel(ctx *app.UpdateLevelFeaturesContext) error {
+	user, err := c.getAuthenticatedUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	// the levels are case-insensitive, but always stored in lower case in the auth service
+	level := strings.ToLower(ctx.Payload.Data.Attributes.Level)
+	if !featuretoggles.IsKnownLevel(level) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("level", level).Expected("internal|experimental|beta|released"))
+	}
+	if !featuretoggles.IsSelectableLevel(level, featuretoggles.IsInternalUser(user, c.config.GetInternalEmailDomains())) {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewForbiddenError(fmt.Sprintf("level '%s' is reserved to internal users", level)))
+	}
+	updatedUser, err := c.updateUserFeatureLevel(ctx, level)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	evalCtx := c.withSnapshot(ctx, ctx.ResponseData)
+	// all the features, since the new level also changes the features which depend on other features (prerequisites),
+	// and the features enabled by email or enrollment are part of the user's feature set as well
+	features := c.togglesClient.GetFeaturesByGroups(evalCtx, []string{"*"}, true, updatedUser)
+	sort.Sort(featuretoggles.ByName(features))
+	return ctx.OK(c.convertFeatures(ctx, features))
+}
+
+// Enroll runs the enroll action.
+func (c *FeaturesController) Enroll(ctx *app.EnrollFeaturesContext) error {
+	user, err := c.getAuthenticatedUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	enrollment := featuretoggles.Enrollment(ctx.Payload.Data.Attributes.Enrollment)
+	if !enrollment.IsValid() {
+		return jsonapi.JSONErrorResponse(ctx, errors.NewBadParameterError("enrollment", enrollment).Expected("opt-in|opt-out"))
+	}
+	if err := c.togglesClient.Enroll(ctx, ctx.FeatureName, user, enrollment); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	evalCtx := c.withSnapshot(ctx, ctx.ResponseData)
+	feature := c.togglesClient.GetFeature(evalCtx, ctx.FeatureName, user)
+	return ctx.OK(c.convertFeature(ctx, ctx.FeatureName, feature))
+}
+
+// Unenroll runs the unenroll action.
+func (c *FeaturesController) Unenroll(ctx *app.UnenrollFeaturesContext) error {
+	user, err := c.getAuthenticatedUser(ctx)
+	if err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	if err := c.togglesClient.Unenroll(ctx, ctx.FeatureName, user); err != nil {
+		return jsonapi.JSONErrorResponse(ctx, err)
+	}
+	evalCtx := c.withSnapshot(ctx, ctx.ResponseData)
+	feature := c.togglesClient.GetFeature(evalCtx, ctx.FeatureName, user)
+	return ctx.OK(c.convertFeature(ctx, ctx.FeatureName, feature))
+}
+
+// withSnapshot returns a context in which all the features of the request are evaluated against the same snapshot,
+// and sets the version of this snapshot in the response headers
+func (c *FeaturesController) withSnapshot(ctx context.Context, response *goa.ResponseData) context.Context {
+	snapshotCtx, version := c.togglesClient.WithSnapshot(ctx)
+	if version != "" {
+		response.Header().Set(FeaturesVersionHeader, version)
+	}
+	return snapshotCtx
+}
+
+// getUser verifies the JWT in the request (if any) and retrieves the user's profile.
+// Returns `nil, nil` if the request has no JWT.
+func (c *FeaturesController) getUser(ctx context.Context) (*authclient.User, error) {
+	jwtToken := goajwt.ContextJWT(ctx)
+	if jwtToken == nil {
+		log.Warn(ctx, map[string]interface{}{}, "No JWT found in the request.")
+		return nil, nil
+	}
+	parseCtx, span := tracing.StartSpan(ctx, "token.Parse")
+	_, err := c.tokenParser.Parse(parseCtx, jwtToken.Raw)
+	tracing.EndSpan(span, err)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{"error": err.Error()}, "error while parsing the user's token")
+		return nil, errors.NewUnauthorizedError("invalid token")
+	}
+	return c.getUserProfile(ctx)
+}
+
+// previewParams the parameters to evaluate the features as another user
+type previewParams struct {
+	level    *string
+	email    *string
+	internal *bool
+	userID   *string
+}
+
+func (p previewParams) isSet() bool {
+	return p.level != nil || p.email != nil || p.internal != nil || p.userID != nil
+}
+
+// applyPreview returns the context and the user to use to evaluate the features. If any preview parameter is set,
+// the features are evaluated as the previewed user (or an anonymous user) with the given attributes.
+// Only admins are allowed to preview features.
+func (c *FeaturesController) applyPreview(ctx context.Context, user *authclient.User, params previewParams) (context.Context, *authclient.User, bool, error) {
+	if !params.isSet() {
+		return ctx, user, false, nil
+	}
+	if !isAdmin(ctx, c.config, user) {
+		log.Warn(ctx, map[string]interface{}{}, "non-admin user attempted to preview features")
+		return nil, nil, false, errors.NewForbiddenError("only admins can preview features")
+	}
+	if params.level != nil && !featuretoggles.IsKnownLevel(*params.level) {
+		return nil, nil, false, errors.NewBadParameterError("preview-level", *params.level).Expected("internal|experimental|beta|released")
+	}
+	var previewUser *authclient.User
+	if params.userID != nil {
+		var err error
+		if previewUser, err = c.getUserProfileByID(ctx, *params.userID); err != nil {
+			return nil, nil, false, err
+		}
+	}
+	log.Info(ctx, map[string]interface{}{
+		"preview_level":    params.level,
+		"preview_email":    params.email,
+		"preview_internal": params.internal,
+		"preview_user":     params.userID,
+	}, "previewing features on behalf of an admin")
+	return featuretoggles.ContextWithPreview(ctx, featuretoggles.Preview{
+		Level:    params.level,
+		Email:    params.email,
+		Internal: params.internal,
+	}), previewUser, true, nil
+}
+
+// getAuthenticatedUser same as getUser, but returns an UnauthorizedError if the request has no JWT
+func (c *FeaturesController) getAuthenticatedUser(ctx context.Context) (*authclient.User, error) {
+	user, err := c.getUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, errors.NewUnauthorizedError("missing token")
+	}
+	return user, nil
+}
+
 // getUserProfile retrieves the user's profile from the auth service, by forwarding the current JWT token
 func (c *FeaturesController) getUserProfile(ctx context.Context) (*authclient.User, error) {
 	authClient, err := auth.NewClient(ctx, c.config.GetAuthServiceURL(), auth.WithHTTPClient(c.httpClient))
@@ -154,10 +658,48 @@ func (c *FeaturesController) getUserProfile(ctx context.Context) (*authclient.Us
 		}, "unable to initialize auth service client")
 		return nil, errs.Wrap(err, "unable to initialize auth service client")
 	}
-	res, err := authClient.ShowUser(goasupport.ForwardContextRequestID(ctx), authclient.ShowUserPath(), nil, nil)
+	start := time.Now()
+	spanCtx, span := tracing.StartSpan(ctx, "auth.ShowUser")
+	res, err := authClient.ShowUser(goasupport.ForwardContextRequestID(spanCtx), authclient.ShowUserPath(), nil, nil)
+	metrics.RecordAuthRequest("show_user", start, res, err)
+	if res != nil {
+		span.AddAttributes(trace.Int64Attribute("http.status_code", int64(res.StatusCode)))
+	}
+	tracing.EndSpan(span, err)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err.Error(),
+		}, "unable to get user from the auth service")
+		return nil, errs.Wrap(err, "unable to get user from the auth service")
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+	// OK
+	case 401:
+		return nil, errors.NewUnauthorizedError(rest.ReadBody(res.Body))
+	default:
+		return nil, errs.Errorf("status: %s, body: %s", res.Status, rest.ReadBody(res.Body))
+	}
+	return authClient.DecodeUser(res)
+}
+
+// getUserProfileByID retrieves the profile of the user with the given ID from the auth service, by forwarding the current JWT token
+func (c *FeaturesController) getUserProfileByID(ctx context.Context, userID string) (*authclient.User, error) {
+	authClient, err := auth.NewClient(ctx, c.config.GetAuthServiceURL(), auth.WithHTTPClient(c.httpClient))
 	if err != nil {
 		log.Error(ctx, map[string]interface{}{
 			"err": err.Error(),
+		}, "unable to initialize auth service client")
+		return nil, errs.Wrap(err, "unable to initialize auth service client")
+	}
+	start := time.Now()
+	res, err := authClient.ShowUsers(goasupport.ForwardContextRequestID(ctx), authclient.ShowUsersPath(userID), nil, nil)
+	metrics.RecordAuthRequest("show_users", start, res, err)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err":     err.Error(),
+			"user_id": userID,
 		}, "unable to get user from the auth service")
 		return nil, errs.Wrap(err, "unable to get user from the auth service")
 	}
@@ -165,6 +707,8 @@ func (c *FeaturesController) getUserProfile(ctx context.Context) (*authclient.Us
 	switch res.StatusCode {
 	case 200:
 	// OK
+	case 400, 404:
+		return nil, errors.NewBadParameterError("preview-user", userID)
 	case 401:
 		return nil, errors.NewUnauthorizedError(rest.ReadBody(res.Body))
 	default:
@@ -173,6 +717,48 @@ func (c *FeaturesController) getUserProfile(ctx context.Context) (*authclient.Us
 	return authClient.DecodeUser(res)
 }
 
+// updateUserFeatureLevel updates the user's feature level in the auth service, by forwarding the current JWT token,
+// and returns the updated user's profile
+func (c *FeaturesController) updateUserFeatureLevel(ctx context.Context, level string) (*authclient.User, error) {
+	authClient, err := auth.NewClient(ctx, c.config.GetAuthServiceURL(), auth.WithHTTPClient(c.httpClient))
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err.Error(),
+		}, "unable to initialize auth service client")
+		return nil, errs.Wrap(err, "unable to initialize auth service client")
+	}
+	payload := &authclient.UpdateUsersPayload{
+		Data: &authclient.UpdateUserData{
+			Type: "identities",
+			Attributes: &authclient.UpdateIdentityDataAttributes{
+				FeatureLevel: &level,
+			},
+		},
+	}
+	res, err := authClient.UpdateUsers(goasupport.ForwardContextRequestID(ctx), authclient.UpdateUsersPath(), payload, "")
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{
+			"err": err.Error(),
+		}, "unable to update user in the auth service")
+		return nil, errs.Wrap(err, "unable to update user in the auth service")
+	}
+	defer res.Body.Close()
+	switch res.StatusCode {
+	case 200:
+	// OK
+	case 400:
+		return nil, errors.NewBadParameterError("level", level)
+	case 401:
+		return nil, errors.NewUnauthorizedError(rest.ReadBody(res.Body))
+	case 403:
+		return nil, errors.NewForbiddenError(rest.ReadBody(res.Body))
+	default:
+		return nil, errs.Errorf("status: %s, body: %s", res.Status, rest.ReadBody(res.Body))
+	}
+	log.Info(ctx, map[string]interface{}{"feature_level": level}, "updated user's feature level")
+	return authClient.DecodeUser(res)
+}
+
 func (c *FeaturesController) convertFeatures(ctx context.Context, features []featuretoggles.UserFeature) *app.UserFeatureList {
 	result := make([]*app.UserFeature, 0)
 	for _, feature := range features {
@@ -185,7 +771,7 @@ func (c *FeaturesController) convertFeatures(ctx context.Context, features []fea
 
 func (c *FeaturesController) convertFeature(ctx context.Context, name string, feature featuretoggles.UserFeature) *app.UserFeatureSingle {
 	// unknown feature has no description and is not enabled at all
-	if feature == featuretoggles.ZeroUserFeature {
+	if feature.IsZero() {
 		log.Warn(ctx, map[string]interface{}{"feature_name": name}, "feature not found")
 		return &app.UserFeatureSingle{
 			Data: &app.UserFeature{
@@ -209,6 +795,11 @@ func (c *FeaturesController) convertFeatureData(ctx context.Context, name string
 	if feature.EnablementLevel != featuretoggles.UnknownLevel {
 		enablementLevel = &feature.EnablementLevel
 	}
+	var userOverride *string
+	if feature.Override != featuretoggles.NoEnrollment {
+		override := string(feature.Override)
+		userOverride = &override
+	}
 	return &app.UserFeature{
 		ID:   feature.Name,
 		Type: "features",
@@ -217,6 +808,19 @@ func (c *FeaturesController) convertFeatureData(ctx context.Context, name string
 			Enabled:         feature.Enabled,
 			EnablementLevel: enablementLevel,
 			UserEnabled:     feature.UserEnabled,
+			UserOverride:    userOverride,
+			Preview:         previewAttribute(feature),
+			Tags:            feature.Tags,
+			BlockedBy:       feature.BlockedBy,
 		},
 	}
 }
+
+// previewAttribute returns a pointer to `true` if the feature was previewed, `nil` otherwise
+func previewAttribute(feature featuretoggles.UserFeature) *bool {
+	if !feature.Preview {
+		return nil
+	}
+	preview := true
+	return &preview
+}
@@ -1,12 +1,19 @@
 package controller_test
 
 import (
+	"bytes"
 	"context"
+	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"regexp"
 	"testing"
 	"time"
 
+	unleashapi "github.com/Unleash/unleash-client-go/api"
 	jwt "github.com/dgrijalva/jwt-go"
 	jwtrequest "github.com/dgrijalva/jwt-go/request"
 	"github.com/dnaeon/go-vcr/cassette"
@@ -18,9 +25,11 @@ import (
 	authclient "github.com/fabric8-services/fabric8-toggles-service/auth/client"
 	"github.com/fabric8-services/fabric8-toggles-service/controller"
 	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/gitops"
 	testsupport "github.com/fabric8-services/fabric8-toggles-service/test"
 	testfeaturetoggles "github.com/fabric8-services/fabric8-toggles-service/test/featuretoggles"
 	"github.com/fabric8-services/fabric8-toggles-service/test/recorder"
+	"github.com/fabric8-services/fabric8-toggles-service/tlsconfig"
 	"github.com/fabric8-services/fabric8-toggles-service/token"
 	"github.com/goadesign/goa"
 	goajwt "github.com/goadesign/goa/middleware/security/jwt"
@@ -92,6 +101,8 @@ func init() {
 
 type TestFeatureControllerConfig struct {
 	authServiceURL string
+	togglesURL     string
+	adminToken     string
 }
 
 func (c *TestFeatureControllerConfig) GetAuthServiceURL() string {
@@ -99,6 +110,34 @@ func (c *TestFeatureControllerConfig) GetAuthServiceURL() string {
 }
 
 func (c *TestFeatureControllerConfig) GetTogglesURL() string {
+	return c.togglesURL
+}
+
+func (c *TestFeatureControllerConfig) GetTogglesAdminToken() string {
+	return c.adminToken
+}
+
+func (c *TestFeatureControllerConfig) GetTogglesAPIToken() string {
+	return ""
+}
+
+func (c *TestFeatureControllerConfig) GetTogglesCustomHeaders() map[string]string {
+	return nil
+}
+
+func (c *TestFeatureControllerConfig) GetTogglesCABundleFile() string {
+	return ""
+}
+
+func (c *TestFeatureControllerConfig) GetTogglesProxyURL() string {
+	return ""
+}
+
+func (c *TestFeatureControllerConfig) GetTogglesTimeout() time.Duration {
+	return 10 * time.Second
+}
+
+func (c *TestFeatureControllerConfig) GetTogglesInstanceID() string {
 	return ""
 }
 
@@ -106,6 +145,14 @@ func (c *TestFeatureControllerConfig) GetFeaturesCacheControl() string {
 	return "private,max-age=120"
 }
 
+func (c *TestFeatureControllerConfig) GetAdminEmails() []string {
+	return []string{"user@foo.com"}
+}
+
+func (c *TestFeatureControllerConfig) GetInternalEmailDomains() []string {
+	return featuretoggles.DefaultInternalEmailDomains
+}
+
 func newFeaturesController(t *testing.T, tokenParser authtoken.Parser, httpClient *http.Client, client featuretoggles.Client) (*goa.Service, *controller.FeaturesController) {
 	svc := goa.New("feature")
 	ctrl := controller.NewFeaturesController(svc,
@@ -138,6 +185,10 @@ func newClientMock(t *testing.T) *testfeaturetoggles.ClientMock {
 		}
 	}
 
+	mockClient.WithSnapshotFunc = func(ctx context.Context) (context.Context, string) {
+		return ctx, "0123456789abcdef"
+	}
+
 	mockClient.GetFeaturesByNameFunc = func(ctx context.Context, names []string, user *authclient.User) []featuretoggles.UserFeature {
 		if reflect.DeepEqual(names, []string{disabledFeature.Name, multiStrategiesFeature.Name}) {
 			return []featuretoggles.UserFeature{disabledFeature, multiStrategiesFeature}
@@ -146,21 +197,42 @@ func newClientMock(t *testing.T) *testfeaturetoggles.ClientMock {
 		}
 		return nil
 	}
-	mockClient.GetFeaturesByPatternFunc = func(ctx context.Context, pattern string, user *authclient.User) []featuretoggles.UserFeature {
-		if pattern == "foo" {
-			return []featuretoggles.UserFeature{
-				disabledFeature,
-				singleStrategyFeature,
-				multiStrategiesFeature,
-				fooGroupFeature,
+	mockClient.GetFeaturesByGroupsFunc = func(ctx context.Context, groups []string, glob bool, user *authclient.User) []featuretoggles.UserFeature {
+		pattern := regexp.MustCompile(featuretoggles.GroupsPattern(groups, glob))
+		result := []featuretoggles.UserFeature{}
+		for _, f := range []featuretoggles.UserFeature{
+			disabledFeature,
+			singleStrategyFeature,
+			multiStrategiesFeature,
+			releasedFeature,
+			devFeature,
+			fooGroupFeature,
+			foobarFeature,
+		} {
+			if pattern.MatchString(f.Name) {
+				result = append(result, f)
 			}
 		}
-		if pattern == "bar" {
-			return []featuretoggles.UserFeature{
-				releasedFeature,
-			}
-		}
-		return []featuretoggles.UserFeature{}
+		return result
+	}
+
+	mockClient.GetFeaturesByTagsFunc = func(ctx context.Context, query featuretoggles.TagQuery, user *authclient.User) []featuretoggles.UserFeature {
+		taggedReleasedFeature := releasedFeature
+		taggedReleasedFeature.Tags = []string{"planner", "ui"}
+		taggedDevFeature := devFeature
+		taggedDevFeature.Tags = []string{"ui"}
+		return query.Filter([]featuretoggles.UserFeature{taggedReleasedFeature, taggedDevFeature})
+	}
+
+	mockClient.GetFeaturesByLevelFunc = func(ctx context.Context, levels []string, user *authclient.User) []featuretoggles.UserFeature {
+		return featuretoggles.FilterByLevel([]featuretoggles.UserFeature{
+			disabledFeature,
+			singleStrategyFeature,
+			multiStrategiesFeature,
+			releasedFeature,
+			devFeature,
+			fooGroupFeature,
+		}, levels)
 	}
 
 	mockClient.GetFeaturesByStrategyFunc = func(ctx context.Context, name string, user *authclient.User) []featuretoggles.UserFeature {
@@ -171,6 +243,32 @@ func newClientMock(t *testing.T) *testfeaturetoggles.ClientMock {
 		}
 		return []featuretoggles.UserFeature{}
 	}
+	mockClient.FeaturesFunc = func() []unleashapi.Feature {
+		return []unleashapi.Feature{
+			{
+				Name:        releasedFeature.Name,
+				Description: releasedFeature.Description,
+				Enabled:     true,
+				Strategies: []unleashapi.Strategy{
+					{
+						Name: featuretoggles.EnableByLevelStrategyName,
+						Parameters: map[string]interface{}{
+							featuretoggles.LevelParameter: featuretoggles.ReleasedLevel,
+						},
+					},
+				},
+			},
+		}
+	}
+	mockClient.ProblemsFunc = func() []featuretoggles.Problem {
+		return []featuretoggles.Problem{
+			{
+				Feature:  singleStrategyFeature.Name,
+				Strategy: featuretoggles.EnableByLevelStrategyName,
+				Message:  "invalid 'level' parameter: unknown level 'beat' (expected internal|experimental|beta|released)",
+			},
+		}
+	}
 	return mockClient
 }
 func TestShowFeatures(t *testing.T) {
@@ -199,7 +297,7 @@ func TestShowFeatures(t *testing.T) {
 			// when
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
-			_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, disabledFeature.Name, nil)
+			_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, disabledFeature.Name, nil, nil, nil, nil, nil)
 			// then
 			require.NotNil(t, appFeature)
 			expectedFeatureData := &app.UserFeature{
@@ -219,7 +317,7 @@ func TestShowFeatures(t *testing.T) {
 			// when
 			ctx, err := createValidContext("../test/private_key.pem", "user_no_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
-			_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, singleStrategyFeature.Name, nil)
+			_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, singleStrategyFeature.Name, nil, nil, nil, nil, nil)
 			// then
 			require.NotNil(t, appFeature)
 			expectedFeatureData := &app.UserFeature{
@@ -248,7 +346,7 @@ func TestShowFeatures(t *testing.T) {
 					ctx, err := createValidContext("../test/private_key.pem", "user_experimental_level", time.Now().Add(1*time.Hour))
 					require.NoError(t, err)
 					// when
-					_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, multiStrategiesFeature.Name, nil)
+					_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, multiStrategiesFeature.Name, nil, nil, nil, nil, nil)
 					// then
 					require.NotNil(t, appFeature)
 					enablementLevel := featuretoggles.BetaLevel
@@ -274,7 +372,7 @@ func TestShowFeatures(t *testing.T) {
 				ctx, err := createValidContext("../test/private_key.pem", "user_no_level", time.Now().Add(1*time.Hour))
 				require.NoError(t, err)
 				// when
-				_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, releasedFeature.Name, nil)
+				_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, releasedFeature.Name, nil, nil, nil, nil, nil)
 				// then
 				require.NotNil(t, appFeature)
 				enablementLevel := featuretoggles.ReleasedLevel
@@ -296,7 +394,7 @@ func TestShowFeatures(t *testing.T) {
 				ctx, err := createValidContext("../test/private_key.pem", "user_empty_level", time.Now().Add(1*time.Hour))
 				require.NoError(t, err)
 				// when
-				_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, releasedFeature.Name, nil)
+				_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, releasedFeature.Name, nil, nil, nil, nil, nil)
 				// then
 				require.NotNil(t, appFeature)
 				enablementLevel := featuretoggles.ReleasedLevel
@@ -320,7 +418,7 @@ func TestShowFeatures(t *testing.T) {
 					ctx, err := createValidContext("../test/private_key.pem", "user_experimental_level", time.Now().Add(1*time.Hour))
 					require.NoError(t, err)
 					// when
-					_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, releasedFeature.Name, nil)
+					_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, releasedFeature.Name, nil, nil, nil, nil, nil)
 					// then
 					require.NotNil(t, appFeature)
 					enablementLevel := featuretoggles.ReleasedLevel
@@ -342,7 +440,7 @@ func TestShowFeatures(t *testing.T) {
 					ctx, err := createValidContext("../test/private_key.pem", "user_released_level", time.Now().Add(1*time.Hour))
 					require.NoError(t, err)
 					// when
-					_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, releasedFeature.Name, nil)
+					_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, releasedFeature.Name, nil, nil, nil, nil, nil)
 					// then
 					require.NotNil(t, appFeature)
 					enablementLevel := featuretoggles.ReleasedLevel
@@ -367,11 +465,21 @@ func TestShowFeatures(t *testing.T) {
 		// given
 		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 		require.NoError(t, err)
-		res, _ := test.ShowFeaturesOK(t, ctx, svc, ctrl, disabledFeature.Name, nil)
+		res, _ := test.ShowFeaturesOK(t, ctx, svc, ctrl, disabledFeature.Name, nil, nil, nil, nil, nil)
 		require.NotEmpty(t, res.Header()[app.ETag])
 		etag := res.Header()[app.ETag][0]
 		// when/then
-		test.ShowFeaturesNotModified(t, ctx, svc, ctrl, disabledFeature.Name, &etag)
+		test.ShowFeaturesNotModified(t, ctx, svc, ctrl, disabledFeature.Name, nil, nil, nil, nil, &etag)
+	})
+
+	t.Run("features version", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when
+		res, _ := test.ShowFeaturesOK(t, ctx, svc, ctrl, disabledFeature.Name, nil, nil, nil, nil, nil)
+		// then
+		assert.Equal(t, "0123456789abcdef", res.Header().Get(controller.FeaturesVersionHeader))
 	})
 
 	t.Run("expired ETag", func(t *testing.T) {
@@ -380,7 +488,7 @@ func TestShowFeatures(t *testing.T) {
 		require.NoError(t, err)
 		etag := "foo"
 		// when
-		_, features := test.ShowFeaturesOK(t, ctx, svc, ctrl, disabledFeature.Name, &etag)
+		_, features := test.ShowFeaturesOK(t, ctx, svc, ctrl, disabledFeature.Name, nil, nil, nil, nil, &etag)
 		//then
 		assert.NotEmpty(t, features)
 	})
@@ -390,7 +498,7 @@ func TestShowFeatures(t *testing.T) {
 		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 		require.NoError(t, err)
 		// when
-		_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, "UnknownFeature", nil)
+		_, appFeature := test.ShowFeaturesOK(t, ctx, svc, ctrl, "UnknownFeature", nil, nil, nil, nil, nil)
 		// then
 		require.NotNil(t, appFeature)
 		expectedFeatureData := &app.UserFeature{
@@ -412,7 +520,7 @@ func TestShowFeatures(t *testing.T) {
 			ctx, err := createValidContext("../test/private_key2.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
 			// when/then
-			test.ShowFeaturesUnauthorized(t, ctx, svc, ctrl, releasedFeature.Name, nil)
+			test.ShowFeaturesUnauthorized(t, ctx, svc, ctrl, releasedFeature.Name, nil, nil, nil, nil, nil)
 		})
 
 		t.Run("expired token", func(t *testing.T) {
@@ -420,7 +528,7 @@ func TestShowFeatures(t *testing.T) {
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(-1*time.Hour))
 			require.NoError(t, err)
 			// when/then
-			test.ShowFeaturesUnauthorized(t, ctx, svc, ctrl, releasedFeature.Name, nil)
+			test.ShowFeaturesUnauthorized(t, ctx, svc, ctrl, releasedFeature.Name, nil, nil, nil, nil, nil)
 		})
 	})
 }
@@ -452,7 +560,7 @@ func TestListFeatures(t *testing.T) {
 			// when
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
-			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{disabledFeature.Name, multiStrategiesFeature.Name}, nil, nil)
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, nil, []string{disabledFeature.Name, multiStrategiesFeature.Name}, nil, nil, nil, nil, nil, nil, nil)
 			// then
 			betaLevel := featuretoggles.BetaLevel
 			expectedData := []*app.UserFeature{
@@ -484,11 +592,11 @@ func TestListFeatures(t *testing.T) {
 			// given
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
-			res, _ := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{disabledFeature.Name, multiStrategiesFeature.Name}, nil, nil)
+			res, _ := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, nil, []string{disabledFeature.Name, multiStrategiesFeature.Name}, nil, nil, nil, nil, nil, nil, nil)
 			require.NotEmpty(t, res.Header()[app.ETag])
 			etag := res.Header()[app.ETag][0]
 			// when/then
-			test.ListFeaturesNotModified(t, ctx, svc, ctrl, nil, []string{disabledFeature.Name, multiStrategiesFeature.Name}, nil, &etag)
+			test.ListFeaturesNotModified(t, ctx, svc, ctrl, nil, nil, nil, []string{disabledFeature.Name, multiStrategiesFeature.Name}, nil, nil, nil, nil, nil, nil, &etag)
 		})
 
 		t.Run("expired ETag", func(t *testing.T) {
@@ -497,7 +605,7 @@ func TestListFeatures(t *testing.T) {
 			require.NoError(t, err)
 			etag := "foo"
 			// when
-			_, features := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{disabledFeature.Name, multiStrategiesFeature.Name}, nil, &etag)
+			_, features := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, nil, []string{disabledFeature.Name, multiStrategiesFeature.Name}, nil, nil, nil, nil, nil, nil, &etag)
 			//then
 			assert.NotEmpty(t, features)
 		})
@@ -506,7 +614,7 @@ func TestListFeatures(t *testing.T) {
 			// when
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
-			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{"FeatureX", "FeatureY", "FeatureZ"}, nil, nil)
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, nil, []string{"FeatureX", "FeatureY", "FeatureZ"}, nil, nil, nil, nil, nil, nil, nil)
 			// then
 			expectedData := []*app.UserFeature{}
 			assert.Equal(t, expectedData, featuresList.Data)
@@ -521,7 +629,7 @@ func TestListFeatures(t *testing.T) {
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
 			strategy := "enableByLevel"
-			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, &strategy, nil)
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, nil, nil, nil, nil, nil, nil, &strategy, nil, nil)
 			// then
 			level := featuretoggles.ReleasedLevel
 			expectedData := []*app.UserFeature{
@@ -543,13 +651,104 @@ func TestListFeatures(t *testing.T) {
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
 			strategy := "anotherStrategyWithoutAnyFeature"
-			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, &strategy, nil)
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, nil, nil, nil, nil, nil, nil, &strategy, nil, nil)
 			// then
 			expectedData := []*app.UserFeature{}
 			assert.Equal(t, expectedData, featuresList.Data)
 		})
 	})
 
+	t.Run("list by tags", func(t *testing.T) {
+
+		t.Run("all tags", func(t *testing.T) {
+			// when
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, nil, nil, nil, nil, nil, nil, nil, []string{"ui", "planner,analyze"}, nil)
+			// then
+			level := featuretoggles.ReleasedLevel
+			expectedData := []*app.UserFeature{
+				{
+					ID:   releasedFeature.Name,
+					Type: "features",
+					Attributes: &app.UserFeatureAttributes{
+						Description:     "Feature released",
+						Enabled:         true,
+						EnablementLevel: &level,
+						UserEnabled:     true,
+						Tags:            []string{"planner", "ui"},
+					},
+				},
+			}
+			assert.Equal(t, expectedData, featuresList.Data)
+		})
+
+		t.Run("any tag", func(t *testing.T) {
+			// when
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, nil, nil, nil, nil, nil, nil, nil, []string{"planner,ui"}, nil)
+			// then
+			require.Len(t, featuresList.Data, 2)
+		})
+
+		t.Run("in group", func(t *testing.T) {
+			// given none of the features of the 'foo' group is tagged
+			pattern := "foo"
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			// when
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{pattern}, nil, nil, nil, nil, nil, nil, nil, []string{"ui"}, nil)
+			// then
+			assert.Empty(t, featuresList.Data)
+		})
+	})
+
+	t.Run("list by level", func(t *testing.T) {
+
+		t.Run("single level", func(t *testing.T) {
+			// given
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			// when
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, []string{"Beta"}, nil, nil, nil, nil, nil, nil, nil, nil)
+			// then
+			require.Len(t, featuresList.Data, 1)
+			assert.Equal(t, multiStrategiesFeature.Name, featuresList.Data[0].ID)
+		})
+
+		t.Run("multiple levels", func(t *testing.T) {
+			// given
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			// when
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, []string{"beta", "released"}, nil, nil, nil, nil, nil, nil, nil, nil)
+			// then
+			require.Len(t, featuresList.Data, 2)
+			assert.Equal(t, releasedFeature.Name, featuresList.Data[0].ID)
+			assert.Equal(t, multiStrategiesFeature.Name, featuresList.Data[1].ID)
+		})
+
+		t.Run("in group", func(t *testing.T) {
+			// given
+			pattern := "foo"
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			// when
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{pattern}, []string{"released"}, nil, nil, nil, nil, nil, nil, nil, nil)
+			// then
+			assert.Empty(t, featuresList.Data)
+		})
+
+		t.Run("unknown level", func(t *testing.T) {
+			// given
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			// when/then
+			test.ListFeaturesBadRequest(t, ctx, svc, ctrl, nil, nil, []string{"beat"}, nil, nil, nil, nil, nil, nil, nil, nil)
+		})
+	})
+
 	t.Run("list by pattern", func(t *testing.T) {
 
 		// given
@@ -559,7 +758,7 @@ func TestListFeatures(t *testing.T) {
 			// when
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
-			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, &pattern, nil, nil, nil)
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{pattern}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			// then
 			experimentalLevel := featuretoggles.BetaLevel
 			expectedData := []*app.UserFeature{ // features are sorted by ID
@@ -611,11 +810,11 @@ func TestListFeatures(t *testing.T) {
 			// given
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
-			res, _ := test.ListFeaturesOK(t, ctx, svc, ctrl, &pattern, nil, nil, nil)
+			res, _ := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{pattern}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			require.NotEmpty(t, res.Header()[app.ETag])
 			etag := res.Header()[app.ETag][0]
 			// when/then
-			test.ListFeaturesNotModified(t, ctx, svc, ctrl, &pattern, nil, nil, &etag)
+			test.ListFeaturesNotModified(t, ctx, svc, ctrl, nil, []string{pattern}, nil, nil, nil, nil, nil, nil, nil, nil, &etag)
 		})
 
 		t.Run("expired ETag", func(t *testing.T) {
@@ -624,7 +823,7 @@ func TestListFeatures(t *testing.T) {
 			require.NoError(t, err)
 			etag := "foo"
 			// when
-			_, features := test.ListFeaturesOK(t, ctx, svc, ctrl, &pattern, nil, nil, &etag)
+			_, features := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{pattern}, nil, nil, nil, nil, nil, nil, nil, nil, &etag)
 			//then
 			assert.NotEmpty(t, features)
 		})
@@ -634,12 +833,59 @@ func TestListFeatures(t *testing.T) {
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
 			pattern := "unknown"
-			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, &pattern, nil, nil, nil)
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{pattern}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			// then
 			expectedData := []*app.UserFeature{}
 			assert.Equal(t, expectedData, featuresList.Data)
 		})
 
+		t.Run("multiple groups", func(t *testing.T) {
+			// given
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			// when
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{"foo", "bar"}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			// then
+			require.Len(t, featuresList.Data, 5)
+			assert.Equal(t, releasedFeature.Name, featuresList.Data[0].ID)
+		})
+
+		t.Run("glob", func(t *testing.T) {
+			// given
+			glob := true
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			// when
+			_, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, &glob, []string{"*.releasedFeature", "foo*"}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			// then
+			require.Len(t, featuresList.Data, 6)
+			assert.Equal(t, releasedFeature.Name, featuresList.Data[0].ID)
+			assert.Equal(t, foobarFeature.Name, featuresList.Data[5].ID)
+		})
+
+		t.Run("invalid groups", func(t *testing.T) {
+			// given
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			glob := true
+			for _, group := range []string{".*", "foo|bar", "foo..bar", "foo.*"} {
+				// when/then
+				test.ListFeaturesBadRequest(t, ctx, svc, ctrl, nil, []string{group}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			}
+			test.ListFeaturesBadRequest(t, ctx, svc, ctrl, &glob, []string{"foo|bar"}, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		})
+
+		t.Run("preview by trusted service", func(t *testing.T) {
+			// given a service authenticated by its client certificate, with no user token
+			ctx := tlsconfig.ContextWithServiceIdentity(context.Background(), "fabric8-admin-console")
+			previewLevel := featuretoggles.InternalLevel
+			// when
+			res, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, []string{pattern}, nil, nil, nil, nil, &previewLevel, nil, nil, nil, nil)
+			// then
+			assert.Equal(t, "no-store", res.Header().Get(app.CacheControl))
+			assert.Len(t, featuresList.Data, 4)
+		})
+
 	})
 
 	t.Run("invalid", func(t *testing.T) {
@@ -649,7 +895,16 @@ func TestListFeatures(t *testing.T) {
 			ctx, err := createValidContext("../test/private_key2.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
 			// when/then
-			test.ListFeaturesUnauthorized(t, ctx, svc, ctrl, nil, []string{"FeatureX", "FeatureY", "FeatureZ"}, nil, nil)
+			test.ListFeaturesUnauthorized(t, ctx, svc, ctrl, nil, nil, nil, []string{"FeatureX", "FeatureY", "FeatureZ"}, nil, nil, nil, nil, nil, nil, nil)
+		})
+
+		t.Run("preview by non-admin", func(t *testing.T) {
+			// given the user's email is not verified, hence he/she is not an admin
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			previewLevel := featuretoggles.InternalLevel
+			// when/then
+			test.ListFeaturesForbidden(t, ctx, svc, ctrl, nil, nil, nil, []string{"FeatureX", "FeatureY", "FeatureZ"}, nil, nil, &previewLevel, nil, nil, nil, nil)
 		})
 
 		t.Run("expired token", func(t *testing.T) {
@@ -657,7 +912,7 @@ func TestListFeatures(t *testing.T) {
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(-1*time.Hour))
 			require.NoError(t, err)
 			// when/then
-			test.ListFeaturesUnauthorized(t, ctx, svc, ctrl, nil, []string{"FeatureX", "FeatureY", "FeatureZ"}, nil, nil)
+			test.ListFeaturesUnauthorized(t, ctx, svc, ctrl, nil, nil, nil, []string{"FeatureX", "FeatureY", "FeatureZ"}, nil, nil, nil, nil, nil, nil, nil)
 		})
 
 		t.Run("missing query param", func(t *testing.T) {
@@ -665,7 +920,7 @@ func TestListFeatures(t *testing.T) {
 			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
 			require.NoError(t, err)
 			// when
-			_, result := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, nil, nil)
+			_, result := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
 			// then
 			require.Empty(t, result.Data)
 		})
@@ -673,6 +928,869 @@ func TestListFeatures(t *testing.T) {
 
 }
 
+func TestPreviewFeatures(t *testing.T) {
+	// given
+	r1, err := recorder.New("../test/data/controller/auth_get_user", recorder.WithMatcher(JWTMatcher()))
+	require.NoError(t, err)
+	defer r1.Stop()
+	r2, err := recorder.New("../test/data/token/auth_get_keys")
+	require.NoError(t, err)
+	c, err := auth.NewClient(
+		context.Background(),
+		"http://authservice",
+		auth.WithHTTPClient(
+			&http.Client{
+				Transport: r2.Transport,
+			}),
+	)
+	require.NoError(t, err)
+	p, err := token.NewParser(c)
+	require.NoError(t, err)
+	// keep the preview and the user with which the features were evaluated
+	var evaluatedPreview *featuretoggles.Preview
+	var evaluatedUser *authclient.User
+	mockClient := newClientMock(t)
+	getFeaturesByGroups := mockClient.GetFeaturesByGroupsFunc
+	mockClient.GetFeaturesByGroupsFunc = func(ctx context.Context, groups []string, glob bool, user *authclient.User) []featuretoggles.UserFeature {
+		if preview, ok := featuretoggles.ContextPreview(ctx); ok {
+			evaluatedPreview = &preview
+		}
+		evaluatedUser = user
+		return getFeaturesByGroups(ctx, groups, glob, user)
+	}
+	getFeature := mockClient.GetFeatureFunc
+	mockClient.GetFeatureFunc = func(ctx context.Context, name string, user *authclient.User) featuretoggles.UserFeature {
+		if preview, ok := featuretoggles.ContextPreview(ctx); ok {
+			evaluatedPreview = &preview
+		}
+		evaluatedUser = user
+		return getFeature(ctx, name, user)
+	}
+	reset := func() {
+		evaluatedPreview = nil
+		evaluatedUser = nil
+	}
+	svc, ctrl := newFeaturesController(t, p, &http.Client{Transport: r1.Transport}, mockClient)
+	groups := []string{"foo"}
+
+	t.Run("admin", func(t *testing.T) {
+		// given an admin, i.e., a user whose verified email address is in the admin emails
+		ctx, err := createValidContext("../test/private_key.pem", "user_admin", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+
+		t.Run("preview-level", func(t *testing.T) {
+			// given
+			reset()
+			previewLevel := featuretoggles.InternalLevel
+			// when
+			res, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, groups, nil, nil, nil, nil, &previewLevel, nil, nil, nil, nil)
+			// then
+			assert.Len(t, featuresList.Data, 4)
+			require.NotNil(t, evaluatedPreview)
+			require.NotNil(t, evaluatedPreview.Level)
+			assert.Equal(t, featuretoggles.InternalLevel, *evaluatedPreview.Level)
+			assert.Nil(t, evaluatedUser)
+			assert.Equal(t, "no-store", res.Header().Get(app.CacheControl))
+			assert.Empty(t, res.Header().Get(app.ETag))
+		})
+
+		t.Run("preview-email", func(t *testing.T) {
+			// given
+			reset()
+			previewEmail := "someone@redhat.com"
+			// when
+			res, _ := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, groups, nil, nil, &previewEmail, nil, nil, nil, nil, nil, nil)
+			// then
+			require.NotNil(t, evaluatedPreview)
+			require.NotNil(t, evaluatedPreview.Email)
+			assert.Equal(t, previewEmail, *evaluatedPreview.Email)
+			assert.Equal(t, "no-store", res.Header().Get(app.CacheControl))
+			assert.Empty(t, res.Header().Get(app.ETag))
+		})
+
+		t.Run("preview-user", func(t *testing.T) {
+			// given
+			reset()
+			previewUser := "22269698-dc27-4ec4-bfc8-290bad6000"
+			// when
+			res, _ := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, groups, nil, nil, nil, nil, nil, &previewUser, nil, nil, nil)
+			// then the features are evaluated as the previewed user, not as the admin
+			require.NotNil(t, evaluatedPreview)
+			require.NotNil(t, evaluatedUser)
+			assert.Equal(t, previewUser, *evaluatedUser.Data.ID)
+			assert.Equal(t, featuretoggles.BetaLevel, *evaluatedUser.Data.Attributes.FeatureLevel)
+			assert.Equal(t, "no-store", res.Header().Get(app.CacheControl))
+			assert.Empty(t, res.Header().Get(app.ETag))
+		})
+
+		t.Run("show", func(t *testing.T) {
+			// given
+			reset()
+			previewLevel := featuretoggles.ExperimentalLevel
+			// when
+			res, _ := test.ShowFeaturesOK(t, ctx, svc, ctrl, releasedFeature.Name, nil, nil, &previewLevel, nil, nil)
+			// then
+			require.NotNil(t, evaluatedPreview)
+			assert.Equal(t, featuretoggles.ExperimentalLevel, *evaluatedPreview.Level)
+			assert.Equal(t, "no-store", res.Header().Get(app.CacheControl))
+			assert.Empty(t, res.Header().Get(app.ETag))
+		})
+
+		t.Run("not served under the admin's ETag", func(t *testing.T) {
+			// given the ETag of the admin's own features
+			res, _ := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, groups, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+			require.NotEmpty(t, res.Header().Get(app.ETag))
+			etag := res.Header().Get(app.ETag)
+			test.ListFeaturesNotModified(t, ctx, svc, ctrl, nil, groups, nil, nil, nil, nil, nil, nil, nil, nil, &etag)
+			previewLevel := featuretoggles.InternalLevel
+			// when
+			res, featuresList := test.ListFeaturesOK(t, ctx, svc, ctrl, nil, groups, nil, nil, nil, nil, &previewLevel, nil, nil, nil, &etag)
+			// then the preview is returned, and is not stored
+			assert.NotEmpty(t, featuresList.Data)
+			assert.Equal(t, "no-store", res.Header().Get(app.CacheControl))
+			assert.Empty(t, res.Header().Get(app.ETag))
+			resShow, _ := test.ShowFeaturesOK(t, ctx, svc, ctrl, releasedFeature.Name, nil, nil, &previewLevel, nil, &etag)
+			assert.Equal(t, "no-store", resShow.Header().Get(app.CacheControl))
+		})
+	})
+
+	t.Run("non-admin", func(t *testing.T) {
+		// given a user whose email address is in the admin emails, but not verified
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		previewLevel := featuretoggles.InternalLevel
+		previewEmail := "someone@redhat.com"
+		previewInternal := true
+		previewUser := "22269698-dc27-4ec4-bfc8-290bad6000"
+
+		t.Run("preview-level", func(t *testing.T) {
+			// given
+			reset()
+			// when/then
+			test.ListFeaturesForbidden(t, ctx, svc, ctrl, nil, groups, nil, nil, nil, nil, &previewLevel, nil, nil, nil, nil)
+			test.ShowFeaturesForbidden(t, ctx, svc, ctrl, releasedFeature.Name, nil, nil, &previewLevel, nil, nil)
+			assert.Nil(t, evaluatedPreview)
+		})
+
+		t.Run("preview-email", func(t *testing.T) {
+			// given
+			reset()
+			// when/then
+			test.ListFeaturesForbidden(t, ctx, svc, ctrl, nil, groups, nil, nil, &previewEmail, nil, nil, nil, nil, nil, nil)
+			test.ShowFeaturesForbidden(t, ctx, svc, ctrl, releasedFeature.Name, &previewEmail, nil, nil, nil, nil)
+			assert.Nil(t, evaluatedPreview)
+		})
+
+		t.Run("preview-internal", func(t *testing.T) {
+			// given
+			reset()
+			// when/then
+			test.ListFeaturesForbidden(t, ctx, svc, ctrl, nil, groups, nil, nil, nil, &previewInternal, nil, nil, nil, nil, nil)
+			test.ShowFeaturesForbidden(t, ctx, svc, ctrl, releasedFeature.Name, nil, &previewInternal, nil, nil, nil)
+			assert.Nil(t, evaluatedPreview)
+		})
+
+		t.Run("preview-user", func(t *testing.T) {
+			// given
+			reset()
+			// when/then
+			test.ListFeaturesForbidden(t, ctx, svc, ctrl, nil, groups, nil, nil, nil, nil, nil, &previewUser, nil, nil, nil)
+			test.ShowFeaturesForbidden(t, ctx, svc, ctrl, releasedFeature.Name, nil, nil, nil, &previewUser, nil)
+			assert.Nil(t, evaluatedPreview)
+			assert.Nil(t, evaluatedUser)
+		})
+
+		t.Run("anonymous user", func(t *testing.T) {
+			// given
+			reset()
+			// when/then
+			test.ListFeaturesForbidden(t, context.Background(), svc, ctrl, nil, groups, nil, nil, nil, nil, &previewLevel, nil, nil, nil, nil)
+			assert.Nil(t, evaluatedPreview)
+		})
+	})
+}
+
+func TestFeatureCatalog(t *testing.T) {
+	// given
+	r1, err := recorder.New("../test/data/controller/auth_get_user", recorder.WithMatcher(JWTMatcher()))
+	require.NoError(t, err)
+	defer r1.Stop()
+	r2, err := recorder.New("../test/data/token/auth_get_keys")
+	require.NoError(t, err)
+	c, err := auth.NewClient(
+		context.Background(),
+		"http://authservice",
+		auth.WithHTTPClient(
+			&http.Client{
+				Transport: r2.Transport,
+			}),
+	)
+	require.NoError(t, err)
+	p, err := token.NewParser(c)
+	require.NoError(t, err)
+	svc, ctrl := newFeaturesController(t, p, &http.Client{Transport: r1.Transport}, newClientMock(t))
+
+	t.Run("ok", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when
+		_, catalog := test.CatalogFeaturesOK(t, ctx, svc, ctrl, nil)
+		// then the features with an unknown level are excluded
+		expectedData := []*app.FeatureCatalogLevel{
+			{
+				ID:   featuretoggles.BetaLevel,
+				Type: "feature-catalog-levels",
+				Attributes: &app.FeatureCatalogLevelAttributes{
+					Level: featuretoggles.BetaLevel,
+					Features: []*app.FeatureCatalogEntry{
+						{
+							Name:          multiStrategiesFeature.Name,
+							Description:   multiStrategiesFeature.Description,
+							UserEnabled:   true,
+							RequiredLevel: featuretoggles.BetaLevel,
+						},
+					},
+				},
+			},
+			{
+				ID:   featuretoggles.ReleasedLevel,
+				Type: "feature-catalog-levels",
+				Attributes: &app.FeatureCatalogLevelAttributes{
+					Level: featuretoggles.ReleasedLevel,
+					Features: []*app.FeatureCatalogEntry{
+						{
+							Name:          releasedFeature.Name,
+							Description:   releasedFeature.Description,
+							UserEnabled:   true,
+							RequiredLevel: featuretoggles.ReleasedLevel,
+						},
+					},
+				},
+			},
+		}
+		assert.Equal(t, expectedData, catalog.Data)
+	})
+
+	t.Run("no change", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		res, _ := test.CatalogFeaturesOK(t, ctx, svc, ctrl, nil)
+		require.NotEmpty(t, res.Header()[app.ETag])
+		etag := res.Header()[app.ETag][0]
+		// when/then
+		test.CatalogFeaturesNotModified(t, ctx, svc, ctrl, &etag)
+	})
+}
+
+func TestFeatureTree(t *testing.T) {
+	// given
+	r1, err := recorder.New("../test/data/controller/auth_get_user", recorder.WithMatcher(JWTMatcher()))
+	require.NoError(t, err)
+	defer r1.Stop()
+	r2, err := recorder.New("../test/data/token/auth_get_keys")
+	require.NoError(t, err)
+	c, err := auth.NewClient(
+		context.Background(),
+		"http://authservice",
+		auth.WithHTTPClient(
+			&http.Client{
+				Transport: r2.Transport,
+			}),
+	)
+	require.NoError(t, err)
+	p, err := token.NewParser(c)
+	require.NoError(t, err)
+	svc, ctrl := newFeaturesController(t, p, &http.Client{Transport: r1.Transport}, newClientMock(t))
+
+	t.Run("all features", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when
+		_, tree := test.TreeFeaturesOK(t, ctx, svc, ctrl, nil, nil)
+		// then
+		require.Len(t, tree.Data, 4)
+		assert.Equal(t, "bar", tree.Data[0].ID)
+		assert.Nil(t, tree.Data[0].Attributes.Feature)
+		assert.Equal(t, 1, tree.Data[0].Attributes.EnabledCount)
+		require.Len(t, tree.Data[0].Children, 1)
+		releasedLevel := featuretoggles.ReleasedLevel
+		assert.Equal(t, &app.FeatureTreeNode{
+			ID:   releasedFeature.Name,
+			Type: "feature-tree-nodes",
+			Attributes: &app.FeatureTreeNodeAttributes{
+				Name:         "releasedFeature",
+				FeatureCount: 1,
+				EnabledCount: 1,
+				Feature: &app.UserFeatureAttributes{
+					Description:     releasedFeature.Description,
+					Enabled:         true,
+					EnablementLevel: &releasedLevel,
+					UserEnabled:     true,
+				},
+			},
+		}, tree.Data[0].Children[0])
+		foo := tree.Data[1]
+		assert.Equal(t, "foo", foo.ID)
+		assert.NotNil(t, foo.Attributes.Feature)
+		assert.Equal(t, 4, foo.Attributes.FeatureCount)
+		assert.Equal(t, 1, foo.Attributes.EnabledCount)
+		assert.Len(t, foo.Children, 3)
+		assert.Equal(t, "foobar", tree.Data[2].ID)
+		assert.Equal(t, "wip", tree.Data[3].ID)
+	})
+
+	t.Run("group", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		group := "foo"
+		// when
+		_, tree := test.TreeFeaturesOK(t, ctx, svc, ctrl, &group, nil)
+		// then
+		require.Len(t, tree.Data, 1)
+		assert.Equal(t, "foo", tree.Data[0].ID)
+		require.Len(t, tree.Data[0].Children, 3)
+		assert.Equal(t, disabledFeature.Name, tree.Data[0].Children[0].ID)
+	})
+
+	t.Run("unknown group", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		group := "unknown"
+		// when
+		_, tree := test.TreeFeaturesOK(t, ctx, svc, ctrl, &group, nil)
+		// then
+		assert.Empty(t, tree.Data)
+	})
+
+	t.Run("invalid group", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		group := "foo.*"
+		// when/then
+		test.TreeFeaturesBadRequest(t, ctx, svc, ctrl, &group, nil)
+	})
+
+	t.Run("no change", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		res, _ := test.TreeFeaturesOK(t, ctx, svc, ctrl, nil, nil)
+		require.NotEmpty(t, res.Header()[app.ETag])
+		etag := res.Header()[app.ETag][0]
+		// when/then
+		test.TreeFeaturesNotModified(t, ctx, svc, ctrl, nil, &etag)
+	})
+}
+
+func TestListProblems(t *testing.T) {
+	// given
+	r1, err := recorder.New("../test/data/controller/auth_get_user", recorder.WithMatcher(JWTMatcher()))
+	require.NoError(t, err)
+	defer r1.Stop()
+	r2, err := recorder.New("../test/data/token/auth_get_keys")
+	require.NoError(t, err)
+	c, err := auth.NewClient(
+		context.Background(),
+		"http://authservice",
+		auth.WithHTTPClient(
+			&http.Client{
+				Transport: r2.Transport,
+			}),
+	)
+	require.NoError(t, err)
+	p, err := token.NewParser(c)
+	require.NoError(t, err)
+	svc, ctrl := newFeaturesController(t, p, &http.Client{Transport: r1.Transport}, newClientMock(t))
+
+	t.Run("ok", func(t *testing.T) {
+		// given a service authenticated by its client certificate
+		ctx := tlsconfig.ContextWithServiceIdentity(context.Background(), "fabric8-admin-console")
+		// when
+		_, problems := test.ProblemsFeaturesOK(t, ctx, svc, ctrl)
+		// then
+		expectedData := []*app.FeatureProblem{
+			{
+				ID:   singleStrategyFeature.Name + "/enableByLevel",
+				Type: "feature-problems",
+				Attributes: &app.FeatureProblemAttributes{
+					Feature:  singleStrategyFeature.Name,
+					Strategy: "enableByLevel",
+					Problem:  "invalid 'level' parameter: unknown level 'beat' (expected internal|experimental|beta|released)",
+				},
+			},
+		}
+		assert.Equal(t, expectedData, problems.Data)
+	})
+
+	t.Run("non-admin", func(t *testing.T) {
+		// given the user's email is not verified, hence he/she is not an admin
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when/then
+		test.ProblemsFeaturesForbidden(t, ctx, svc, ctrl)
+	})
+
+	t.Run("anonymous user", func(t *testing.T) {
+		// when/then
+		test.ProblemsFeaturesForbidden(t, context.Background(), svc, ctrl)
+	})
+}
+
+func TestListStaleFeatures(t *testing.T) {
+	// given
+	r1, err := recorder.New("../test/data/controller/auth_get_user", recorder.WithMatcher(JWTMatcher()))
+	require.NoError(t, err)
+	defer r1.Stop()
+	r2, err := recorder.New("../test/data/token/auth_get_keys")
+	require.NoError(t, err)
+	c, err := auth.NewClient(
+		context.Background(),
+		"http://authservice",
+		auth.WithHTTPClient(
+			&http.Client{
+				Transport: r2.Transport,
+			}),
+	)
+	require.NoError(t, err)
+	p, err := token.NewParser(c)
+	require.NoError(t, err)
+	// the feature files with the metadata of the features
+	metadataDir, err := ioutil.TempDir("", "features")
+	require.NoError(t, err)
+	defer os.RemoveAll(metadataDir)
+	err = ioutil.WriteFile(filepath.Join(metadataDir, "features.yaml"), []byte(`
+features:
+- name: `+releasedFeature.Name+`
+  level: released
+  owner: foo-team@foo.com
+  type: release
+  expires: 2018-01-01
+`), 0644)
+	require.NoError(t, err)
+	metadataStore, err := gitops.NewMetadataStore(metadataDir)
+	require.NoError(t, err)
+	svc := goa.New("feature")
+	ctrl := controller.NewFeaturesController(svc, p,
+		&TestFeatureControllerConfig{
+			authServiceURL: "http://auth",
+		},
+		controller.WithHTTPClient(&http.Client{Transport: r1.Transport}),
+		controller.WithTogglesClient(newClientMock(t)),
+		controller.WithMetadataProvider(metadataStore),
+	)
+
+	t.Run("ok", func(t *testing.T) {
+		// given a service authenticated by its client certificate
+		ctx := tlsconfig.ContextWithServiceIdentity(context.Background(), "fabric8-admin-console")
+		unusedDays := 0
+		// when
+		_, staleFeatures := test.StaleFeaturesOK(t, ctx, svc, ctrl, nil, &unusedDays)
+		// then
+		require.Len(t, staleFeatures.Data, 1)
+		assert.Equal(t, releasedFeature.Name+"/expired", staleFeatures.Data[0].ID)
+		assert.Equal(t, "stale-features", staleFeatures.Data[0].Type)
+		assert.Equal(t, "expired", staleFeatures.Data[0].Attributes.Rule)
+		require.NotNil(t, staleFeatures.Data[0].Attributes.Owner)
+		assert.Equal(t, "foo-team@foo.com", *staleFeatures.Data[0].Attributes.Owner)
+		require.NotNil(t, staleFeatures.Data[0].Attributes.FeatureType)
+		assert.Equal(t, "release", *staleFeatures.Data[0].Attributes.FeatureType)
+	})
+
+	t.Run("invalid number of days", func(t *testing.T) {
+		// given
+		ctx := tlsconfig.ContextWithServiceIdentity(context.Background(), "fabric8-admin-console")
+		releasedDays := -1
+		// when/then
+		test.StaleFeaturesBadRequest(t, ctx, svc, ctrl, &releasedDays, nil)
+	})
+
+	t.Run("non-admin", func(t *testing.T) {
+		// given the user's email is not verified, hence he/she is not an admin
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when/then
+		test.StaleFeaturesForbidden(t, ctx, svc, ctrl, nil, nil)
+	})
+}
+
+func TestExportImportFeatures(t *testing.T) {
+	// given
+	r1, err := recorder.New("../test/data/controller/auth_get_user", recorder.WithMatcher(JWTMatcher()))
+	require.NoError(t, err)
+	defer r1.Stop()
+	r2, err := recorder.New("../test/data/token/auth_get_keys")
+	require.NoError(t, err)
+	c, err := auth.NewClient(
+		context.Background(),
+		"http://authservice",
+		auth.WithHTTPClient(
+			&http.Client{
+				Transport: r2.Transport,
+			}),
+	)
+	require.NoError(t, err)
+	p, err := token.NewParser(c)
+	require.NoError(t, err)
+	// the admin API of the toggles server, with a single feature
+	requests := []string{}
+	togglesServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "admin-token" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		if req.Method == http.MethodGet {
+			rw.Write([]byte(`{"features":[{"name":"deploy","enabled":true,"strategies":[{"name":"default"}]}]}`))
+		}
+	}))
+	defer togglesServer.Close()
+	svc := goa.New("feature")
+	ctrl := controller.NewFeaturesController(svc, p,
+		&TestFeatureControllerConfig{
+			authServiceURL: "http://auth",
+			togglesURL:     togglesServer.URL + "/api",
+			adminToken:     "admin-token",
+		},
+		controller.WithHTTPClient(&http.Client{Transport: r1.Transport}),
+		controller.WithTogglesClient(newClientMock(t)),
+	)
+	adminCtx := tlsconfig.ContextWithServiceIdentity(context.Background(), "fabric8-admin-console")
+
+	t.Run("export", func(t *testing.T) {
+		// when
+		_, export := test.ExportFeaturesOK(t, adminCtx, svc, ctrl)
+		// then
+		assert.Equal(t, featuretoggles.ExportVersion, export.Version)
+		require.Len(t, export.Features, 1)
+		assert.Equal(t, releasedFeature.Name, export.Features[0].Name)
+		require.Len(t, export.Features[0].Strategies, 1)
+		assert.Equal(t, featuretoggles.EnableByLevelStrategyName, export.Features[0].Strategies[0].Name)
+	})
+
+	t.Run("import", func(t *testing.T) {
+		// given
+		description := "Imported feature"
+		payload := &app.FeatureRepository{
+			Version: featuretoggles.ExportVersion,
+			Features: []*app.ExportedFeature{
+				{
+					Name:        "planner",
+					Description: &description,
+					Enabled:     true,
+					Strategies: []*app.ExportedStrategy{
+						{
+							Name:       featuretoggles.EnableByLevelStrategyName,
+							Parameters: map[string]interface{}{featuretoggles.LevelParameter: featuretoggles.BetaLevel},
+						},
+					},
+				},
+			},
+		}
+
+		t.Run("dry-run", func(t *testing.T) {
+			// given
+			requests = []string{}
+			// when
+			_, plan := test.ImportFeaturesOK(t, adminCtx, svc, ctrl, nil, nil, payload)
+			// then
+			assert.False(t, plan.Applied)
+			require.Len(t, plan.Changes, 1)
+			assert.Equal(t, "create", plan.Changes[0].Action)
+			assert.Equal(t, "planner", plan.Changes[0].Name)
+			assert.Equal(t, []string{"deploy"}, plan.Unmanaged)
+			assert.Equal(t, []string{"GET /api/admin/features"}, requests)
+		})
+
+		t.Run("apply and prune", func(t *testing.T) {
+			// given
+			requests = []string{}
+			apply, prune := true, true
+			// when
+			_, plan := test.ImportFeaturesOK(t, adminCtx, svc, ctrl, &apply, &prune, payload)
+			// then
+			assert.True(t, plan.Applied)
+			assert.Equal(t, []string{
+				"GET /api/admin/features",
+				"POST /api/admin/features",
+				"DELETE /api/admin/features/deploy",
+			}, requests)
+		})
+
+		t.Run("unsupported version", func(t *testing.T) {
+			// given
+			payload := &app.FeatureRepository{
+				Version:  featuretoggles.ExportVersion + 1,
+				Features: []*app.ExportedFeature{},
+			}
+			// when/then
+			test.ImportFeaturesBadRequest(t, adminCtx, svc, ctrl, nil, nil, payload)
+		})
+
+		t.Run("reserved name", func(t *testing.T) {
+			// given a feature which could not be shown, since its name is the path of another endpoint
+			payload := &app.FeatureRepository{
+				Version: featuretoggles.ExportVersion,
+				Features: []*app.ExportedFeature{
+					{
+						Name:       "catalog",
+						Enabled:    true,
+						Strategies: []*app.ExportedStrategy{},
+					},
+				},
+			}
+			requests = []string{}
+			// when
+			test.ImportFeaturesBadRequest(t, adminCtx, svc, ctrl, nil, nil, payload)
+			// then
+			assert.Empty(t, requests)
+		})
+
+		t.Run("non-admin", func(t *testing.T) {
+			// given the user's email is not verified, hence he/she is not an admin
+			ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+			require.NoError(t, err)
+			// when/then
+			test.ImportFeaturesForbidden(t, ctx, svc, ctrl, nil, nil, payload)
+		})
+	})
+}
+
+func TestUpdateLevel(t *testing.T) {
+	// given
+	r1, err := recorder.New("../test/data/controller/auth_get_user", recorder.WithMatcher(JWTMatcher()))
+	require.NoError(t, err)
+	defer r1.Stop()
+	r2, err := recorder.New("../test/data/token/auth_get_keys")
+	require.NoError(t, err)
+	c, err := auth.NewClient(
+		context.Background(),
+		"http://authservice",
+		auth.WithHTTPClient(
+			&http.Client{
+				Transport: r2.Transport,
+			}),
+	)
+	require.NoError(t, err)
+	p, err := token.NewParser(c)
+	require.NoError(t, err)
+	mockClient := newClientMock(t)
+	var evaluatedLevel string
+	getFeaturesByGroups := mockClient.GetFeaturesByGroupsFunc
+	mockClient.GetFeaturesByGroupsFunc = func(ctx context.Context, groups []string, glob bool, user *authclient.User) []featuretoggles.UserFeature {
+		evaluatedLevel = *user.Data.Attributes.FeatureLevel
+		return getFeaturesByGroups(ctx, groups, glob, user)
+	}
+	authRequests := &payloadRecorder{transport: r1.Transport}
+	svc, ctrl := newFeaturesController(t, p, &http.Client{Transport: authRequests}, mockClient)
+
+	newPayload := func(level string) *app.UserFeatureLevel {
+		return &app.UserFeatureLevel{
+			Data: &app.UserFeatureLevelData{
+				Type: "feature-levels",
+				Attributes: &app.UserFeatureLevelAttributes{
+					Level: level,
+				},
+			},
+		}
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when
+		_, featuresList := test.UpdateLevelFeaturesOK(t, ctx, svc, ctrl, newPayload("Experimental"))
+		// then the level is sent in lower case, and all the features are evaluated with the new level
+		require.NotEmpty(t, authRequests.payloads)
+		assert.Contains(t, authRequests.payloads[len(authRequests.payloads)-1], `"featureLevel":"experimental"`)
+		assert.Equal(t, featuretoggles.ExperimentalLevel, evaluatedLevel)
+		require.NotNil(t, featuresList)
+		names := make([]string, len(featuresList.Data))
+		for i, f := range featuresList.Data {
+			names[i] = f.ID
+		}
+		assert.Contains(t, names, releasedFeature.Name)
+		assert.Contains(t, names, devFeature.Name)
+	})
+
+	t.Run("unknown level", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when/then
+		test.UpdateLevelFeaturesBadRequest(t, ctx, svc, ctrl, newPayload("beat"))
+	})
+
+	t.Run("internal level for external user", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when/then
+		test.UpdateLevelFeaturesForbidden(t, ctx, svc, ctrl, newPayload(featuretoggles.InternalLevel))
+	})
+
+	t.Run("anonymous user", func(t *testing.T) {
+		// when/then
+		test.UpdateLevelFeaturesUnauthorized(t, context.Background(), svc, ctrl, newPayload(featuretoggles.BetaLevel))
+	})
+}
+
+func TestEnrollment(t *testing.T) {
+	// given
+	r1, err := recorder.New("../test/data/controller/auth_get_user", recorder.WithMatcher(JWTMatcher()))
+	require.NoError(t, err)
+	defer r1.Stop()
+	r2, err := recorder.New("../test/data/token/auth_get_keys")
+	require.NoError(t, err)
+	c, err := auth.NewClient(
+		context.Background(),
+		"http://authservice",
+		auth.WithHTTPClient(
+			&http.Client{
+				Transport: r2.Transport,
+			}),
+	)
+	require.NoError(t, err)
+	p, err := token.NewParser(c)
+	require.NoError(t, err)
+	togglesClient := featuretoggles.NewClientWithState(featuretoggles.NewSnapshot([]unleashapi.Feature{
+		{
+			Name:        "experimental.selfEnrollment",
+			Description: "Experimental feature open to self-enrollment",
+			Enabled:     true,
+			Strategies: []unleashapi.Strategy{
+				{
+					Name: featuretoggles.EnableByLevelStrategyName,
+					Parameters: map[string]interface{}{
+						featuretoggles.LevelParameter:          featuretoggles.ExperimentalLevel,
+						featuretoggles.SelfEnrollmentParameter: "true",
+					},
+				},
+			},
+		},
+		{
+			Name:        "beta.closed",
+			Description: "Beta feature closed to self-enrollment",
+			Enabled:     true,
+			Strategies: []unleashapi.Strategy{
+				{
+					Name: featuretoggles.EnableByLevelStrategyName,
+					Parameters: map[string]interface{}{
+						featuretoggles.LevelParameter: featuretoggles.BetaLevel,
+					},
+				},
+			},
+		},
+	}), true)
+	svc, ctrl := newFeaturesController(t, p, &http.Client{Transport: r1.Transport}, togglesClient)
+
+	newPayload := func(enrollment featuretoggles.Enrollment) *app.UserFeatureEnrollment {
+		return &app.UserFeatureEnrollment{
+			Data: &app.UserFeatureEnrollmentData{
+				Type: "enrollments",
+				Attributes: &app.UserFeatureEnrollmentAttributes{
+					Enrollment: string(enrollment),
+				},
+			},
+		}
+	}
+
+	t.Run("opt-in", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when
+		_, appFeature := test.EnrollFeaturesOK(t, ctx, svc, ctrl, "experimental.selfEnrollment", newPayload(featuretoggles.OptIn))
+		// then
+		require.NotNil(t, appFeature)
+		assert.True(t, appFeature.Data.Attributes.UserEnabled)
+		require.NotNil(t, appFeature.Data.Attributes.UserOverride)
+		assert.Equal(t, string(featuretoggles.OptIn), *appFeature.Data.Attributes.UserOverride)
+
+		t.Run("unenroll", func(t *testing.T) {
+			// when
+			_, appFeature := test.UnenrollFeaturesOK(t, ctx, svc, ctrl, "experimental.selfEnrollment")
+			// then
+			require.NotNil(t, appFeature)
+			assert.False(t, appFeature.Data.Attributes.UserEnabled)
+			assert.Nil(t, appFeature.Data.Attributes.UserOverride)
+		})
+	})
+
+	t.Run("opt-out", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when
+		_, appFeature := test.EnrollFeaturesOK(t, ctx, svc, ctrl, "beta.closed", newPayload(featuretoggles.OptOut))
+		// then
+		require.NotNil(t, appFeature)
+		assert.False(t, appFeature.Data.Attributes.UserEnabled)
+		require.NotNil(t, appFeature.Data.Attributes.UserOverride)
+		assert.Equal(t, string(featuretoggles.OptOut), *appFeature.Data.Attributes.UserOverride)
+
+		t.Run("unenroll", func(t *testing.T) {
+			// when
+			_, appFeature := test.UnenrollFeaturesOK(t, ctx, svc, ctrl, "beta.closed")
+			// then
+			require.NotNil(t, appFeature)
+			assert.True(t, appFeature.Data.Attributes.UserEnabled)
+			assert.Nil(t, appFeature.Data.Attributes.UserOverride)
+		})
+	})
+
+	t.Run("feature does not allow self-enrollment", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when/then
+		test.EnrollFeaturesForbidden(t, ctx, svc, ctrl, "beta.closed", newPayload(featuretoggles.OptIn))
+	})
+
+	t.Run("unknown feature", func(t *testing.T) {
+		// given
+		ctx, err := createValidContext("../test/private_key.pem", "user_beta_level", time.Now().Add(1*time.Hour))
+		require.NoError(t, err)
+		// when/then
+		test.EnrollFeaturesNotFound(t, ctx, svc, ctrl, "unknown", newPayload(featuretoggles.OptOut))
+		test.UnenrollFeaturesNotFound(t, ctx, svc, ctrl, "unknown")
+	})
+
+	t.Run("anonymous user", func(t *testing.T) {
+		// when/then
+		test.EnrollFeaturesUnauthorized(t, context.Background(), svc, ctrl, "experimental.selfEnrollment", newPayload(featuretoggles.OptIn))
+		test.UnenrollFeaturesUnauthorized(t, context.Background(), svc, ctrl, "experimental.selfEnrollment")
+	})
+}
+
+// payloadRecorder a transport which records the payloads of the requests, then sends them with the underlying transport
+type payloadRecorder struct {
+	transport http.RoundTripper
+	payloads  []string
+}
+
+func (r *payloadRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body == nil {
+		return r.transport.RoundTrip(req)
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body.Close()
+	r.payloads = append(r.payloads, string(body))
+	// do not modify the given request (see `http.RoundTripper`)
+	replay := *req
+	replay.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return r.transport.RoundTrip(&replay)
+}
+
 // JWTMatcher a cassette matcher that verifies the request method/URL and the subject of the token in the "Authorization" header.
 func JWTMatcher() cassette.Matcher {
 	return func(httpRequest *http.Request, cassetteRequest cassette.Request) bool {
@@ -1,9 +1,17 @@
 package controller
 
 import (
+	"context"
+	"net/http"
 	"time"
 
+	"github.com/fabric8-services/fabric8-auth/goasupport"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-auth/token"
 	"github.com/fabric8-services/fabric8-toggles-service/app"
+	"github.com/fabric8-services/fabric8-toggles-service/auth"
+	authclient "github.com/fabric8-services/fabric8-toggles-service/auth/client"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
 	"github.com/goadesign/goa"
 )
 
@@ -16,27 +24,71 @@ var (
 	StartTime = time.Now().UTC().Format("2006-01-02T15:04:05Z")
 )
 
+// authCheckTimeout the maximum duration of the call to the auth service when checking the readiness
+const authCheckTimeout = 2 * time.Second
+
 // StatusConfiguration the status configuration
 type StatusConfiguration interface {
 	IsDeveloperModeEnabled() bool
+	GetAuthServiceURL() string
 }
 
 // StatusController implements the status resource.
 type StatusController struct {
 	*goa.Controller
-	config StatusConfiguration
+	config        StatusConfiguration
+	togglesClient featuretoggles.Client
+	tokenParser   token.Parser
+	httpClient    *http.Client
+}
+
+// StatusControllerOption an option to customize the StatusController
+type StatusControllerOption func(*StatusController)
+
+// WithTogglesState configures the toggles client whose state is checked for the readiness
+func WithTogglesState(client featuretoggles.Client) StatusControllerOption {
+	return func(c *StatusController) {
+		c.togglesClient = client
+	}
+}
+
+// WithTokenParser configures the token parser whose keys are checked for the readiness
+func WithTokenParser(parser token.Parser) StatusControllerOption {
+	return func(c *StatusController) {
+		c.tokenParser = parser
+	}
+}
+
+// WithStatusHTTPClient uses a custom http client to check whether the auth service is reachable
+func WithStatusHTTPClient(client *http.Client) StatusControllerOption {
+	return func(c *StatusController) {
+		c.httpClient = client
+	}
 }
 
 // NewStatusController creates a status controller.
-func NewStatusController(service *goa.Service, config StatusConfiguration) *StatusController {
-	return &StatusController{
+func NewStatusController(service *goa.Service, config StatusConfiguration, options ...StatusControllerOption) *StatusController {
+	c := StatusController{
 		Controller: service.NewController("StatusController"),
 		config:     config,
 	}
+	for _, opt := range options {
+		opt(&c)
+	}
+	return &c
 }
 
 // Show runs the show action.
 func (c *StatusController) Show(ctx *app.ShowStatusContext) error {
+	return ctx.OK(c.status())
+}
+
+// Liveness runs the liveness action. The instance is alive as long as it can respond, regardless of its dependencies.
+func (c *StatusController) Liveness(ctx *app.LivenessStatusContext) error {
+	return ctx.OK(c.status())
+}
+
+func (c *StatusController) status() *app.Status {
 	res := &app.Status{
 		Commit:    Commit,
 		BuildTime: BuildTime,
@@ -46,5 +98,65 @@ func (c *StatusController) Show(ctx *app.ShowStatusContext) error {
 	if devMode {
 		res.DevMode = &devMode
 	}
+	return res
+}
+
+// Readiness runs the readiness action.
+func (c *StatusController) Readiness(ctx *app.ReadinessStatusContext) error {
+	res := &app.Readiness{}
+	reasons := []string{}
+	if c.togglesClient != nil {
+		state := c.togglesClient.State()
+		res.TogglesReady = state.Ready
+		res.FeatureCount = state.FeatureCount
+		if !state.LastRefresh.IsZero() {
+			res.TogglesLastRefresh = &state.LastRefresh
+		}
+	}
+	if !res.TogglesReady {
+		reasons = append(reasons, "toggles client is not ready")
+	}
+	if c.tokenParser != nil {
+		res.TokenKeyCount = len(c.tokenParser.PublicKeys())
+	}
+	if res.TokenKeyCount == 0 {
+		reasons = append(reasons, "no public key loaded to verify the tokens")
+	}
+	// the instance remains ready if the auth service is not reachable: the features can still be evaluated for the anonymous
+	// users and the tokens verified with the loaded keys, while taking all the instances out of the service would only turn
+	// an outage of the auth service into an outage of this service
+	res.AuthReachable = c.isAuthReachable(ctx)
+	if !res.AuthReachable {
+		log.Warn(ctx, map[string]interface{}{}, "auth service is not reachable")
+	}
+	res.Ready = len(reasons) == 0
+	if !res.Ready {
+		res.Reasons = reasons
+		log.Warn(ctx, map[string]interface{}{"reasons": reasons}, "service is not ready")
+		return ctx.ServiceUnavailable(res)
+	}
 	return ctx.OK(res)
 }
+
+// isAuthReachable returns `true` if the auth service responded to a status request
+func (c *StatusController) isAuthReachable(ctx context.Context) bool {
+	options := []auth.ClientConfigOption{}
+	if c.httpClient != nil {
+		options = append(options, auth.WithHTTPClient(c.httpClient))
+	}
+	authClient, err := auth.NewClient(ctx, c.config.GetAuthServiceURL(), options...)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{"err": err.Error()}, "unable to initialize auth service client")
+		return false
+	}
+	checkCtx, cancel := context.WithTimeout(goasupport.ForwardContextRequestID(ctx), authCheckTimeout)
+	defer cancel()
+	res, err := authClient.ShowStatus(checkCtx, authclient.ShowStatusPath())
+	if err != nil {
+		log.Warn(ctx, map[string]interface{}{"err": err.Error()}, "unable to reach the auth service")
+		return false
+	}
+	defer res.Body.Close()
+	// the auth service may answer `503 Service Unavailable` with its own status if its database is not reachable
+	return res.StatusCode == http.StatusOK
+}
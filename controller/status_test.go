@@ -0,0 +1,131 @@
+package controller_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-toggles-service/app/test"
+	"github.com/fabric8-services/fabric8-toggles-service/controller"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	testfeaturetoggles "github.com/fabric8-services/fabric8-toggles-service/test/featuretoggles"
+	testtoken "github.com/fabric8-services/fabric8-toggles-service/test/token"
+	"github.com/goadesign/goa"
+	"github.com/stretchr/testify/assert"
+)
+
+type TestStatusControllerConfig struct {
+	authServiceURL string
+}
+
+func (c *TestStatusControllerConfig) IsDeveloperModeEnabled() bool {
+	return false
+}
+
+func (c *TestStatusControllerConfig) GetAuthServiceURL() string {
+	return c.authServiceURL
+}
+
+func newStatusController(t *testing.T, authServiceURL string, state featuretoggles.State, keys []*rsa.PublicKey) (*goa.Service, *controller.StatusController) {
+	mockClient := testfeaturetoggles.NewClientMock(t)
+	mockClient.StateFunc = func() featuretoggles.State {
+		return state
+	}
+	mockParser := testtoken.NewParserMock(t)
+	mockParser.PublicKeysFunc = func() []*rsa.PublicKey {
+		return keys
+	}
+	svc := goa.New("status")
+	ctrl := controller.NewStatusController(svc,
+		&TestStatusControllerConfig{
+			authServiceURL: authServiceURL,
+		},
+		controller.WithTogglesState(mockClient),
+		controller.WithTokenParser(mockParser),
+	)
+	return svc, ctrl
+}
+
+func TestLiveness(t *testing.T) {
+	// given
+	svc, ctrl := newStatusController(t, "http://auth", featuretoggles.State{}, nil)
+	// when
+	_, status := test.LivenessStatusOK(t, context.Background(), svc, ctrl)
+	// then
+	assert.Equal(t, controller.Commit, status.Commit)
+}
+
+func TestReadiness(t *testing.T) {
+	// given
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/status" {
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"commit":"0","buildTime":"0","startTime":"0"}`))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer authService.Close()
+	lastRefresh := time.Now()
+	readyState := featuretoggles.State{
+		Ready:        true,
+		LastRefresh:  lastRefresh,
+		FeatureCount: 3,
+	}
+	keys := []*rsa.PublicKey{{}}
+
+	t.Run("ready", func(t *testing.T) {
+		// given
+		svc, ctrl := newStatusController(t, authService.URL, readyState, keys)
+		// when
+		_, readiness := test.ReadinessStatusOK(t, context.Background(), svc, ctrl)
+		// then
+		assert.True(t, readiness.Ready)
+		assert.True(t, readiness.TogglesReady)
+		assert.True(t, readiness.AuthReachable)
+		assert.Equal(t, 3, readiness.FeatureCount)
+		assert.Equal(t, 1, readiness.TokenKeyCount)
+		assert.Equal(t, &lastRefresh, readiness.TogglesLastRefresh)
+		assert.Empty(t, readiness.Reasons)
+	})
+
+	t.Run("not ready", func(t *testing.T) {
+
+		t.Run("toggles client not ready", func(t *testing.T) {
+			// given
+			svc, ctrl := newStatusController(t, authService.URL, featuretoggles.State{}, keys)
+			// when
+			_, readiness := test.ReadinessStatusServiceUnavailable(t, context.Background(), svc, ctrl)
+			// then
+			assert.False(t, readiness.Ready)
+			assert.False(t, readiness.TogglesReady)
+			assert.Nil(t, readiness.TogglesLastRefresh)
+			assert.Equal(t, []string{"toggles client is not ready"}, readiness.Reasons)
+		})
+
+		t.Run("no token key", func(t *testing.T) {
+			// given
+			svc, ctrl := newStatusController(t, authService.URL, readyState, nil)
+			// when
+			_, readiness := test.ReadinessStatusServiceUnavailable(t, context.Background(), svc, ctrl)
+			// then
+			assert.False(t, readiness.Ready)
+			assert.Equal(t, 0, readiness.TokenKeyCount)
+			assert.Equal(t, []string{"no public key loaded to verify the tokens"}, readiness.Reasons)
+		})
+	})
+
+	t.Run("auth service not reachable", func(t *testing.T) {
+		// given
+		svc, ctrl := newStatusController(t, "http://localhost:1", readyState, keys)
+		// when
+		_, readiness := test.ReadinessStatusOK(t, context.Background(), svc, ctrl)
+		// then the instance is still ready
+		assert.True(t, readiness.Ready)
+		assert.False(t, readiness.AuthReachable)
+		assert.Empty(t, readiness.Reasons)
+	})
+}
@@ -54,6 +54,7 @@ var _ = a.API("feature", func() {
 			a.Header("Last-Modified", d.DateTime)
 			a.Header("ETag")
 			a.Header("Cache-Control")
+			a.Header("X-Features-Version")
 		})
 	})
 
@@ -43,9 +43,310 @@ var userFeatureAttributes = a.Type("UserFeatureAttributes", func() {
 	a.Attribute("enablement-level", d.String, "The mimimum level of enablement for this feature. Empty/missing means that the feature is not accessible to the user", func() {
 		a.Example("beta")
 	})
+	a.Attribute("preview", d.Boolean, "marks if the feature was evaluated for another user, on behalf of an admin", func() {
+		a.Example(false)
+	})
+	a.Attribute("user-override", d.String, "The user's enrollment which overrode the user enablement, if any", func() {
+		a.Enum("opt-in", "opt-out")
+		a.Example("opt-in")
+	})
+	a.Attribute("tags", a.ArrayOf(d.String), "The tags of the feature, if any", func() {
+		a.Example([]string{"ui", "planner"})
+	})
+	a.Attribute("blocked-by", a.ArrayOf(d.String), "The prerequisites of the feature which are not enabled for the current user, and which hence disabled the feature", func() {
+		a.Example([]string{"planner.board"})
+	})
 	a.Required("description", "enabled", "user-enabled")
 })
 
+var userFeatureEnrollment = a.Type("UserFeatureEnrollment", func() {
+	a.Description(`JSONAPI for the user's enrollment in a feature. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("data", userFeatureEnrollmentData)
+	a.Required("data")
+})
+
+var userFeatureEnrollmentData = a.Type("UserFeatureEnrollmentData", func() {
+	a.Attribute("type", d.String, "the 'enrollments' type", func() {
+		a.Example("enrollments")
+	})
+	a.Attribute("attributes", userFeatureEnrollmentAttributes)
+	a.Required("type", "attributes")
+})
+
+var userFeatureEnrollmentAttributes = a.Type("UserFeatureEnrollmentAttributes", func() {
+	a.Description(`JSONAPI store for all the "attributes" of an enrollment.`)
+	a.Attribute("enrollment", d.String, "Whether the user joins or leaves the feature, regardless of his/her feature level", func() {
+		a.Enum("opt-in", "opt-out")
+		a.Example("opt-in")
+	})
+	a.Required("enrollment")
+})
+
+var userFeatureLevel = a.Type("UserFeatureLevel", func() {
+	a.Description(`JSONAPI for the user's level of features. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("data", userFeatureLevelData)
+	a.Required("data")
+})
+
+var userFeatureLevelData = a.Type("UserFeatureLevelData", func() {
+	a.Attribute("type", d.String, "the 'feature-levels' type", func() {
+		a.Example("feature-levels")
+	})
+	a.Attribute("attributes", userFeatureLevelAttributes)
+	a.Required("type", "attributes")
+})
+
+var userFeatureLevelAttributes = a.Type("UserFeatureLevelAttributes", func() {
+	a.Description(`JSONAPI store for all the "attributes" of a user's level of features.`)
+	a.Attribute("level", d.String, "The level of features that the user opts-in for", func() {
+		a.Example("beta")
+	})
+	a.Required("level")
+})
+
+var featureProblemList = JSONList(
+	"FeatureProblem", "Holds the list of misconfigured feature strategies",
+	featureProblem,
+	nil,
+	nil)
+
+var featureProblem = a.Type("FeatureProblem", func() {
+	a.Description(`JSONAPI for a misconfigured strategy of a feature. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("id", d.String, "Id of the problem (the feature and strategy names)", func() {
+		a.Example("Feature name/enableByLevel")
+	})
+	a.Attribute("type", d.String, "the 'feature-problems' type", func() {
+		a.Example("feature-problems")
+	})
+	a.Attribute("attributes", featureProblemAttributes)
+	a.Required("id", "type", "attributes")
+})
+
+var featureProblemAttributes = a.Type("FeatureProblemAttributes", func() {
+	a.Description(`JSONAPI store for all the "attributes" of a misconfigured strategy of a feature.`)
+	a.Attribute("feature", d.String, "The name of the feature", func() {
+		a.Example("Feature name")
+	})
+	a.Attribute("strategy", d.String, "The name of the misconfigured strategy", func() {
+		a.Example("enableByLevel")
+	})
+	a.Attribute("problem", d.String, "The description of the problem. The strategy is never enabled until the problem is fixed.", func() {
+		a.Example("unknown level 'beat'")
+	})
+	a.Required("feature", "strategy", "problem")
+})
+
+var featureCatalogList = JSONList(
+	"FeatureCatalogLevel", "Holds the catalog of features, grouped by level",
+	featureCatalogLevel,
+	nil,
+	nil)
+
+var featureCatalogLevel = a.Type("FeatureCatalogLevel", func() {
+	a.Description(`JSONAPI for the features available at a level. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("id", d.String, "Id of the level (the level name)", func() {
+		a.Example("beta")
+	})
+	a.Attribute("type", d.String, "the 'feature-catalog-levels' type", func() {
+		a.Example("feature-catalog-levels")
+	})
+	a.Attribute("attributes", featureCatalogLevelAttributes)
+	a.Required("id", "type", "attributes")
+})
+
+var featureCatalogLevelAttributes = a.Type("FeatureCatalogLevelAttributes", func() {
+	a.Description(`JSONAPI store for all the "attributes" of a level of the catalog.`)
+	a.Attribute("level", d.String, "The level of the features", func() {
+		a.Enum("internal", "experimental", "beta", "released")
+		a.Example("beta")
+	})
+	a.Attribute("features", a.ArrayOf(featureCatalogEntry), "The features available at this level, sorted by name")
+	a.Required("level", "features")
+})
+
+var featureCatalogEntry = a.Type("FeatureCatalogEntry", func() {
+	a.Description(`A feature of the catalog, with the current user's access to it`)
+	a.Attribute("name", d.String, "The name of the feature", func() {
+		a.Example("Feature name")
+	})
+	a.Attribute("description", d.String, "The description of the feature", func() {
+		a.Example("Description of the feature")
+	})
+	a.Attribute("user-enabled", d.Boolean, "marks if the current user has access to the feature", func() {
+		a.Example(false)
+	})
+	a.Attribute("required-level", d.String, "The level of features that the user needs to access the feature", func() {
+		a.Example("beta")
+	})
+	a.Attribute("user-override", d.String, "The user's enrollment which overrode the user enablement, if any", func() {
+		a.Enum("opt-in", "opt-out")
+		a.Example("opt-in")
+	})
+	a.Required("name", "description", "user-enabled", "required-level")
+})
+
+var featureTreeNodeList = JSONList(
+	"FeatureTreeNode", "Holds the hierarchy of the features",
+	featureTreeNode,
+	nil,
+	nil)
+
+var featureTreeNode = a.Type("FeatureTreeNode", func() {
+	a.Description(`JSONAPI for a node of the hierarchy of the features, in which the features are grouped by the dot-separated segments of their names.
+See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("id", d.String, "Id of the node (the full name of the node)", func() {
+		a.Example("planner.board")
+	})
+	a.Attribute("type", d.String, "the 'feature-tree-nodes' type", func() {
+		a.Example("feature-tree-nodes")
+	})
+	a.Attribute("attributes", featureTreeNodeAttributes)
+	a.Attribute("children", a.ArrayOf("FeatureTreeNode"), "The child nodes, sorted by name")
+	a.Required("id", "type", "attributes")
+})
+
+var featureTreeNodeAttributes = a.Type("FeatureTreeNodeAttributes", func() {
+	a.Description(`JSONAPI store for all the "attributes" of a node of the hierarchy of the features.`)
+	a.Attribute("name", d.String, "The last segment of the full name of the node", func() {
+		a.Example("board")
+	})
+	a.Attribute("feature", userFeatureAttributes, "The feature named after the node, evaluated for the current user (missing if the node only groups other features)")
+	a.Attribute("feature-count", d.Integer, "The number of features in the sub-tree of the node, including the feature of the node itself", func() {
+		a.Example(12)
+	})
+	a.Attribute("enabled-count", d.Integer, "The number of features enabled for the current user in the sub-tree of the node, including the feature of the node itself", func() {
+		a.Example(5)
+	})
+	a.Required("name", "feature-count", "enabled-count")
+})
+
+var staleFeatureList = JSONList(
+	"StaleFeature", "Holds the list of stale features",
+	staleFeature,
+	nil,
+	nil)
+
+var staleFeature = a.Type("StaleFeature", func() {
+	a.Description(`JSONAPI for the reason why a feature is stale. See also http://jsonapi.org/format/#document-resource-object`)
+	a.Attribute("id", d.String, "Id of the stale feature (the feature name and the rule)", func() {
+		a.Example("Feature name/expired")
+	})
+	a.Attribute("type", d.String, "the 'stale-features' type", func() {
+		a.Example("stale-features")
+	})
+	a.Attribute("attributes", staleFeatureAttributes)
+	a.Required("id", "type", "attributes")
+})
+
+var staleFeatureAttributes = a.Type("StaleFeatureAttributes", func() {
+	a.Description(`JSONAPI store for all the "attributes" of a stale feature.`)
+	a.Attribute("feature", d.String, "The name of the feature", func() {
+		a.Example("Feature name")
+	})
+	a.Attribute("rule", d.String, "The reason why the feature is stale", func() {
+		a.Enum("expired", "released", "unused")
+		a.Example("expired")
+	})
+	a.Attribute("message", d.String, "The description of the reason", func() {
+		a.Example("expired on 2018-08-01 (31 days ago)")
+	})
+	a.Attribute("owner", d.String, "The owner of the feature, if known", func() {
+		a.Example("planner-team@redhat.com")
+	})
+	a.Attribute("feature-type", d.String, "The type of the feature, if known", func() {
+		a.Enum("release", "experiment", "ops", "permission")
+		a.Example("release")
+	})
+	a.Required("feature", "rule", "message")
+})
+
+var exportedStrategy = a.Type("ExportedStrategy", func() {
+	a.Description(`A strategy of an exported feature`)
+	a.Attribute("name", d.String, "The name of the strategy", func() {
+		a.Example("enableByLevel")
+	})
+	a.Attribute("parameters", a.HashOf(d.String, d.Any), "The parameters of the strategy")
+	a.Required("name")
+})
+
+var exportedFeature = a.Type("ExportedFeature", func() {
+	a.Description(`An exported feature, with its strategies and their parameters`)
+	a.Attribute("name", d.String, "The name of the feature", func() {
+		a.Example("Feature name")
+	})
+	a.Attribute("description", d.String, "The description of the feature", func() {
+		a.Example("Description of the feature")
+	})
+	a.Attribute("enabled", d.Boolean, "marks if the feature is globally enabled (prior to applying strategies)", func() {
+		a.Example(true)
+	})
+	a.Attribute("strategies", a.ArrayOf(exportedStrategy), "The strategies of the feature")
+	a.Required("name", "enabled", "strategies")
+})
+
+// featureRepositoryAttributes the attributes of an exported feature repository
+var featureRepositoryAttributes = func() {
+	a.Attribute("version", d.Integer, "The version of the format of the document", func() {
+		a.Example(1)
+	})
+	a.Attribute("exportedAt", d.DateTime, "The time when the features were exported")
+	a.Attribute("features", a.ArrayOf(exportedFeature), "The exported features, sorted by name")
+	a.Required("version", "features")
+}
+
+var featureRepository = a.Type("FeatureRepository", func() {
+	a.Description(`A versioned copy of all the features of a repository, as produced by the export`)
+	featureRepositoryAttributes()
+})
+
+var featureExport = a.MediaType("application/vnd.featureexport+json", func() {
+	a.Description(`A versioned copy of all the features of a repository, with their strategies and parameters`)
+	a.TypeName("FeatureExport")
+	a.Attributes(featureRepositoryAttributes)
+	a.View("default", func() {
+		a.Attribute("version")
+		a.Attribute("exportedAt")
+		a.Attribute("features")
+	})
+})
+
+var featureChange = a.Type("FeatureChange", func() {
+	a.Description(`A change to apply on a feature of the toggles server`)
+	a.Attribute("action", d.String, "The action to apply on the feature", func() {
+		a.Enum("create", "update", "archive")
+		a.Example("update")
+	})
+	a.Attribute("name", d.String, "The name of the feature", func() {
+		a.Example("Feature name")
+	})
+	a.Attribute("diff", a.ArrayOf(d.String), "The differences between the current and the imported feature")
+	a.Required("action", "name")
+})
+
+var featureImportPlan = a.MediaType("application/vnd.featureimportplan+json", func() {
+	a.Description(`The changes to apply on the toggles server to import a feature repository`)
+	a.TypeName("FeatureImportPlan")
+	a.Attributes(func() {
+		a.Attribute("changes", a.ArrayOf(featureChange), "The changes to apply")
+		a.Attribute("unmanaged", a.ArrayOf(d.String), "The features of the toggles server which are not in the imported repository (archived only when pruning)")
+		a.Attribute("applied", d.Boolean, "'True' if the changes were applied")
+		a.Required("changes", "applied")
+	})
+	a.View("default", func() {
+		a.Attribute("changes")
+		a.Attribute("unmanaged")
+		a.Attribute("applied")
+	})
+})
+
+// previewParams the admin-only parameters to evaluate the features as another user
+var previewParams = func() {
+	a.Param("preview-level", d.String, "evaluate the features with the given user level (admins only)")
+	a.Param("preview-email", d.String, "evaluate the features with the given user email (admins only)")
+	a.Param("preview-internal", d.Boolean, "evaluate the features as an internal or external user (admins only)")
+	a.Param("preview-user", d.String, "evaluate the features as the user with the given ID (admins only)")
+}
+
 var _ = a.Resource("features", func() {
 	a.BasePath("/features")
 
@@ -55,13 +356,15 @@ var _ = a.Resource("features", func() {
 		)
 		a.Params(func() {
 			a.Param("featureName", d.String, "featureName")
+			previewParams()
 		})
-		a.Description("Show feature details.")
+		a.Description("Show feature details. The features cannot be named after the other endpoints of this resource (catalog, export, problems, stale or tree).")
 		a.UseTrait("conditional")
 		a.Response(d.OK, userFeatureSingle)
 		a.Response(d.NotModified)
 		a.Response(d.BadRequest, JSONAPIErrors)
 		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
 		a.Response(d.InternalServerError, JSONAPIErrors)
 	})
 
@@ -71,8 +374,12 @@ var _ = a.Resource("features", func() {
 		)
 		a.Params(func() {
 			a.Param("names", a.ArrayOf(d.String), "names")
-			a.Param("group", d.String, "group")
+			a.Param("group", a.ArrayOf(d.String), "groups of features: dotted names (e.g. 'planner.board') which match the feature with the same name and its sub-features")
+			a.Param("glob", d.Boolean, "interpret the groups as glob patterns, in which '*' matches any part of a name segment (e.g. 'planner.*' or '*.beta')")
 			a.Param("strategy", d.String, "strategy")
+			a.Param("tag", a.ArrayOf(d.String), "tags of the features: each value is a comma-separated list of alternative tags (OR), and all the values must match (AND)")
+			a.Param("level", a.ArrayOf(d.String), "enablement levels of the features (internal, experimental, beta or released)")
+			previewParams()
 		})
 		a.Description("Show a list of features by their names.")
 		a.UseTrait("conditional")
@@ -80,6 +387,135 @@ var _ = a.Resource("features", func() {
 		a.Response(d.NotModified)
 		a.Response(d.BadRequest, JSONAPIErrors)
 		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("catalog", func() {
+		a.Routing(
+			a.GET("/catalog"),
+		)
+		a.Description("Show the catalog of features, grouped by their enablement level, with the current user's access to them.")
+		a.UseTrait("conditional")
+		a.Response(d.OK, featureCatalogList)
+		a.Response(d.NotModified)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("tree", func() {
+		a.Routing(
+			a.GET("/tree"),
+		)
+		a.Params(func() {
+			a.Param("group", d.String, "the group of features (a dotted name, e.g. 'planner') whose sub-tree is returned (all the features if not set)")
+		})
+		a.Description("Show the hierarchy of the features, evaluated for the current user.")
+		a.UseTrait("conditional")
+		a.Response(d.OK, featureTreeNodeList)
+		a.Response(d.NotModified)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("problems", func() {
+		a.Routing(
+			a.GET("/problems"),
+		)
+		a.Description("List the misconfigured strategies of the features (admins only).")
+		a.Response(d.OK, featureProblemList)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("stale", func() {
+		a.Routing(
+			a.GET("/stale"),
+		)
+		a.Params(func() {
+			a.Param("released-days", d.Integer, "number of days after which a feature released to everyone is stale (90 by default, 0 to disable)")
+			a.Param("unused-days", d.Integer, "number of days after which a feature which was not evaluated is stale (30 by default, 0 to disable)")
+		})
+		a.Description("List the stale features: the features past their expiry date, released to everyone for a long time or not evaluated recently for the users (the evaluations are recorded in memory by each instance of the service, since it last started, so the other replicas may still use these features) (admins only).")
+		a.Response(d.OK, staleFeatureList)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("export", func() {
+		a.Routing(
+			a.GET("/export"),
+		)
+		a.Description("Export all the features, with their strategies and parameters (admins only).")
+		a.Response(d.OK, featureExport)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("import", func() {
+		a.Routing(
+			a.POST("/import"),
+		)
+		a.Params(func() {
+			a.Param("apply", d.Boolean, "apply the changes (only show them otherwise)")
+			a.Param("prune", d.Boolean, "archive the features which are not in the imported repository")
+		})
+		a.Description("Import a feature repository in the toggles server (admins only).")
+		a.Payload(featureRepository)
+		a.Response(d.OK, featureImportPlan)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("updateLevel", func() {
+		a.Routing(
+			a.PATCH("/level"),
+		)
+		a.Description("Change the current user's level of features and return all the features, evaluated with the new level.")
+		a.Payload(userFeatureLevel)
+		a.Response(d.OK, userFeatureList)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("enroll", func() {
+		a.Routing(
+			a.PUT("/:featureName/enrollment"),
+		)
+		a.Params(func() {
+			a.Param("featureName", d.String, "featureName")
+		})
+		a.Description("Join or leave a feature for the current user, regardless of his/her feature level.")
+		a.Payload(userFeatureEnrollment)
+		a.Response(d.OK, userFeatureSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.Forbidden, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
+		a.Response(d.InternalServerError, JSONAPIErrors)
+	})
+
+	a.Action("unenroll", func() {
+		a.Routing(
+			a.DELETE("/:featureName/enrollment"),
+		)
+		a.Params(func() {
+			a.Param("featureName", d.String, "featureName")
+		})
+		a.Description("Remove the current user's enrollment in a feature, so that his/her feature level applies again.")
+		a.Response(d.OK, userFeatureSingle)
+		a.Response(d.BadRequest, JSONAPIErrors)
+		a.Response(d.Unauthorized, JSONAPIErrors)
+		a.Response(d.NotFound, JSONAPIErrors)
 		a.Response(d.InternalServerError, JSONAPIErrors)
 	})
 })
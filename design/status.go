@@ -23,6 +23,30 @@ var status = a.MediaType("application/vnd.status+json", func() {
 	})
 })
 
+// readiness defines the readiness of the current running instance, i.e., whether it can evaluate the features correctly
+var readiness = a.MediaType("application/vnd.readiness+json", func() {
+	a.Description("The readiness of the current running instance")
+	a.Attributes(func() {
+		a.Attribute("ready", d.Boolean, "'True' if the instance can evaluate the features correctly")
+		a.Attribute("togglesReady", d.Boolean, "'True' if the toggles client received the features from the toggles server")
+		a.Attribute("togglesLastRefresh", d.DateTime, "The time when the toggles client last refreshed the features")
+		a.Attribute("featureCount", d.Integer, "The number of features loaded in the toggles client")
+		a.Attribute("tokenKeyCount", d.Integer, "The number of public keys loaded to verify the tokens")
+		a.Attribute("authReachable", d.Boolean, "'True' if the auth service is reachable (the instance remains ready if it is not)")
+		a.Attribute("reasons", a.ArrayOf(d.String), "The reasons why the instance is not ready")
+		a.Required("ready", "togglesReady", "featureCount", "tokenKeyCount", "authReachable")
+	})
+	a.View("default", func() {
+		a.Attribute("ready")
+		a.Attribute("togglesReady")
+		a.Attribute("togglesLastRefresh")
+		a.Attribute("featureCount")
+		a.Attribute("tokenKeyCount")
+		a.Attribute("authReachable")
+		a.Attribute("reasons")
+	})
+})
+
 var _ = a.Resource("status", func() {
 
 	a.DefaultMedia(status)
@@ -36,4 +60,21 @@ var _ = a.Resource("status", func() {
 		a.Response(d.OK)
 		a.Response(d.ServiceUnavailable, status)
 	})
+
+	a.Action("liveness", func() {
+		a.Routing(
+			a.GET("/liveness"),
+		)
+		a.Description("Show whether the current running instance is alive, along with its status")
+		a.Response(d.OK)
+	})
+
+	a.Action("readiness", func() {
+		a.Routing(
+			a.GET("/readiness"),
+		)
+		a.Description("Show whether the current running instance is ready to evaluate the features, along with the state of its dependencies")
+		a.Response(d.OK, readiness)
+		a.Response(d.ServiceUnavailable, readiness)
+	})
 })
@@ -0,0 +1,91 @@
+// Package evaluator provides an in-process evaluation of the feature toggles, for the services which cannot afford
+// to call the toggles service on each request. It evaluates a snapshot of the Unleash features with the same strategies,
+// enablement level and internal user rules as the toggles service, so that the results match those of the `/api/features`
+// endpoints for the same user.
+package evaluator
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	authclient "github.com/fabric8-services/fabric8-toggles-service/auth/client"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+)
+
+// Evaluator evaluates the features of a snapshot for a given user. The snapshot can be replaced at any time.
+type Evaluator struct {
+	mu      sync.RWMutex
+	client  featuretoggles.Client
+	options []featuretoggles.ClientOption
+}
+
+// New returns a new evaluator of the given snapshot. The options are applied on the underlying toggles client
+// (e.g., `featuretoggles.WithEnrollmentStore` to take the users' enrollments into account).
+func New(snapshot *featuretoggles.Snapshot, options ...featuretoggles.ClientOption) *Evaluator {
+	e := Evaluator{
+		options: options,
+	}
+	e.Update(snapshot)
+	return &e
+}
+
+// Update replaces the snapshot to evaluate
+func (e *Evaluator) Update(snapshot *featuretoggles.Snapshot) {
+	client := featuretoggles.NewClientWithState(snapshot, true, e.options...)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.client = client
+}
+
+func (e *Evaluator) getClient() featuretoggles.Client {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.client
+}
+
+// IsEnabled returns `true` if the feature with the given name is enabled for the given user
+func (e *Evaluator) IsEnabled(ctx context.Context, name string, user *authclient.User) bool {
+	return e.Feature(ctx, name, user).UserEnabled
+}
+
+// Feature returns the feature with the given name, evaluated for the given user
+// (or `featuretoggles.ZeroUserFeature` if the feature does not exist)
+func (e *Evaluator) Feature(ctx context.Context, name string, user *authclient.User) featuretoggles.UserFeature {
+	return e.getClient().GetFeature(ctx, name, user)
+}
+
+// FeaturesByName returns the features with the given names, evaluated for the given user and sorted by name
+func (e *Evaluator) FeaturesByName(ctx context.Context, names []string, user *authclient.User) []featuretoggles.UserFeature {
+	return sorted(e.getClient().GetFeaturesByName(ctx, names, user))
+}
+
+// FeaturesByGroup returns the features of the given group, evaluated for the given user and sorted by name
+func (e *Evaluator) FeaturesByGroup(ctx context.Context, group string, user *authclient.User) []featuretoggles.UserFeature {
+	return sorted(e.getClient().GetFeaturesByPattern(ctx, group, user))
+}
+
+// FeaturesByStrategy returns the features which have the given strategy, evaluated for the given user and sorted by name
+func (e *Evaluator) FeaturesByStrategy(ctx context.Context, strategy string, user *authclient.User) []featuretoggles.UserFeature {
+	return sorted(e.getClient().GetFeaturesByStrategy(ctx, strategy, user))
+}
+
+// sorted sorts the given features by name, as the toggles service does
+func sorted(features []featuretoggles.UserFeature) []featuretoggles.UserFeature {
+	sort.Sort(featuretoggles.ByName(features))
+	return features
+}
+
+// NewUser returns a user with the given attributes, as returned by the auth service
+func NewUser(id, email string, emailVerified bool, featureLevel string) *authclient.User {
+	return &authclient.User{
+		Data: &authclient.UserData{
+			ID: &id,
+			Attributes: &authclient.UserDataAttributes{
+				Email:         &email,
+				EmailVerified: &emailVerified,
+				FeatureLevel:  &featureLevel,
+			},
+		},
+	}
+}
@@ -0,0 +1,197 @@
+package evaluator_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	authclient "github.com/fabric8-services/fabric8-toggles-service/auth/client"
+	"github.com/fabric8-services/fabric8-toggles-service/evaluator"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func levelFeature(name, level string) unleashapi.Feature {
+	return unleashapi.Feature{
+		Name:        name,
+		Description: name,
+		Enabled:     true,
+		Strategies: []unleashapi.Strategy{
+			{
+				Name: featuretoggles.EnableByLevelStrategyName,
+				Parameters: map[string]interface{}{
+					featuretoggles.LevelParameter: level,
+				},
+			},
+		},
+	}
+}
+
+func TestEvaluator(t *testing.T) {
+	// given
+	e := evaluator.New(featuretoggles.NewSnapshot([]unleashapi.Feature{
+		levelFeature("foo.released", featuretoggles.ReleasedLevel),
+		levelFeature("foo.internal", featuretoggles.InternalLevel),
+		levelFeature("foo.beta", featuretoggles.BetaLevel),
+		levelFeature("bar", featuretoggles.ExperimentalLevel),
+	}))
+	ctx := context.Background()
+
+	t.Run("anonymous user", func(t *testing.T) {
+		// when
+		features := e.FeaturesByGroup(ctx, "foo", nil)
+		// then
+		assert.Equal(t, []featuretoggles.UserFeature{
+			{Name: "foo.beta", Description: "foo.beta", Enabled: true, UserEnabled: false, EnablementLevel: featuretoggles.BetaLevel},
+			{Name: "foo.internal", Description: "foo.internal", Enabled: true, UserEnabled: false, EnablementLevel: featuretoggles.UnknownLevel},
+			{Name: "foo.released", Description: "foo.released", Enabled: true, UserEnabled: true, EnablementLevel: featuretoggles.ReleasedLevel},
+		}, features)
+	})
+
+	t.Run("internal user", func(t *testing.T) {
+		// given
+		user := evaluator.NewUser("user-1", "user@redhat.com", true, featuretoggles.InternalLevel)
+		// when
+		f := e.Feature(ctx, "foo.internal", user)
+		// then
+		assert.True(t, f.UserEnabled)
+		assert.Equal(t, featuretoggles.InternalLevel, f.EnablementLevel)
+	})
+
+	t.Run("external user with internal level", func(t *testing.T) {
+		// given
+		user := evaluator.NewUser("user-2", "user@foo.com", true, featuretoggles.InternalLevel)
+		// when
+		f := e.Feature(ctx, "foo.internal", user)
+		// then
+		assert.True(t, f.UserEnabled)
+		assert.Equal(t, featuretoggles.UnknownLevel, f.EnablementLevel)
+	})
+
+	t.Run("beta user", func(t *testing.T) {
+		// given
+		user := evaluator.NewUser("user-3", "user@foo.com", true, featuretoggles.BetaLevel)
+		// when
+		features := e.FeaturesByStrategy(ctx, featuretoggles.EnableByLevelStrategyName, user)
+		// then
+		enabled := map[string]bool{}
+		for _, f := range features {
+			enabled[f.Name] = f.UserEnabled
+		}
+		assert.Equal(t, map[string]bool{"bar": false, "foo.beta": true, "foo.internal": false, "foo.released": true}, enabled)
+	})
+
+	t.Run("unknown feature", func(t *testing.T) {
+		assert.Equal(t, featuretoggles.ZeroUserFeature, e.Feature(ctx, "unknown", nil))
+		assert.False(t, e.IsEnabled(ctx, "unknown", nil))
+	})
+
+	t.Run("update", func(t *testing.T) {
+		// given
+		e := evaluator.New(featuretoggles.NewSnapshot([]unleashapi.Feature{}))
+		require.False(t, e.IsEnabled(ctx, "foo.released", nil))
+		// when
+		e.Update(featuretoggles.NewSnapshot([]unleashapi.Feature{levelFeature("foo.released", featuretoggles.ReleasedLevel)}))
+		// then
+		assert.True(t, e.IsEnabled(ctx, "foo.released", nil))
+	})
+}
+
+type testToggleServiceConfig struct {
+	url string
+}
+
+func (c testToggleServiceConfig) GetTogglesURL() string                      { return c.url }
+func (c testToggleServiceConfig) GetTogglesAPIToken() string                 { return "" }
+func (c testToggleServiceConfig) GetTogglesCustomHeaders() map[string]string { return nil }
+func (c testToggleServiceConfig) GetTogglesCABundleFile() string             { return "" }
+func (c testToggleServiceConfig) GetTogglesProxyURL() string                 { return "" }
+func (c testToggleServiceConfig) GetTogglesTimeout() time.Duration           { return 10 * time.Second }
+func (c testToggleServiceConfig) GetTogglesInstanceID() string               { return "evaluator-test" }
+
+func strategyFeature(name string, enabled bool, strategy string, parameters map[string]interface{}) unleashapi.Feature {
+	return unleashapi.Feature{
+		Name:        name,
+		Description: name,
+		Enabled:     enabled,
+		Strategies: []unleashapi.Strategy{
+			{
+				Name:       strategy,
+				Parameters: parameters,
+			},
+		},
+	}
+}
+
+// TestEvaluatorMatchesClient verifies that the evaluator and the toggles client backed by an actual unleash client
+// return the same results for the same features, including those with the built-in strategies of the Unleash client
+func TestEvaluatorMatchesClient(t *testing.T) {
+	// given
+	hostname, _ := os.Hostname()
+	features := []unleashapi.Feature{
+		strategyFeature("fixture.default", true, featuretoggles.DefaultStrategyName, nil),
+		strategyFeature("fixture.disabled", false, featuretoggles.DefaultStrategyName, nil),
+		strategyFeature("fixture.hostname", true, featuretoggles.ApplicationHostnameStrategyName, map[string]interface{}{
+			"hostNames": strings.Join([]string{hostname, os.Getenv("HOSTNAME")}, ","),
+		}),
+		strategyFeature("fixture.otherhost", true, featuretoggles.ApplicationHostnameStrategyName, map[string]interface{}{"hostNames": "not-this-host"}),
+		strategyFeature("fixture.random.all", true, featuretoggles.GradualRolloutRandomStrategyName, map[string]interface{}{"percentage": "100"}),
+		strategyFeature("fixture.random.none", true, featuretoggles.GradualRolloutRandomStrategyName, map[string]interface{}{"percentage": "0"}),
+		strategyFeature("fixture.rollout.user", true, featuretoggles.GradualRolloutUserIDStrategyName, map[string]interface{}{"percentage": "100", "groupId": "fixture"}),
+		strategyFeature("fixture.rollout.session", true, featuretoggles.GradualRolloutSessionIDStrategyName, map[string]interface{}{"percentage": "100", "groupId": "fixture"}),
+		strategyFeature("fixture.remote", true, featuretoggles.RemoteAddressStrategyName, map[string]interface{}{"IPs": "127.0.0.1"}),
+		strategyFeature("fixture.userids", true, featuretoggles.UserWithIDStrategyName, map[string]interface{}{"userIds": "user-1"}),
+		strategyFeature("fixture.unknown", true, "unknownStrategy", nil),
+		levelFeature("fixture.beta", featuretoggles.BetaLevel),
+		strategyFeature("fixture.emails", true, featuretoggles.EnableByEmailsStrategyName, map[string]interface{}{
+			featuretoggles.EmailsParameter: "user@foo.com",
+		}),
+	}
+	body, err := json.Marshal(map[string]interface{}{"version": 1, "features": features})
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/client/features") {
+			w.Write(body)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client, err := featuretoggles.NewDefaultClient("evaluator-test", testToggleServiceConfig{url: server.URL + "/api/"})
+	require.NoError(t, err)
+	defer client.Close()
+	for deadline := time.Now().Add(5 * time.Second); !client.State().Ready; time.Sleep(10 * time.Millisecond) {
+		require.True(t, time.Now().Before(deadline), "the toggles client is not ready")
+	}
+	e := evaluator.New(featuretoggles.NewSnapshot(features))
+	ctx := context.Background()
+	users := map[string]*authclient.User{
+		"anonymous":     nil,
+		"released user": evaluator.NewUser("user-1", "user@bar.com", true, featuretoggles.ReleasedLevel),
+		"beta user":     evaluator.NewUser("user-2", "user@foo.com", true, featuretoggles.BetaLevel),
+	}
+	for name, user := range users {
+		t.Run(name, func(t *testing.T) {
+			// when
+			expected := map[string]bool{}
+			for _, f := range client.GetFeaturesByPattern(ctx, "fixture", user) {
+				expected[f.Name] = f.UserEnabled
+			}
+			actual := map[string]bool{}
+			for _, f := range e.FeaturesByGroup(ctx, "fixture", user) {
+				actual[f.Name] = f.UserEnabled
+			}
+			// then
+			require.Len(t, expected, len(features))
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
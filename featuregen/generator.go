@@ -0,0 +1,243 @@
+// Package featuregen generates a Go package with typed constants for the features of a repository, so that the consumers
+// of the toggles service do not hard-code the feature names (see the `toggles generate` command).
+package featuregen
+
+import (
+	"bytes"
+	"go/format"
+	"io"
+	"sort"
+	"strings"
+	"text/template"
+	"unicode"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	errs "github.com/pkg/errors"
+)
+
+// Constant a feature or group constant in the generated package
+type Constant struct {
+	Ident string
+	Value string
+	Doc   []string
+}
+
+// Group a group of features (i.e., a dot-separated prefix of the feature names) in the generated package
+type Group struct {
+	Constant
+	Features []string
+}
+
+// Package the content of the generated package
+type Package struct {
+	Name     string
+	Features []Constant
+	Groups   []Group
+}
+
+// Generate writes the source of a Go package with the given name, in which each feature has a typed constant (documented
+// with the feature description) and each group has a typed constant which lists its features. Returns an error if two
+// features or groups have the same Go identifier.
+func Generate(w io.Writer, pkg string, features []unleashapi.Feature) error {
+	p, err := NewPackage(pkg, features)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := packageTmpl.Execute(&buf, p); err != nil {
+		return errs.Wrap(err, "unable to generate the features package")
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return errs.Wrap(err, "unable to format the generated features package")
+	}
+	_, err = w.Write(source)
+	return err
+}
+
+// NewPackage returns the content of the package to generate for the given features, sorted by name
+func NewPackage(pkg string, features []unleashapi.Feature) (Package, error) {
+	p := Package{
+		Name:     pkg,
+		Features: make([]Constant, 0, len(features)),
+		Groups:   []Group{},
+	}
+	idents := map[string]string{}
+	declare := func(ident, name string) error {
+		if other, found := idents[ident]; found {
+			return errs.Errorf("'%s' and '%s' have the same Go identifier '%s'", other, name, ident)
+		}
+		idents[ident] = name
+		return nil
+	}
+	sorted := make([]unleashapi.Feature, len(features))
+	copy(sorted, features)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	groups := map[string][]string{}
+	for _, f := range sorted {
+		ident := Goify(f.Name)
+		if err := declare(ident, f.Name); err != nil {
+			return Package{}, err
+		}
+		doc := []string{ident + " the '" + f.Name + "' feature"}
+		if description := strings.TrimSpace(f.Description); description != "" {
+			doc[0] += ":"
+			doc = append(doc, strings.Split(description, "\n")...)
+		}
+		p.Features = append(p.Features, Constant{Ident: ident, Value: f.Name, Doc: doc})
+		// the feature belongs to all the groups matching a prefix of its name (and to the group of its own name, if any)
+		segments := strings.Split(f.Name, ".")
+		for i := 1; i < len(segments); i++ {
+			group := strings.Join(segments[:i], ".")
+			groups[group] = append(groups[group], ident)
+		}
+	}
+	names := make([]string, 0, len(groups))
+	for name := range groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		members := groups[name]
+		for _, f := range p.Features {
+			if f.Value == name {
+				// the feature named after the group comes first
+				members = append([]string{f.Ident}, members...)
+			}
+		}
+		ident := Goify(name) + "Group"
+		if err := declare(ident, name); err != nil {
+			return Package{}, err
+		}
+		p.Groups = append(p.Groups, Group{
+			Constant: Constant{
+				Ident: ident,
+				Value: name,
+				Doc:   []string{ident + " the '" + name + "' group of features"},
+			},
+			Features: members,
+		})
+	}
+	return p, nil
+}
+
+// Goify returns the exported Go identifier for the given feature name, e.g. `PlannerBoard` for `planner.board`
+func Goify(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	ident := b.String()
+	if ident == "" || !unicode.IsLetter([]rune(ident)[0]) {
+		// identifiers cannot start with a digit
+		ident = "F" + ident
+	}
+	return ident
+}
+
+var packageTmpl = template.Must(template.New("package").Funcs(template.FuncMap{
+	"comment": func(lines []string) string {
+		comment := make([]string, len(lines))
+		for i, line := range lines {
+			comment[i] = strings.TrimRight("// "+strings.TrimSpace(line), " ")
+		}
+		return strings.Join(comment, "\n\t")
+	},
+}).Parse(`// Code generated by "toggles generate"; DO NOT EDIT.
+
+// Package {{ .Name }} the features of the toggles service
+package {{ .Name }}
+
+import "sort"
+
+// Name the name of a feature
+type Name string
+
+// Group the name of a group of features, i.e., the dot-separated prefix of their names
+type Group string
+
+// the features
+const (
+{{- range .Features }}
+	{{ comment .Doc }}
+	{{ .Ident }} Name = {{ printf "%q" .Value }}
+{{- end }}
+)
+
+// the groups of features
+const (
+{{- range .Groups }}
+	{{ comment .Doc }}
+	{{ .Ident }} Group = {{ printf "%q" .Value }}
+{{- end }}
+)
+
+var features = []Name{
+{{- range .Features }}
+	{{ .Ident }},
+{{- end }}
+}
+
+var groups = map[Group][]Name{
+{{- range .Groups }}
+	{{ .Ident }}: { {{- range $i, $f := .Features }}{{ if $i }}, {{ end }}{{ $f }}{{ end -}} },
+{{- end }}
+}
+
+// String returns the name of the feature
+func (n Name) String() string {
+	return string(n)
+}
+
+// String returns the name of the group
+func (g Group) String() string {
+	return string(g)
+}
+
+// Features returns the features of the group, i.e., the features listed with ` + "`GET /api/features?group=<group>`" + `
+func (g Group) Features() []Name {
+	result := make([]Name, len(groups[g]))
+	copy(result, groups[g])
+	return result
+}
+
+// All returns all the features, sorted by name
+func All() []Name {
+	result := make([]Name, len(features))
+	copy(result, features)
+	return result
+}
+
+// Groups returns all the groups of features, sorted by name
+func Groups() []Group {
+	result := make([]Group, 0, len(groups))
+	for g := range groups {
+		result = append(result, g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i] < result[j]
+	})
+	return result
+}
+
+// IsKnown returns true if a feature has the given name
+func IsKnown(name string) bool {
+	for _, f := range features {
+		if string(f) == name {
+			return true
+		}
+	}
+	return false
+}
+`))
@@ -0,0 +1,72 @@
+package featuregen_test
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-toggles-service/featuregen"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoify(t *testing.T) {
+	assert.Equal(t, "PlannerBoard", featuregen.Goify("planner.board"))
+	assert.Equal(t, "DeployPipeline", featuregen.Goify("deploy-pipeline"))
+	assert.Equal(t, "FooBar", featuregen.Goify("foo_bar"))
+	assert.Equal(t, "F3scale", featuregen.Goify("3scale"))
+}
+
+func TestGenerate(t *testing.T) {
+
+	t.Run("ok", func(t *testing.T) {
+		// given
+		features := []unleashapi.Feature{
+			{Name: "planner.board", Description: "The new planner board"},
+			{Name: "planner"},
+			{Name: "deploy"},
+		}
+		buf := &bytes.Buffer{}
+		// when
+		err := featuregen.Generate(buf, "features", features)
+		// then
+		require.NoError(t, err)
+		_, err = parser.ParseFile(token.NewFileSet(), "features.go", buf.Bytes(), parser.ParseComments)
+		require.NoError(t, err, buf.String())
+		source := buf.String()
+		assert.Contains(t, source, "package features")
+		assert.Contains(t, source, "// PlannerBoard the 'planner.board' feature:\n\t// The new planner board\n\tPlannerBoard Name = \"planner.board\"")
+		assert.Contains(t, source, "Deploy Name = \"deploy\"")
+		assert.Contains(t, source, "PlannerGroup Group = \"planner\"")
+		assert.Contains(t, source, "PlannerGroup: {Planner, PlannerBoard},")
+	})
+
+	t.Run("groups", func(t *testing.T) {
+		// when
+		p, err := featuregen.NewPackage("features", []unleashapi.Feature{
+			{Name: "planner.board.dnd"},
+			{Name: "planner.board"},
+			{Name: "deploy"},
+		})
+		// then
+		require.NoError(t, err)
+		require.Len(t, p.Groups, 2)
+		assert.Equal(t, "planner", p.Groups[0].Value)
+		assert.Equal(t, []string{"PlannerBoard", "PlannerBoardDnd"}, p.Groups[0].Features)
+		assert.Equal(t, "planner.board", p.Groups[1].Value)
+		assert.Equal(t, []string{"PlannerBoard", "PlannerBoardDnd"}, p.Groups[1].Features)
+	})
+
+	t.Run("identifier conflict", func(t *testing.T) {
+		// when
+		err := featuregen.Generate(&bytes.Buffer{}, "features", []unleashapi.Feature{
+			{Name: "foo-bar"},
+			{Name: "foo.bar"},
+		})
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "have the same Go identifier 'FooBar'")
+	})
+}
@@ -0,0 +1,257 @@
+package featuretoggles
+
+import (
+	"math/rand"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	unleashcontext "github.com/Unleash/unleash-client-go/context"
+	"github.com/Unleash/unleash-client-go/strategy"
+)
+
+// The built-in strategies of the Unleash client cannot be reused outside of its package (they are internal to the client),
+// so they are implemented here with the same semantics, in order to evaluate the features locally (see `EvaluateStrategies`).
+// Each of them is cross-checked against the vendored client on the same contexts, so that an upgrade of the client which
+// changes their results is detected (see `TestBuiltinStrategiesMatchUnleash`).
+const (
+	// DefaultStrategyName the name of the built-in Unleash strategy which enables a feature for everyone
+	DefaultStrategyName = "default"
+	// ApplicationHostnameStrategyName the name of the built-in Unleash strategy which enables a feature on some hosts
+	ApplicationHostnameStrategyName = "applicationHostname"
+	// GradualRolloutRandomStrategyName the name of the built-in Unleash strategy which enables a feature randomly
+	GradualRolloutRandomStrategyName = "gradualRolloutRandom"
+	// GradualRolloutSessionIDStrategyName the name of the built-in Unleash strategy which enables a feature for a percentage of the sessions
+	GradualRolloutSessionIDStrategyName = "gradualRolloutSessionId"
+	// GradualRolloutUserIDStrategyName the name of the built-in Unleash strategy which enables a feature for a percentage of the users
+	GradualRolloutUserIDStrategyName = "gradualRolloutUserId"
+	// RemoteAddressStrategyName the name of the built-in Unleash strategy which enables a feature for some IP addresses
+	RemoteAddressStrategyName = "remoteAddress"
+	// UserWithIDStrategyName the name of the built-in Unleash strategy which enables a feature for some users
+	UserWithIDStrategyName = "userWithId"
+)
+
+const (
+	hostNamesParameter  = "hostNames"
+	percentageParameter = "percentage"
+	groupIDParameter    = "groupId"
+	ipsParameter        = "IPs"
+	userIDsParameter    = "userIds"
+)
+
+// builtinStrategies the built-in strategies of the Unleash client
+var builtinStrategies = []strategy.Strategy{
+	defaultStrategy{},
+	applicationHostnameStrategy{hostname: resolveHostname()},
+	gradualRolloutRandomStrategy{},
+	gradualRolloutStrategy{name: GradualRolloutSessionIDStrategyName, id: func(ctx *unleashcontext.Context) string { return ctx.SessionId }},
+	gradualRolloutStrategy{name: GradualRolloutUserIDStrategyName, id: func(ctx *unleashcontext.Context) string { return ctx.UserId }},
+	remoteAddressStrategy{},
+	userWithIDStrategy{},
+}
+
+// IsBuiltinStrategy returns `true` if the strategy with the given name is one of the built-in strategies of the Unleash client
+func IsBuiltinStrategy(name string) bool {
+	for _, s := range builtinStrategies {
+		if s.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveHostname returns the name of the host, as resolved by the Unleash client
+func resolveHostname() string {
+	if hostname := os.Getenv("HOSTNAME"); hostname != "" {
+		return hostname
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "undefined"
+	}
+	return hostname
+}
+
+type defaultStrategy struct{}
+
+func (s defaultStrategy) Name() string {
+	return DefaultStrategyName
+}
+
+func (s defaultStrategy) IsEnabled(map[string]interface{}, *unleashcontext.Context) bool {
+	return true
+}
+
+type applicationHostnameStrategy struct {
+	hostname string
+}
+
+func (s applicationHostnameStrategy) Name() string {
+	return ApplicationHostnameStrategyName
+}
+
+func (s applicationHostnameStrategy) IsEnabled(params map[string]interface{}, _ *unleashcontext.Context) bool {
+	hostNames, ok := params[hostNamesParameter].(string)
+	if !ok {
+		return false
+	}
+	for _, h := range strings.Split(hostNames, ",") {
+		if strings.ToLower(strings.TrimSpace(h)) == strings.ToLower(s.hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+type gradualRolloutRandomStrategy struct{}
+
+func (s gradualRolloutRandomStrategy) Name() string {
+	return GradualRolloutRandomStrategyName
+}
+
+func (s gradualRolloutRandomStrategy) IsEnabled(params map[string]interface{}, _ *unleashcontext.Context) bool {
+	percentage, ok := percentageParam(params)
+	if !ok {
+		return false
+	}
+	return float64(rand.Intn(100)+1) <= percentage
+}
+
+// gradualRolloutStrategy the strategy which enables a feature for a stable percentage of the users or sessions
+type gradualRolloutStrategy struct {
+	name string
+	id   func(ctx *unleashcontext.Context) string
+}
+
+func (s gradualRolloutStrategy) Name() string {
+	return s.name
+}
+
+func (s gradualRolloutStrategy) IsEnabled(params map[string]interface{}, ctx *unleashcontext.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	id := s.id(ctx)
+	if id == "" {
+		return false
+	}
+	percentage, ok := percentageParam(params)
+	if !ok {
+		return false
+	}
+	groupID, _ := params[groupIDParameter].(string)
+	return percentage > 0 && float64(normalizedValue(id, groupID)) <= percentage
+}
+
+type remoteAddressStrategy struct{}
+
+func (s remoteAddressStrategy) Name() string {
+	return RemoteAddressStrategyName
+}
+
+func (s remoteAddressStrategy) IsEnabled(params map[string]interface{}, ctx *unleashcontext.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	remoteAddress := net.ParseIP(strings.TrimSpace(ctx.RemoteAddress))
+	ips, ok := params[ipsParameter].(string)
+	if remoteAddress == nil || !ok {
+		return false
+	}
+	for _, ip := range strings.Split(ips, ",") {
+		ip = strings.TrimSpace(ip)
+		if _, network, err := net.ParseCIDR(ip); err == nil {
+			if network.Contains(remoteAddress) {
+				return true
+			}
+		} else if remoteAddress.Equal(net.ParseIP(ip)) {
+			return true
+		}
+	}
+	return false
+}
+
+type userWithIDStrategy struct{}
+
+func (s userWithIDStrategy) Name() string {
+	return UserWithIDStrategyName
+}
+
+func (s userWithIDStrategy) IsEnabled(params map[string]interface{}, ctx *unleashcontext.Context) bool {
+	if ctx == nil || ctx.UserId == "" {
+		return false
+	}
+	userIDs, ok := params[userIDsParameter].(string)
+	if !ok {
+		return false
+	}
+	for _, u := range strings.Split(userIDs, ",") {
+		if strings.TrimSpace(u) == ctx.UserId {
+			return true
+		}
+	}
+	return false
+}
+
+// percentageParam returns the value of the `percentage` parameter, which the Unleash server sends as a number or a string
+func percentageParam(params map[string]interface{}) (float64, bool) {
+	switch value := params[percentageParameter].(type) {
+	case float64:
+		return value, true
+	case int:
+		return float64(value), true
+	case string:
+		percentage, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		return percentage, err == nil
+	}
+	return 0, false
+}
+
+// normalizedValue returns a stable value between 1 and 100 for the given ID and group, computed with the same hash
+// (MurmurHash3, 32-bit, seed 0) as all the Unleash clients
+func normalizedValue(id, groupID string) uint32 {
+	return murmur3([]byte(groupID+":"+id))%100 + 1
+}
+
+// murmur3 returns the 32-bit MurmurHash3 (x86 variant, seed 0) of the given data
+func murmur3(data []byte) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	var h uint32
+	n := len(data) / 4
+	for i := 0; i < n; i++ {
+		k := uint32(data[4*i]) | uint32(data[4*i+1])<<8 | uint32(data[4*i+2])<<16 | uint32(data[4*i+3])<<24
+		k *= c1
+		k = k<<15 | k>>17
+		k *= c2
+		h ^= k
+		h = h<<13 | h>>19
+		h = h*5 + 0xe6546b64
+	}
+	tail := data[4*n:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = k<<15 | k>>17
+		k *= c2
+		h ^= k
+	}
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
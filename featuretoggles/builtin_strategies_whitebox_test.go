@@ -0,0 +1,166 @@
+package featuretoggles
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	unleash "github.com/Unleash/unleash-client-go"
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	unleashcontext "github.com/Unleash/unleash-client-go/context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinStrategies(t *testing.T) {
+
+	t.Run("application hostname", func(t *testing.T) {
+		// given
+		s := applicationHostnameStrategy{hostname: "Pod-1"}
+		// then
+		assert.True(t, s.IsEnabled(map[string]interface{}{hostNamesParameter: "pod-0, pod-1"}, &unleashcontext.Context{}))
+		assert.False(t, s.IsEnabled(map[string]interface{}{hostNamesParameter: "pod-0"}, &unleashcontext.Context{}))
+		assert.False(t, s.IsEnabled(map[string]interface{}{}, &unleashcontext.Context{}))
+	})
+
+	t.Run("gradual rollout random", func(t *testing.T) {
+		// given
+		s := gradualRolloutRandomStrategy{}
+		// then
+		assert.True(t, s.IsEnabled(map[string]interface{}{percentageParameter: float64(100)}, nil))
+		assert.True(t, s.IsEnabled(map[string]interface{}{percentageParameter: "100"}, nil))
+		assert.False(t, s.IsEnabled(map[string]interface{}{percentageParameter: 0}, nil))
+		assert.False(t, s.IsEnabled(map[string]interface{}{percentageParameter: "foo"}, nil))
+	})
+
+	t.Run("gradual rollout user ID", func(t *testing.T) {
+		// given
+		s := gradualRolloutStrategy{name: GradualRolloutUserIDStrategyName, id: func(ctx *unleashcontext.Context) string { return ctx.UserId }}
+		params := map[string]interface{}{percentageParameter: "50", groupIDParameter: "foo"}
+		// then the result is stable for a given user
+		enabled := s.IsEnabled(params, &unleashcontext.Context{UserId: "user-1"})
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, enabled, s.IsEnabled(params, &unleashcontext.Context{UserId: "user-1"}))
+		}
+		assert.True(t, s.IsEnabled(map[string]interface{}{percentageParameter: 100}, &unleashcontext.Context{UserId: "user-1"}))
+		assert.False(t, s.IsEnabled(map[string]interface{}{percentageParameter: 100}, &unleashcontext.Context{}))
+	})
+
+	t.Run("remote address", func(t *testing.T) {
+		// given
+		s := remoteAddressStrategy{}
+		params := map[string]interface{}{ipsParameter: "10.0.0.1, 192.168.0.0/16"}
+		// then
+		assert.True(t, s.IsEnabled(params, &unleashcontext.Context{RemoteAddress: "10.0.0.1"}))
+		assert.True(t, s.IsEnabled(params, &unleashcontext.Context{RemoteAddress: "192.168.1.2"}))
+		assert.False(t, s.IsEnabled(params, &unleashcontext.Context{RemoteAddress: "10.0.0.2"}))
+		assert.False(t, s.IsEnabled(params, &unleashcontext.Context{}))
+	})
+
+	t.Run("user with ID", func(t *testing.T) {
+		// given
+		s := userWithIDStrategy{}
+		params := map[string]interface{}{userIDsParameter: "user-1, user-2"}
+		// then
+		assert.True(t, s.IsEnabled(params, &unleashcontext.Context{UserId: "user-2"}))
+		assert.False(t, s.IsEnabled(params, &unleashcontext.Context{UserId: "user-3"}))
+		assert.False(t, s.IsEnabled(params, &unleashcontext.Context{}))
+	})
+}
+
+func TestMurmur3(t *testing.T) {
+	// reference values of the 32-bit MurmurHash3 (x86 variant, seed 0)
+	assert.Equal(t, uint32(0), murmur3([]byte("")))
+	assert.Equal(t, uint32(0x248bfa47), murmur3([]byte("hello")))
+	assert.Equal(t, uint32(0xc0363e43), murmur3([]byte("Hello, world!")))
+}
+
+// TestBuiltinStrategiesMatchUnleash verifies that each built-in strategy returns the same result as its implementation
+// in the vendored Unleash client (which cannot be reused since it is internal to the client), on the same contexts
+func TestBuiltinStrategiesMatchUnleash(t *testing.T) {
+	// given
+	cases := []struct {
+		strategy string
+		params   map[string]interface{}
+	}{
+		{strategy: DefaultStrategyName},
+		{strategy: ApplicationHostnameStrategyName, params: map[string]interface{}{hostNamesParameter: "other-host, " + strings.ToUpper(resolveHostname())}},
+		{strategy: ApplicationHostnameStrategyName, params: map[string]interface{}{hostNamesParameter: "other-host"}},
+		{strategy: ApplicationHostnameStrategyName},
+		{strategy: GradualRolloutRandomStrategyName, params: map[string]interface{}{percentageParameter: "100"}},
+		{strategy: GradualRolloutRandomStrategyName, params: map[string]interface{}{percentageParameter: 0}},
+		{strategy: GradualRolloutUserIDStrategyName, params: map[string]interface{}{percentageParameter: "50", groupIDParameter: "planner"}},
+		{strategy: GradualRolloutUserIDStrategyName, params: map[string]interface{}{percentageParameter: 25, groupIDParameter: ""}},
+		{strategy: GradualRolloutUserIDStrategyName, params: map[string]interface{}{percentageParameter: "100"}},
+		{strategy: GradualRolloutUserIDStrategyName, params: map[string]interface{}{percentageParameter: "0", groupIDParameter: "planner"}},
+		{strategy: GradualRolloutSessionIDStrategyName, params: map[string]interface{}{percentageParameter: "50", groupIDParameter: "planner"}},
+		{strategy: GradualRolloutSessionIDStrategyName, params: map[string]interface{}{percentageParameter: "75", groupIDParameter: "analyze"}},
+		{strategy: RemoteAddressStrategyName, params: map[string]interface{}{ipsParameter: "10.0.0.1, 192.168.0.0/16"}},
+		{strategy: RemoteAddressStrategyName, params: map[string]interface{}{ipsParameter: "invalid"}},
+		{strategy: UserWithIDStrategyName, params: map[string]interface{}{userIDsParameter: "user-1, user-2,user-3"}},
+		{strategy: UserWithIDStrategyName},
+	}
+	features := make([]unleashapi.Feature, len(cases))
+	for i, c := range cases {
+		features[i] = unleashapi.Feature{
+			Name:       fmt.Sprintf("feature-%d", i),
+			Enabled:    true,
+			Strategies: []unleashapi.Strategy{{Name: c.strategy, Parameters: c.params}},
+		}
+	}
+	body, err := json.Marshal(map[string]interface{}{"version": 1, "features": features})
+	require.NoError(t, err)
+	// the parameters are evaluated as received by the client, i.e., after a JSON round trip
+	received := struct {
+		Features []unleashapi.Feature `json:"features"`
+	}{}
+	require.NoError(t, json.Unmarshal(body, &received))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/client/features") {
+			w.Write(body)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	client, err := unleash.NewClient(
+		unleash.WithAppName("builtin-strategies-test"),
+		unleash.WithUrl(server.URL+"/api/"),
+		unleash.WithListener(&UnleashClientListener{}),
+		unleash.WithRefreshInterval(time.Hour),
+		unleash.WithMetricsInterval(time.Hour),
+	)
+	require.NoError(t, err)
+	defer client.Close()
+	for deadline := time.Now().Add(5 * time.Second); client.GetFeature(features[0].Name) == nil; time.Sleep(10 * time.Millisecond) {
+		require.True(t, time.Now().Before(deadline), "the unleash client did not fetch the features")
+	}
+	contexts := []unleashcontext.Context{{}}
+	for i := 0; i < 20; i++ {
+		contexts = append(contexts, unleashcontext.Context{
+			UserId:        fmt.Sprintf("user-%d", i),
+			SessionId:     fmt.Sprintf("session-%d", i),
+			RemoteAddress: fmt.Sprintf("%d.%d.0.1", []int{10, 192, 172}[i%3], []int{0, 168, 16}[i%3]),
+		})
+	}
+	for i, c := range cases {
+		f := received.Features[i]
+		t.Run(fmt.Sprintf("%s %v", c.strategy, c.params), func(t *testing.T) {
+			s, found := lookupEvaluationStrategy(c.strategy)
+			require.True(t, found)
+			for _, ctx := range contexts {
+				ctx := ctx
+				// when
+				expected := client.IsEnabled(f.Name, unleash.WithContext(ctx))
+				actual := s.IsEnabled(f.Strategies[0].Parameters, &ctx)
+				// then
+				assert.Equal(t, expected, actual, "context: %+v", ctx)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package featuretoggles
+
+import (
+	"sort"
+	"strings"
+)
+
+// FilterByLevel returns the features whose enablement level is one of the given levels
+func FilterByLevel(features []UserFeature, levels []string) []UserFeature {
+	result := make([]UserFeature, 0, len(features))
+	for _, f := range features {
+		for _, level := range levels {
+			if strings.ToLower(level) == f.EnablementLevel {
+				result = append(result, f)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// FeatureCatalogLevel the features available at a given level
+type FeatureCatalogLevel struct {
+	Level string
+	// Features the features whose enablement level is this level, sorted by name
+	Features []UserFeature
+}
+
+// GetETagData returns the field values to use to generate the ETag
+func (l FeatureCatalogLevel) GetETagData() []interface{} {
+	data := []interface{}{l.Level}
+	for _, f := range l.Features {
+		data = append(data, f.GetETagData())
+	}
+	return data
+}
+
+// NewCatalog groups the given features by their enablement level, from the least to the most stable level.
+// The features without a known enablement level (e.g., the features only enabled with emails) are excluded,
+// as well as the levels without features.
+func NewCatalog(features []UserFeature) []FeatureCatalogLevel {
+	catalog := make([]FeatureCatalogLevel, 0, len(Levels()))
+	for _, level := range Levels() {
+		levelFeatures := FilterByLevel(features, []string{level})
+		if len(levelFeatures) == 0 {
+			continue
+		}
+		sort.Sort(ByName(levelFeatures))
+		catalog = append(catalog, FeatureCatalogLevel{
+			Level:    level,
+			Features: levelFeatures,
+		})
+	}
+	return catalog
+}
@@ -0,0 +1,45 @@
+package featuretoggles_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterByLevel(t *testing.T) {
+	// given
+	features := []featuretoggles.UserFeature{
+		{Name: "foo", EnablementLevel: featuretoggles.BetaLevel},
+		{Name: "bar", EnablementLevel: featuretoggles.ReleasedLevel},
+		{Name: "baz", EnablementLevel: featuretoggles.UnknownLevel},
+	}
+	// when
+	result := featuretoggles.FilterByLevel(features, []string{"Beta", featuretoggles.ReleasedLevel})
+	// then
+	require.Len(t, result, 2)
+	assert.Equal(t, "foo", result[0].Name)
+	assert.Equal(t, "bar", result[1].Name)
+}
+
+func TestNewCatalog(t *testing.T) {
+	// given
+	features := []featuretoggles.UserFeature{
+		{Name: "planner.board", EnablementLevel: featuretoggles.BetaLevel},
+		{Name: "analyze", EnablementLevel: featuretoggles.BetaLevel},
+		{Name: "planner", EnablementLevel: featuretoggles.ReleasedLevel},
+		{Name: "chat", EnablementLevel: featuretoggles.InternalLevel},
+		{Name: "by-email", EnablementLevel: featuretoggles.UnknownLevel},
+	}
+	// when
+	catalog := featuretoggles.NewCatalog(features)
+	// then levels are ordered from the least stable one, and the empty levels are excluded
+	require.Len(t, catalog, 3)
+	assert.Equal(t, featuretoggles.InternalLevel, catalog[0].Level)
+	assert.Equal(t, featuretoggles.BetaLevel, catalog[1].Level)
+	require.Len(t, catalog[1].Features, 2)
+	assert.Equal(t, "analyze", catalog[1].Features[0].Name)
+	assert.Equal(t, "planner.board", catalog[1].Features[1].Name)
+	assert.Equal(t, featuretoggles.ReleasedLevel, catalog[2].Level)
+}
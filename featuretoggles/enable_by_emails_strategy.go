@@ -5,6 +5,7 @@ import (
 
 	unleashcontext "github.com/Unleash/unleash-client-go/context"
 	"github.com/fabric8-services/fabric8-auth/log"
+	errs "github.com/pkg/errors"
 )
 
 const (
@@ -23,22 +24,60 @@ func (s EnableByEmailsStrategy) Name() string {
 	return EnableByEmailsStrategyName
 }
 
-// IsEnabled returns `true` if the given context is compatible with the settings configured on the Unleash server
+// verify that `EnableByEmailsStrategy` validates its parameters
+var _ ParameterValidator = EnableByEmailsStrategy{}
+
+// ValidateParameters verifies that the `emails` parameter is a non-empty, comma-separated list of email addresses
+// (blank entries are ignored). The invalid entries are reported, but they do not disable the strategy (see `IsEnabled`).
+func (s EnableByEmailsStrategy) ValidateParameters(settings map[string]interface{}) error {
+	emails, err := stringParameter(settings, EmailsParameter)
+	if err != nil {
+		return err
+	}
+	var invalid []string
+	for _, email := range strings.Split(emails, ",") {
+		// blank entries (e.g., a trailing comma) are ignored
+		if email = strings.TrimSpace(email); email != "" && !isEmail(email) {
+			invalid = append(invalid, "'"+email+"'")
+		}
+	}
+	switch len(invalid) {
+	case 0:
+		return nil
+	case 1:
+		return errs.Errorf("invalid '%s' parameter: %s is not an email address (ignored)", EmailsParameter, invalid[0])
+	default:
+		return errs.Errorf("invalid '%s' parameter: %s are not email addresses (ignored)", EmailsParameter, strings.Join(invalid, ", "))
+	}
+}
+
+// isEmail returns `true` if the given (trimmed) entry of the `emails` parameter looks like an email address
+func isEmail(email string) bool {
+	return strings.Contains(email, "@")
+}
+
+// IsEnabled returns `true` if the given context is compatible with the settings configured on the Unleash server.
+// Returns `false` if the `emails` parameter is missing, while its entries which are not email addresses are skipped.
 func (s EnableByEmailsStrategy) IsEnabled(settings map[string]interface{}, ctx *unleashcontext.Context) bool {
 	log.Debug(nil, map[string]interface{}{"settings_emails": settings[EmailsParameter], "context_email": ctx.Properties[EmailsParameter]}, "checking if feature is enabled for user, based on his/her email...")
-	settingsEmails := settings[EmailsParameter]
+	settingsEmails, err := stringParameter(settings, EmailsParameter)
+	if err != nil {
+		log.Debug(nil, map[string]interface{}{"strategy_name": s.Name(), "err": err.Error()}, "invalid strategy parameters: feature is not enabled")
+		return false
+	}
 	userEmail := ctx.Properties[EmailsParameter]
-	if settingsEmails, ok := settingsEmails.(string); ok {
-		emails := strings.Split(settingsEmails, ",")
-		log.Debug(nil, map[string]interface{}{"emails": emails, "context_email": ctx.Properties[EmailsParameter]}, "checking if feature is enabled for user, based on his/her email...")
-		for _, email := range emails {
-			log.Debug(nil, map[string]interface{}{"email": email, "context_email": ctx.Properties[EmailsParameter]}, "checking if feature is enabled for user, based on his/her email...")
-			if userEmail == strings.TrimSpace(email) {
-				log.Debug(nil, map[string]interface{}{"settings_emails": settings[EmailsParameter], "context_email": ctx.Properties[EmailsParameter]}, "feature is enabled for user, based on his/her email.")
-				return true
-			}
+	emails := strings.Split(settingsEmails, ",")
+	log.Debug(nil, map[string]interface{}{"emails": emails, "context_email": userEmail}, "checking if feature is enabled for user, based on his/her email...")
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		// the invalid entries are reported by `ValidateParameters`, but they do not disable the other entries
+		if !isEmail(email) {
+			continue
+		}
+		if userEmail == email {
+			log.Debug(nil, map[string]interface{}{"settings_emails": settings[EmailsParameter], "context_email": userEmail}, "feature is enabled for user, based on his/her email.")
+			return true
 		}
 	}
 	return false
-
 }
@@ -51,3 +51,18 @@ func TestFeatureIsEnabledByEmail(t *testing.T) {
 	})
 
 }
+
+func TestFeatureIsEnabledByEmailWithInvalidEntries(t *testing.T) {
+	// given
+	s := EnableByEmailsStrategy{}
+	settings := map[string]interface{}{
+		EmailsParameter: "foo, bar@foo.com,baz",
+	}
+	// when
+	err := s.ValidateParameters(settings)
+	// then the invalid entries are reported...
+	assert.EqualError(t, err, "invalid 'emails' parameter: 'foo', 'baz' are not email addresses (ignored)")
+	// ... but the valid entries still enable the feature
+	assert.True(t, s.IsEnabled(settings, &unleashcontext.Context{Properties: map[string]string{EmailsParameter: "bar@foo.com"}}))
+	assert.False(t, s.IsEnabled(settings, &unleashcontext.Context{Properties: map[string]string{EmailsParameter: "foo"}}))
+}
@@ -3,6 +3,7 @@ package featuretoggles
 import (
 	unleashcontext "github.com/Unleash/unleash-client-go/context"
 	"github.com/fabric8-services/fabric8-auth/log"
+	errs "github.com/pkg/errors"
 )
 
 const (
@@ -21,9 +22,30 @@ func (s EnableByLevelStrategy) Name() string {
 	return EnableByLevelStrategyName
 }
 
-// IsEnabled returns `true` if the given context is compatible with the settings configured on the Unleash server
+// verify that `EnableByLevelStrategy` validates its parameters
+var _ ParameterValidator = EnableByLevelStrategy{}
+
+// ValidateParameters verifies that the `level` parameter is one of the known levels, and that the optional `selfEnrollment`
+// parameter is a boolean
+func (s EnableByLevelStrategy) ValidateParameters(settings map[string]interface{}) error {
+	level, err := stringParameter(settings, LevelParameter)
+	if err != nil {
+		return err
+	}
+	if !IsKnownLevel(level) {
+		return errs.Errorf("invalid '%s' parameter: unknown level '%s' (expected internal|experimental|beta|released)", LevelParameter, level)
+	}
+	return validateBoolParameter(settings, SelfEnrollmentParameter)
+}
+
+// IsEnabled returns `true` if the given context is compatible with the settings configured on the Unleash server.
+// Returns `false` if the settings are invalid.
 func (s EnableByLevelStrategy) IsEnabled(settings map[string]interface{}, ctx *unleashcontext.Context) bool {
 	log.Debug(nil, map[string]interface{}{"settings_level": settings[LevelParameter], "context_level": ctx.Properties[LevelParameter]}, "checking if feature is enabled for user, based on his/her feature level...")
+	if err := s.ValidateParameters(settings); err != nil {
+		log.Debug(nil, map[string]interface{}{"strategy_name": s.Name(), "err": err.Error()}, "invalid strategy parameters: feature is not enabled")
+		return false
+	}
 	userLevel := ctx.Properties[LevelParameter]
 	featureLevel := toFeatureLevel(settings[LevelParameter].(string), unknown)
 	return featureLevel.IsEnabled(userLevel)
@@ -56,6 +56,30 @@ func TestFeatureIsEnabledByLevel(t *testing.T) {
 			// then
 			assert.False(t, result)
 		})
+
+		t.Run("invalid settings", func(t *testing.T) {
+			// given
+			ctx := &unleashcontext.Context{
+				Properties: map[string]string{
+					LevelParameter: "internal",
+				},
+			}
+			for name, settings := range map[string]map[string]interface{}{
+				"missing level":  {},
+				"empty level":    {LevelParameter: ""},
+				"numeric level":  {LevelParameter: 3},
+				"unknown level":  {LevelParameter: "beat"},
+				"invalid opt-in": {LevelParameter: BetaLevel, SelfEnrollmentParameter: "maybe"},
+			} {
+				t.Run(name, func(t *testing.T) {
+					// when
+					result := s.IsEnabled(settings, ctx)
+					// then
+					assert.False(t, result)
+					assert.Error(t, s.ValidateParameters(settings))
+				})
+			}
+		})
 	})
 
 }
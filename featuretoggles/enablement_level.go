@@ -33,6 +33,11 @@ const (
 	UnknownLevel = "unknown"
 )
 
+// Levels returns the known levels of features, from the least to the most stable one
+func Levels() []string {
+	return []string{InternalLevel, ExperimentalLevel, BetaLevel, ReleasedLevel}
+}
+
 // ComputeEnablementLevel computes the enablement level required to be able to use the given feature (if it is enabled at all)
 func ComputeEnablementLevel(ctx context.Context, feature unleashapi.Feature, internalUser bool) string {
 	log.Debug(ctx, map[string]interface{}{"feature_enabled": feature.Enabled}, "computing enablement level...")
@@ -50,7 +55,7 @@ func ComputeEnablementLevel(ctx context.Context, feature unleashapi.Feature, int
 					log.Debug(ctx, map[string]interface{}{"feature_name": feature.Name, "current_enablement_level": enablementLevel, "feature_level": featureLevel}, "computing enablement level")
 					// beta > experimental > internal (if user is a RH internal)
 					if featureLevel > enablementLevel {
-						if internalUser || (!internalUser && featureLevel != internal) {
+						if isAccessibleLevel(featureLevel, internalUser) {
 							log.Debug(ctx, map[string]interface{}{"feature_name": feature.Name, "current_enablement_level": enablementLevel, "feature_level": featureLevel, "internal_user": internalUser}, "retaining level")
 							enablementLevel = featureLevel
 						}
@@ -64,6 +69,24 @@ func ComputeEnablementLevel(ctx context.Context, feature unleashapi.Feature, int
 	return result
 }
 
+// isAccessibleLevel returns `true` if the given level of features is accessible to the user: all levels are accessible
+// to internal users, but the `internal` level is reserved to them.
+func isAccessibleLevel(level FeatureLevel, internalUser bool) bool {
+	return internalUser || level != internal
+}
+
+// IsSelectableLevel returns `true` if the given level is a known level of features that the user can opt-in for.
+// The `internal` level can only be selected by internal users, using the same rule as when computing the enablement level.
+func IsSelectableLevel(level string, internalUser bool) bool {
+	featureLevel := toFeatureLevel(level, unknown)
+	return featureLevel != unknown && isAccessibleLevel(featureLevel, internalUser)
+}
+
+// IsKnownLevel returns `true` if the given level is one of `internal`, `experimental`, `beta` or `released`
+func IsKnownLevel(level string) bool {
+	return toFeatureLevel(level, unknown) != unknown
+}
+
 func toFeatureLevel(level string, defaultLevel FeatureLevel) (result FeatureLevel) {
 	defer log.Debug(nil,
 		map[string]interface{}{
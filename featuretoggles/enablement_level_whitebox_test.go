@@ -236,3 +236,21 @@ func TestFeatureLevelConversion(t *testing.T) {
 		}
 	})
 }
+
+func TestIsSelectableLevel(t *testing.T) {
+
+	t.Run("external user", func(t *testing.T) {
+		assert.True(t, IsSelectableLevel(ReleasedLevel, false))
+		assert.True(t, IsSelectableLevel(BetaLevel, false))
+		assert.True(t, IsSelectableLevel("Experimental", false))
+		assert.False(t, IsSelectableLevel(InternalLevel, false))
+		assert.False(t, IsSelectableLevel("beat", false))
+		assert.False(t, IsSelectableLevel("", false))
+	})
+
+	t.Run("internal user", func(t *testing.T) {
+		assert.True(t, IsSelectableLevel(ReleasedLevel, true))
+		assert.True(t, IsSelectableLevel(InternalLevel, true))
+		assert.False(t, IsSelectableLevel(UnknownLevel, true))
+	})
+}
@@ -0,0 +1,262 @@
+package featuretoggles
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-auth/log"
+	errs "github.com/pkg/errors"
+)
+
+// Enrollment the choice of a user to join or leave a specific feature, regardless of his/her feature level
+type Enrollment string
+
+const (
+	// OptIn the user joined the feature
+	OptIn Enrollment = "opt-in"
+	// OptOut the user left the feature
+	OptOut Enrollment = "opt-out"
+	// NoEnrollment the user did not join or leave the feature
+	NoEnrollment Enrollment = ""
+)
+
+// SelfEnrollmentParameter the name of the (optional) parameter in the `enableByLevel` strategy
+// which specifies if users can opt-in for the feature, regardless of their level
+const SelfEnrollmentParameter string = "selfEnrollment"
+
+// IsValid returns `true` if the enrollment is `opt-in` or `opt-out`
+func (e Enrollment) IsValid() bool {
+	return e == OptIn || e == OptOut
+}
+
+// AllowsSelfEnrollment returns `true` if one of the feature's `enableByLevel` strategies has the `selfEnrollment` parameter set to `true`
+func AllowsSelfEnrollment(feature unleashapi.Feature) bool {
+	for _, s := range feature.Strategies {
+		if s.Name != EnableByLevelStrategyName {
+			continue
+		}
+		switch value := s.Parameters[SelfEnrollmentParameter].(type) {
+		case bool:
+			if value {
+				return true
+			}
+		case string:
+			if allowed, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil && allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EnrollmentStore the store of the users' enrollments
+type EnrollmentStore interface {
+	// Get returns the enrollment of the given user for the given feature, or `NoEnrollment` if there is none
+	Get(userID, featureName string) (Enrollment, error)
+	// Set records the enrollment of the given user for the given feature
+	Set(userID, featureName string, enrollment Enrollment) error
+	// Delete removes the enrollment of the given user for the given feature
+	Delete(userID, featureName string) error
+}
+
+// enrollments the enrollments indexed by user ID, then by feature name
+type enrollments map[string]map[string]Enrollment
+
+// FileEnrollmentStore an enrollment store that keeps the enrollments in memory and persists them in a JSON file which
+// can be shared by several replicas of the service (e.g., on a `ReadWriteMany` volume): the file is reloaded when
+// another replica changed it, and each change is applied on the latest content of the file while holding an exclusive
+// lock on it, so that the changes of the replicas do not overwrite each other.
+// If no file path is configured, the enrollments are only kept in memory.
+type FileEnrollmentStore struct {
+	mu          sync.Mutex
+	path        string
+	enrollments enrollments
+	// file the file when it was last loaded or saved. Since each save replaces the file, another file means
+	// that another replica changed the enrollments.
+	file os.FileInfo
+}
+
+// verify that `FileEnrollmentStore` is a valid impl of the `EnrollmentStore` interface
+var _ EnrollmentStore = &FileEnrollmentStore{}
+
+// NewFileEnrollmentStore returns a new store which loads and persists the enrollments in the file at the given path.
+// The directory of the file is created if needed, so that an unusable path is reported when the store is created rather
+// than when the first enrollment is lost. The file itself is created during the first change if it does not exist yet.
+func NewFileEnrollmentStore(path string) (*FileEnrollmentStore, error) {
+	s := FileEnrollmentStore{
+		path:        path,
+		enrollments: enrollments{},
+	}
+	if path == "" {
+		log.Warn(nil, map[string]interface{}{}, "no path configured for the enrollment store: user enrollments will not be persisted")
+		return &s, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errs.Wrapf(err, "unable to create the directory of the enrollments file '%s'", path)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	log.Info(nil, map[string]interface{}{"path": path, "users": len(s.enrollments)}, "loaded enrollments")
+	return &s, nil
+}
+
+// NewInMemoryEnrollmentStore returns a new store which does not persist the enrollments
+func NewInMemoryEnrollmentStore() *FileEnrollmentStore {
+	return &FileEnrollmentStore{
+		enrollments: enrollments{},
+	}
+}
+
+// Get returns the enrollment of the given user for the given feature, or `NoEnrollment` if there is none
+func (s *FileEnrollmentStore) Get(userID, featureName string) (Enrollment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.reload(); err != nil {
+		return NoEnrollment, err
+	}
+	return s.enrollments[userID][featureName], nil
+}
+
+// Set records the enrollment of the given user for the given feature
+func (s *FileEnrollmentStore) Set(userID, featureName string, enrollment Enrollment) error {
+	if !enrollment.IsValid() {
+		return errs.Errorf("invalid enrollment: '%s'", enrollment)
+	}
+	return s.update(func(e enrollments) bool {
+		if _, found := e[userID]; !found {
+			e[userID] = map[string]Enrollment{}
+		}
+		e[userID][featureName] = enrollment
+		return true
+	})
+}
+
+// Delete removes the enrollment of the given user for the given feature
+func (s *FileEnrollmentStore) Delete(userID, featureName string) error {
+	return s.update(func(e enrollments) bool {
+		if _, found := e[userID][featureName]; !found {
+			return false
+		}
+		delete(e[userID], featureName)
+		if len(e[userID]) == 0 {
+			delete(e, userID)
+		}
+		return true
+	})
+}
+
+// update applies the given change on the latest enrollments while holding the lock of the file, then saves them
+// if the change returned `true`
+func (s *FileEnrollmentStore) update(change func(enrollments) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.path == "" {
+		change(s.enrollments)
+		return nil
+	}
+	unlock, err := lockFile(s.path + ".lock")
+	if err != nil {
+		return errs.Wrap(err, "unable to save enrollments")
+	}
+	defer unlock()
+	if err := s.load(); err != nil {
+		return err
+	}
+	if !change(s.enrollments) {
+		return nil
+	}
+	return s.save()
+}
+
+// reload loads the enrollments again if the file was changed (by another replica) since it was last loaded or saved.
+// Must be called while holding the lock.
+func (s *FileEnrollmentStore) reload() error {
+	if s.path == "" {
+		return nil
+	}
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errs.Wrapf(err, "unable to read enrollments from '%s'", s.path)
+	}
+	if s.file != nil && os.SameFile(info, s.file) {
+		return nil
+	}
+	return s.load()
+}
+
+// load reads all enrollments from the file, if it exists. Must be called while holding the lock.
+func (s *FileEnrollmentStore) load() error {
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return errs.Wrapf(err, "unable to read enrollments from '%s'", s.path)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return errs.Wrapf(err, "unable to read enrollments from '%s'", s.path)
+	}
+	e := enrollments{}
+	if err := json.NewDecoder(f).Decode(&e); err != nil {
+		return errs.Wrapf(err, "unable to read enrollments from '%s'", s.path)
+	}
+	s.enrollments = e
+	s.file = info
+	return nil
+}
+
+// save writes all enrollments in a temporary file which then replaces the actual file, so that
+// the file is never left half-written. Must be called while holding the lock of the file.
+func (s *FileEnrollmentStore) save() error {
+	data, err := json.Marshal(s.enrollments)
+	if err != nil {
+		return errs.Wrap(err, "unable to save enrollments")
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(s.path), filepath.Base(s.path))
+	if err != nil {
+		return errs.Wrap(err, "unable to save enrollments")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errs.Wrap(err, "unable to save enrollments")
+	}
+	if err := tmp.Close(); err != nil {
+		return errs.Wrap(err, "unable to save enrollments")
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return errs.Wrap(err, "unable to save enrollments")
+	}
+	if info, err := os.Stat(s.path); err == nil {
+		s.file = info
+	}
+	return nil
+}
+
+// lockFile acquires an exclusive lock on the file at the given path (which is created if needed), and returns the
+// function which releases it. The lock is held on a separate file since the enrollments file is replaced on each save.
+func lockFile(path string) (func(), error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, errs.Wrapf(err, "unable to open the lock file '%s'", path)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, errs.Wrapf(err, "unable to lock the file '%s'", path)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
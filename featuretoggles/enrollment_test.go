@@ -0,0 +1,290 @@
+package featuretoggles_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	unleash "github.com/Unleash/unleash-client-go"
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	authclient "github.com/fabric8-services/fabric8-toggles-service/auth/client"
+	"github.com/fabric8-services/fabric8-toggles-service/errors"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	testfeaturetoggles "github.com/fabric8-services/fabric8-toggles-service/test/featuretoggles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileEnrollmentStore(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "enrollments")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "enrollments.json")
+	s, err := featuretoggles.NewFileEnrollmentStore(path)
+	require.NoError(t, err)
+
+	t.Run("set and reload", func(t *testing.T) {
+		// when
+		err := s.Set("user1", "foo", featuretoggles.OptIn)
+		require.NoError(t, err)
+		err = s.Set("user1", "bar", featuretoggles.OptOut)
+		require.NoError(t, err)
+		// then
+		reloaded, err := featuretoggles.NewFileEnrollmentStore(path)
+		require.NoError(t, err)
+		e, err := reloaded.Get("user1", "foo")
+		require.NoError(t, err)
+		assert.Equal(t, featuretoggles.OptIn, e)
+		e, err = reloaded.Get("user1", "bar")
+		require.NoError(t, err)
+		assert.Equal(t, featuretoggles.OptOut, e)
+		e, err = reloaded.Get("user2", "foo")
+		require.NoError(t, err)
+		assert.Equal(t, featuretoggles.NoEnrollment, e)
+	})
+
+	t.Run("delete and reload", func(t *testing.T) {
+		// when
+		err := s.Delete("user1", "foo")
+		require.NoError(t, err)
+		// then
+		reloaded, err := featuretoggles.NewFileEnrollmentStore(path)
+		require.NoError(t, err)
+		e, err := reloaded.Get("user1", "foo")
+		require.NoError(t, err)
+		assert.Equal(t, featuretoggles.NoEnrollment, e)
+	})
+
+	t.Run("invalid enrollment", func(t *testing.T) {
+		// when
+		err := s.Set("user1", "foo", featuretoggles.Enrollment("maybe"))
+		// then
+		require.Error(t, err)
+	})
+
+	t.Run("shared by replicas", func(t *testing.T) {
+		// given another replica which uses the same file
+		other, err := featuretoggles.NewFileEnrollmentStore(path)
+		require.NoError(t, err)
+		// when
+		err = s.Set("user2", "foo", featuretoggles.OptIn)
+		require.NoError(t, err)
+		err = other.Set("user2", "bar", featuretoggles.OptOut)
+		require.NoError(t, err)
+		// then each replica sees the changes of the other one
+		e, err := other.Get("user2", "foo")
+		require.NoError(t, err)
+		assert.Equal(t, featuretoggles.OptIn, e)
+		e, err = s.Get("user2", "bar")
+		require.NoError(t, err)
+		assert.Equal(t, featuretoggles.OptOut, e)
+	})
+
+	t.Run("concurrent changes by replicas", func(t *testing.T) {
+		// given
+		replicas := make([]*featuretoggles.FileEnrollmentStore, 2)
+		for i := range replicas {
+			replicas[i], err = featuretoggles.NewFileEnrollmentStore(path)
+			require.NoError(t, err)
+		}
+		// when
+		wg := sync.WaitGroup{}
+		for i, r := range replicas {
+			for j := 0; j < 10; j++ {
+				wg.Add(1)
+				go func(r *featuretoggles.FileEnrollmentStore, feature string) {
+					defer wg.Done()
+					assert.NoError(t, r.Set("user3", feature, featuretoggles.OptIn))
+				}(r, fmt.Sprintf("feature-%d-%d", i, j))
+			}
+		}
+		wg.Wait()
+		// then no change was lost
+		reloaded, err := featuretoggles.NewFileEnrollmentStore(path)
+		require.NoError(t, err)
+		for i := range replicas {
+			for j := 0; j < 10; j++ {
+				e, err := reloaded.Get("user3", fmt.Sprintf("feature-%d-%d", i, j))
+				require.NoError(t, err)
+				assert.Equal(t, featuretoggles.OptIn, e)
+			}
+		}
+	})
+}
+
+func TestEnrollment(t *testing.T) {
+	// given
+	selfEnrollmentFeature := unleashapi.Feature{
+		Name:    "self-enrollment",
+		Enabled: true,
+		Strategies: []unleashapi.Strategy{
+			{
+				Name: featuretoggles.EnableByLevelStrategyName,
+				Parameters: map[string]interface{}{
+					featuretoggles.LevelParameter:          featuretoggles.ExperimentalLevel,
+					featuretoggles.SelfEnrollmentParameter: "true",
+				},
+			},
+		},
+	}
+	betaFeature := unleashapi.Feature{
+		Name:    "beta",
+		Enabled: true,
+		Strategies: []unleashapi.Strategy{
+			{
+				Name: featuretoggles.EnableByLevelStrategyName,
+				Parameters: map[string]interface{}{
+					featuretoggles.LevelParameter: featuretoggles.BetaLevel,
+				},
+			},
+		},
+	}
+	allFeatures := []unleashapi.Feature{selfEnrollmentFeature, betaFeature}
+	mockUnleashClient := testfeaturetoggles.NewUnleashClientMock(t)
+	mockUnleashClient.GetFeatureFunc = func(name string) *unleashapi.Feature {
+		for _, f := range allFeatures {
+			if f.Name == name {
+				return &f
+			}
+		}
+		return nil
+	}
+	mockUnleashClient.IsEnabledFunc = func(feature string, options ...unleash.FeatureOption) (enabled bool) {
+		// the beta user is only enabled on the beta feature
+		return feature == betaFeature.Name
+	}
+	betaLevel := featuretoggles.BetaLevel
+	userID := "user1"
+	user := &authclient.User{
+		Data: &authclient.UserData{
+			ID: &userID,
+			Attributes: &authclient.UserDataAttributes{
+				FeatureLevel: &betaLevel,
+			},
+		},
+	}
+
+	t.Run("opt-in", func(t *testing.T) {
+
+		t.Run("feature allows self-enrollment", func(t *testing.T) {
+			// given
+			ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+			// when
+			err := ft.Enroll(context.Background(), selfEnrollmentFeature.Name, user, featuretoggles.OptIn)
+			// then
+			require.NoError(t, err)
+			f := ft.GetFeature(context.Background(), selfEnrollmentFeature.Name, user)
+			assert.True(t, f.UserEnabled)
+			assert.Equal(t, featuretoggles.OptIn, f.Override)
+		})
+
+		t.Run("feature does not allow self-enrollment", func(t *testing.T) {
+			// given
+			ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+			// when
+			err := ft.Enroll(context.Background(), betaFeature.Name, user, featuretoggles.OptIn)
+			// then
+			require.Error(t, err)
+			forbidden, _ := errors.IsForbiddenError(err)
+			assert.True(t, forbidden)
+		})
+
+		t.Run("self-enrollment revoked after opt-in", func(t *testing.T) {
+			// given a feature which allows self-enrollment when the user opts in
+			revokedFeature := unleashapi.Feature{
+				Name:    "revoked",
+				Enabled: true,
+				Strategies: []unleashapi.Strategy{
+					{
+						Name: featuretoggles.EnableByLevelStrategyName,
+						Parameters: map[string]interface{}{
+							featuretoggles.LevelParameter:          featuretoggles.ExperimentalLevel,
+							featuretoggles.SelfEnrollmentParameter: "true",
+						},
+					},
+				},
+			}
+			revokedClient := testfeaturetoggles.NewUnleashClientMock(t)
+			revokedClient.GetFeatureFunc = func(name string) *unleashapi.Feature {
+				if name == revokedFeature.Name {
+					f := revokedFeature
+					return &f
+				}
+				return nil
+			}
+			revokedClient.IsEnabledFunc = func(feature string, options ...unleash.FeatureOption) (enabled bool) {
+				// the beta user is not enabled on the experimental feature
+				return false
+			}
+			ft := featuretoggles.NewClientWithState(revokedClient, true)
+			err := ft.Enroll(context.Background(), revokedFeature.Name, user, featuretoggles.OptIn)
+			require.NoError(t, err)
+			require.True(t, ft.GetFeature(context.Background(), revokedFeature.Name, user).UserEnabled)
+			// when the feature no longer allows self-enrollment
+			revokedFeature.Strategies = []unleashapi.Strategy{
+				{
+					Name: featuretoggles.EnableByLevelStrategyName,
+					Parameters: map[string]interface{}{
+						featuretoggles.LevelParameter: featuretoggles.ExperimentalLevel,
+					},
+				},
+			}
+			f := ft.GetFeature(context.Background(), revokedFeature.Name, user)
+			// then
+			assert.False(t, f.UserEnabled)
+			assert.Equal(t, featuretoggles.NoEnrollment, f.Override)
+		})
+	})
+
+	t.Run("opt-out", func(t *testing.T) {
+		// given
+		ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+		// when
+		err := ft.Enroll(context.Background(), betaFeature.Name, user, featuretoggles.OptOut)
+		// then
+		require.NoError(t, err)
+		f := ft.GetFeature(context.Background(), betaFeature.Name, user)
+		assert.False(t, f.UserEnabled)
+		assert.Equal(t, featuretoggles.OptOut, f.Override)
+
+		t.Run("unenroll", func(t *testing.T) {
+			// when
+			err := ft.Unenroll(context.Background(), betaFeature.Name, user)
+			// then
+			require.NoError(t, err)
+			f := ft.GetFeature(context.Background(), betaFeature.Name, user)
+			assert.True(t, f.UserEnabled)
+			assert.Equal(t, featuretoggles.NoEnrollment, f.Override)
+		})
+	})
+
+	t.Run("failures", func(t *testing.T) {
+
+		t.Run("unknown feature", func(t *testing.T) {
+			// given
+			ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+			// when
+			err := ft.Enroll(context.Background(), "unknown", user, featuretoggles.OptOut)
+			// then
+			require.Error(t, err)
+			notFound, _ := errors.IsNotFoundError(err)
+			assert.True(t, notFound)
+		})
+
+		t.Run("anonymous user", func(t *testing.T) {
+			// given
+			ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+			// when
+			err := ft.Enroll(context.Background(), betaFeature.Name, nil, featuretoggles.OptOut)
+			// then
+			require.Error(t, err)
+			unauthorized, _ := errors.IsUnauthorizedError(err)
+			assert.True(t, unauthorized)
+		})
+	})
+}
@@ -0,0 +1,121 @@
+package featuretoggles
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	errs "github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// ExportVersion the version of the format of the exported feature repositories
+const ExportVersion = 1
+
+// ExportFormat the format of an exported feature repository
+type ExportFormat string
+
+const (
+	// JSONFormat the JSON format, which can also be read with `ReadSnapshot` (and by the unleash client as a backup file)
+	JSONFormat ExportFormat = "json"
+	// YAMLFormat the YAML format
+	YAMLFormat ExportFormat = "yaml"
+)
+
+// Export a versioned copy of all the features of a repository, with their strategies and parameters
+type Export struct {
+	Version    int                  `json:"version"`
+	ExportedAt time.Time            `json:"exportedAt"`
+	Features   []unleashapi.Feature `json:"features"`
+}
+
+// NewExport returns a new export of the given features, sorted by name
+func NewExport(features []unleashapi.Feature) Export {
+	sorted := make([]unleashapi.Feature, len(features))
+	copy(sorted, features)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return Export{
+		Version:    ExportVersion,
+		ExportedAt: time.Now().UTC(),
+		Features:   sorted,
+	}
+}
+
+// ParseExportFormat returns the format with the given name
+func ParseExportFormat(format string) (ExportFormat, error) {
+	switch ExportFormat(format) {
+	case JSONFormat, YAMLFormat:
+		return ExportFormat(format), nil
+	default:
+		return "", errs.Errorf("invalid format '%s' (expected 'json' or 'yaml')", format)
+	}
+}
+
+// WriteExport writes the given export in the given format. The YAML document has the same attributes as the JSON document.
+func WriteExport(w io.Writer, export Export, format ExportFormat) error {
+	content, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return errs.Wrap(err, "unable to write the export")
+	}
+	if format == YAMLFormat {
+		var doc interface{}
+		if err := json.Unmarshal(content, &doc); err != nil {
+			return errs.Wrap(err, "unable to write the export")
+		}
+		if content, err = yaml.Marshal(doc); err != nil {
+			return errs.Wrap(err, "unable to write the export")
+		}
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+// ReadExport reads an export in the given format. Returns an error if the export has a newer version than `ExportVersion`.
+func ReadExport(r io.Reader, format ExportFormat) (Export, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Export{}, errs.Wrap(err, "unable to read the export")
+	}
+	if format == YAMLFormat {
+		var doc interface{}
+		if err := yaml.Unmarshal(content, &doc); err != nil {
+			return Export{}, errs.Wrap(err, "unable to read the export")
+		}
+		if content, err = json.Marshal(toJSONValue(doc)); err != nil {
+			return Export{}, errs.Wrap(err, "unable to read the export")
+		}
+	}
+	var export Export
+	if err := json.Unmarshal(content, &export); err != nil {
+		return Export{}, errs.Wrap(err, "unable to read the export")
+	}
+	if export.Version > ExportVersion {
+		return Export{}, errs.Errorf("unsupported export version %d (expected %d or lower)", export.Version, ExportVersion)
+	}
+	return export, nil
+}
+
+// toJSONValue converts the maps decoded from YAML (whose keys are not necessarily strings) to maps which can be encoded in JSON
+func toJSONValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			result[fmt.Sprintf("%v", key)] = toJSONValue(val)
+		}
+		return result
+	case []interface{}:
+		for i, val := range v {
+			v[i] = toJSONValue(val)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,116 @@
+package featuretoggles_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	// given
+	features := []unleashapi.Feature{
+		{
+			Name:        "foo.bar",
+			Description: "Foo Bar",
+			Enabled:     true,
+			Strategies: []unleashapi.Strategy{
+				{
+					Name:       featuretoggles.EnableByEmailsStrategyName,
+					Parameters: map[string]interface{}{featuretoggles.EmailsParameter: "user@foo.com"},
+				},
+			},
+		},
+		{
+			Name:        "foo",
+			Description: "Foo",
+			Enabled:     true,
+			Strategies: []unleashapi.Strategy{
+				{
+					Name:       featuretoggles.EnableByLevelStrategyName,
+					Parameters: map[string]interface{}{featuretoggles.LevelParameter: featuretoggles.BetaLevel},
+				},
+			},
+		},
+	}
+	export := featuretoggles.NewExport(features)
+
+	t.Run("sorted features", func(t *testing.T) {
+		assert.Equal(t, featuretoggles.ExportVersion, export.Version)
+		require.Len(t, export.Features, 2)
+		assert.Equal(t, "foo", export.Features[0].Name)
+		assert.Equal(t, "foo.bar", export.Features[1].Name)
+	})
+
+	for _, format := range []featuretoggles.ExportFormat{featuretoggles.JSONFormat, featuretoggles.YAMLFormat} {
+		t.Run("round trip in "+string(format), func(t *testing.T) {
+			// given
+			buf := &bytes.Buffer{}
+			// when
+			err := featuretoggles.WriteExport(buf, export, format)
+			require.NoError(t, err)
+			result, err := featuretoggles.ReadExport(buf, format)
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, export.Version, result.Version)
+			assert.True(t, export.ExportedAt.Equal(result.ExportedAt))
+			assert.Equal(t, export.Features, result.Features)
+		})
+	}
+
+	t.Run("json export readable as a snapshot", func(t *testing.T) {
+		// given
+		buf := &bytes.Buffer{}
+		err := featuretoggles.WriteExport(buf, export, featuretoggles.JSONFormat)
+		require.NoError(t, err)
+		// when
+		s, err := featuretoggles.ReadSnapshot(buf)
+		// then
+		require.NoError(t, err)
+		assert.NotNil(t, s.GetFeature("foo.bar"))
+		assert.Len(t, s.Features(), 2)
+	})
+}
+
+func TestReadExport(t *testing.T) {
+
+	t.Run("yaml", func(t *testing.T) {
+		// given
+		content := `version: 1
+features:
+- name: foo
+  description: Foo
+  enabled: true
+  strategies:
+  - name: enableByLevel
+    parameters:
+      level: beta
+`
+		// when
+		export, err := featuretoggles.ReadExport(strings.NewReader(content), featuretoggles.YAMLFormat)
+		// then
+		require.NoError(t, err)
+		require.Len(t, export.Features, 1)
+		assert.Equal(t, "foo", export.Features[0].Name)
+		assert.Equal(t, "beta", export.Features[0].Strategies[0].Parameters[featuretoggles.LevelParameter])
+	})
+
+	t.Run("unsupported version", func(t *testing.T) {
+		// when
+		_, err := featuretoggles.ReadExport(strings.NewReader(`{"version": 2, "features": []}`), featuretoggles.JSONFormat)
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported export version 2")
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		// when
+		_, err := featuretoggles.ParseExportFormat("xml")
+		// then
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,53 @@
+package featuretoggles
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// groupNamePattern the groups are dotted names, e.g. `planner.board`
+	groupNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+(\.[A-Za-z0-9_-]+)*$`)
+	// groupGlobPattern the glob patterns of groups are dotted names in which `*` matches any part of a segment,
+	// e.g. `planner.*` or `*.beta`
+	groupGlobPattern = regexp.MustCompile(`^[A-Za-z0-9_*-]+(\.[A-Za-z0-9_*-]+)*$`)
+)
+
+// ReservedNames the names which cannot be given to a feature, since the `GET /api/features/<name>` request of such
+// a feature would be served by another endpoint (e.g. `/api/features/catalog`). Their sub-features are valid.
+var ReservedNames = []string{"catalog", "export", "problems", "stale", "tree"}
+
+// IsValidGroup returns `true` if the given group is a dotted name (e.g. `planner.board`), or a dotted name
+// with `*` wildcards if `glob` is `true` (e.g. `planner.*` or `*.beta`)
+func IsValidGroup(group string, glob bool) bool {
+	if glob {
+		return groupGlobPattern.MatchString(group)
+	}
+	return groupNamePattern.MatchString(group)
+}
+
+// IsValidName returns `true` if the given feature name is a dotted name (e.g. `planner.board`), so that
+// the group of a feature selects the feature and its sub-features, and if it is not reserved (see `ReservedNames`)
+func IsValidName(name string) bool {
+	return groupNamePattern.MatchString(name) && !IsReservedName(name)
+}
+
+// IsReservedName returns `true` if the given name is one of the `ReservedNames`
+func IsReservedName(name string) bool {
+	return contains(ReservedNames, name)
+}
+
+// GroupsPattern returns the regular expression which matches the features of the given groups, i.e., the features
+// named after a group and their sub-features. The groups are escaped, unless `glob` is `true`, in which case
+// a `*` matches any part of a segment of the feature names (but not the `.` separator).
+func GroupsPattern(groups []string, glob bool) string {
+	alternatives := make([]string, len(groups))
+	for i, group := range groups {
+		alternatives[i] = regexp.QuoteMeta(group)
+		if glob {
+			alternatives[i] = strings.Replace(alternatives[i], `\*`, `[^.]*`, -1)
+		}
+	}
+	return fmt.Sprintf(`^(%s)(\..*)?$`, strings.Join(alternatives, "|"))
+}
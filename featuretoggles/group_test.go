@@ -0,0 +1,74 @@
+package featuretoggles_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsValidGroup(t *testing.T) {
+
+	t.Run("names", func(t *testing.T) {
+		for _, group := range []string{"planner", "planner.board", "Planner_Board-2"} {
+			assert.True(t, featuretoggles.IsValidGroup(group, false), group)
+			assert.True(t, featuretoggles.IsValidName(group), group)
+		}
+		for _, group := range []string{"", ".*", "planner|deploy", "planner.", ".planner", "planner..board", "planner.*", "(planner)"} {
+			assert.False(t, featuretoggles.IsValidGroup(group, false), group)
+			assert.False(t, featuretoggles.IsValidName(group), group)
+		}
+	})
+
+	t.Run("reserved names", func(t *testing.T) {
+		for _, name := range featuretoggles.ReservedNames {
+			// a reserved name is still a valid group, e.g., to select its sub-features
+			assert.True(t, featuretoggles.IsValidGroup(name, false), name)
+			assert.False(t, featuretoggles.IsValidName(name), name)
+			assert.True(t, featuretoggles.IsValidName(name+".foo"), name)
+		}
+	})
+
+	t.Run("globs", func(t *testing.T) {
+		for _, group := range []string{"planner", "planner.*", "*.beta", "plan*.board"} {
+			assert.True(t, featuretoggles.IsValidGroup(group, true), group)
+		}
+		for _, group := range []string{".*", "planner|deploy", "planner.?", "planner..*"} {
+			assert.False(t, featuretoggles.IsValidGroup(group, true), group)
+		}
+	})
+}
+
+func TestGroupsPattern(t *testing.T) {
+	// given
+	names := []string{"planner", "planner.board", "planner.board.beta", "plannerx", "deploy", "deploy.beta", "analyze"}
+	matching := func(pattern string) []string {
+		result := []string{}
+		for _, name := range names {
+			if regexp.MustCompile(pattern).MatchString(name) {
+				result = append(result, name)
+			}
+		}
+		return result
+	}
+
+	t.Run("single group", func(t *testing.T) {
+		assert.Equal(t, []string{"planner", "planner.board", "planner.board.beta"}, matching(featuretoggles.GroupsPattern([]string{"planner"}, false)))
+	})
+
+	t.Run("multiple groups", func(t *testing.T) {
+		assert.Equal(t, []string{"planner.board", "planner.board.beta", "deploy", "deploy.beta"}, matching(featuretoggles.GroupsPattern([]string{"planner.board", "deploy"}, false)))
+	})
+
+	t.Run("escaped", func(t *testing.T) {
+		assert.Empty(t, matching(featuretoggles.GroupsPattern([]string{".*"}, false)))
+		assert.Empty(t, matching(featuretoggles.GroupsPattern([]string{"planner.*"}, false)))
+	})
+
+	t.Run("glob", func(t *testing.T) {
+		assert.Equal(t, []string{"planner.board", "planner.board.beta"}, matching(featuretoggles.GroupsPattern([]string{"planner.*"}, true)))
+		assert.Equal(t, []string{"deploy.beta"}, matching(featuretoggles.GroupsPattern([]string{"*.beta"}, true)))
+		assert.Equal(t, []string{"planner", "planner.board", "planner.board.beta", "plannerx"}, matching(featuretoggles.GroupsPattern([]string{"plan*"}, true)))
+	})
+}
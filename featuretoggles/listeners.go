@@ -7,11 +7,21 @@ import (
 
 // UnleashClientListener a listener to the unleash client. Retains the `ready` state of the client it is registered to.
 type UnleashClientListener struct {
-	ready bool
+	ready    bool
+	recorder Recorder
+}
+
+// metrics returns the recorder of the listener, or a recorder which records nothing if none was set
+func (l *UnleashClientListener) metrics() Recorder {
+	if l.recorder == nil {
+		return nopRecorder{}
+	}
+	return l.recorder
 }
 
 // OnError prints out errors.
 func (l *UnleashClientListener) OnError(err error) {
+	l.metrics().RecordUnleashError()
 	log.Error(nil, map[string]interface{}{
 		"err": err.Error(),
 	}, "toggles error")
@@ -19,6 +29,7 @@ func (l *UnleashClientListener) OnError(err error) {
 
 // OnWarning prints out warning.
 func (l *UnleashClientListener) OnWarning(warning error) {
+	l.metrics().RecordUnleashWarning()
 	log.Warn(nil, map[string]interface{}{
 		"err": warning.Error(),
 	}, "toggles warning")
@@ -27,12 +38,13 @@ func (l *UnleashClientListener) OnWarning(warning error) {
 // OnReady prints to the console when the repository is ready.
 func (l *UnleashClientListener) OnReady() {
 	l.ready = true
+	l.metrics().SetUnleashReady(true)
 	log.Info(nil, map[string]interface{}{}, "toggles ready")
 }
 
-// OnCount prints to the console when the feature is queried.
+// OnCount prints to the console when the feature is queried. The evaluations are counted in the metrics instead.
 func (l *UnleashClientListener) OnCount(name string, enabled bool) {
-	log.Info(nil, map[string]interface{}{
+	log.Debug(nil, map[string]interface{}{
 		"name":    name,
 		"enabled": enabled,
 	}, "toggles count")
@@ -0,0 +1,112 @@
+package featuretoggles
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// the types of features
+const (
+	// ReleaseType a feature which hides the code of a release until it is ready (removed once released)
+	ReleaseType = "release"
+	// ExperimentType a feature used to run an experiment (e.g., with variants) for a limited time
+	ExperimentType = "experiment"
+	// OpsType a feature used to control the operational aspects of the service (e.g., a kill switch), which may last forever
+	OpsType = "ops"
+	// PermissionType a feature which grants access to some users, which may last forever
+	PermissionType = "permission"
+)
+
+// IsKnownType returns `true` if the given type is one of `release`, `experiment`, `ops` or `permission`
+func IsKnownType(featureType string) bool {
+	switch featureType {
+	case ReleaseType, ExperimentType, OpsType, PermissionType:
+		return true
+	default:
+		return false
+	}
+}
+
+// Metadata the optional metadata of a feature, which are not stored in the Unleash server
+type Metadata struct {
+	// Owner the team or person in charge of the feature (e.g., an email address)
+	Owner string `json:"owner,omitempty"`
+	// Type the type of feature (`release`, `experiment`, `ops` or `permission`)
+	Type string `json:"type,omitempty"`
+	// Created the date when the feature was created
+	Created time.Time `json:"created,omitempty"`
+	// Expires the date when the feature is expected to be removed
+	Expires time.Time `json:"expires,omitempty"`
+	// Tags the tags of the feature (e.g., `ui` or `needs-docs`)
+	Tags []string `json:"tags,omitempty"`
+}
+
+// MetadataProvider provides the metadata of the features
+type MetadataProvider interface {
+	// GetMetadata returns the metadata of the feature with the given name, and `false` if the feature has no metadata
+	GetMetadata(name string) (Metadata, bool)
+}
+
+// tagPattern the tags are lowercase words, optionally separated with `-`, `_` or `.` (but no comma, which separates
+// the alternative tags in a query)
+var tagPattern = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*$`)
+
+// IsValidTag returns `true` if the given tag is a lowercase word, optionally separated with `-`, `_` or `.` (e.g. `needs-docs`)
+func IsValidTag(tag string) bool {
+	return tagPattern.MatchString(tag)
+}
+
+// TagQuery a query on the tags of the features: a feature matches if, for each group of tags, it has at least one of the tags
+// of the group (i.e., the groups are combined with AND and the tags of a group are combined with OR)
+type TagQuery [][]string
+
+// ParseTagQuery returns the query for the given values, in which the alternative tags are comma-separated.
+// E.g., `[]string{"ui", "planner,analyze"}` matches the features tagged `ui` and either `planner` or `analyze`.
+func ParseTagQuery(values []string) TagQuery {
+	query := TagQuery{}
+	for _, value := range values {
+		group := []string{}
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.ToLower(strings.TrimSpace(tag)); tag != "" {
+				group = append(group, tag)
+			}
+		}
+		if len(group) > 0 {
+			query = append(query, group)
+		}
+	}
+	return query
+}
+
+// Matches returns `true` if the given tags match the query (an empty query matches all tags)
+func (q TagQuery) Matches(tags []string) bool {
+	for _, group := range q {
+		if !containsAny(tags, group) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the features whose tags match the query
+func (q TagQuery) Filter(features []UserFeature) []UserFeature {
+	result := make([]UserFeature, 0, len(features))
+	for _, f := range features {
+		if q.Matches(f.Tags) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+func containsAny(tags []string, candidates []string) bool {
+	for _, t := range tags {
+		for _, c := range candidates {
+			if t == c {
+				return true
+			}
+		}
+	}
+	return false
+}
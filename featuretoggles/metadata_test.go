@@ -0,0 +1,44 @@
+package featuretoggles_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagQuery(t *testing.T) {
+	// given
+	query := featuretoggles.ParseTagQuery([]string{"UI", " planner, analyze ", ""})
+
+	t.Run("parse", func(t *testing.T) {
+		assert.Equal(t, featuretoggles.TagQuery{{"ui"}, {"planner", "analyze"}}, query)
+	})
+
+	t.Run("matches", func(t *testing.T) {
+		assert.True(t, query.Matches([]string{"ui", "planner"}))
+		assert.True(t, query.Matches([]string{"analyze", "ui", "needs-docs"}))
+		assert.False(t, query.Matches([]string{"ui"}))
+		assert.False(t, query.Matches([]string{"planner", "analyze"}))
+		assert.False(t, query.Matches(nil))
+		assert.True(t, featuretoggles.ParseTagQuery(nil).Matches(nil))
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		// when
+		result := query.Filter([]featuretoggles.UserFeature{
+			{Name: "foo", Tags: []string{"ui", "planner"}},
+			{Name: "bar", Tags: []string{"ui"}},
+		})
+		// then
+		assert.Equal(t, []featuretoggles.UserFeature{{Name: "foo", Tags: []string{"ui", "planner"}}}, result)
+	})
+}
+
+func TestIsValidTag(t *testing.T) {
+	assert.True(t, featuretoggles.IsValidTag("ui"))
+	assert.True(t, featuretoggles.IsValidTag("needs-docs"))
+	assert.False(t, featuretoggles.IsValidTag("UI"))
+	assert.False(t, featuretoggles.IsValidTag("ui,planner"))
+	assert.False(t, featuretoggles.IsValidTag(""))
+}
@@ -0,0 +1,106 @@
+package featuretoggles
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+)
+
+// PrerequisitesParameter the optional parameter of the strategies with the comma-separated names of the features which must
+// be enabled for the user for the feature to be enabled (e.g., `planner.board` for the `planner.board.swimlanes` feature)
+const PrerequisitesParameter string = "prerequisites"
+
+// Prerequisites returns the prerequisites of the given feature, declared in the parameters of any of its strategies
+func Prerequisites(feature unleashapi.Feature) []string {
+	result := []string{}
+	for _, s := range feature.Strategies {
+		value, ok := s.Parameters[PrerequisitesParameter].(string)
+		if !ok {
+			continue
+		}
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" && !contains(result, name) {
+				result = append(result, name)
+			}
+		}
+	}
+	return result
+}
+
+// ValidatePrerequisites returns the problems with the prerequisites of the given features: invalid parameters,
+// unknown prerequisites and cycles (in which case the features of the cycle are never enabled)
+func ValidatePrerequisites(features []unleashapi.Feature) []Problem {
+	problems := []Problem{}
+	graph := make(map[string][]string, len(features))
+	for _, f := range features {
+		graph[f.Name] = Prerequisites(f)
+	}
+	for _, f := range features {
+		for _, s := range f.Strategies {
+			value, found := s.Parameters[PrerequisitesParameter]
+			if !found {
+				continue
+			}
+			if _, ok := value.(string); !ok {
+				problems = append(problems, Problem{Feature: f.Name, Strategy: s.Name,
+					Message: fmt.Sprintf("invalid '%s' parameter: expected a string but got '%v' (%T)", PrerequisitesParameter, value, value)})
+			}
+		}
+		strategy := prerequisitesStrategy(f)
+		for _, name := range graph[f.Name] {
+			if _, found := graph[name]; !found {
+				problems = append(problems, Problem{Feature: f.Name, Strategy: strategy, Message: fmt.Sprintf("unknown prerequisite '%s'", name)})
+			}
+		}
+		if cycle := findCycle(graph, f.Name); cycle != nil {
+			problems = append(problems, Problem{Feature: f.Name, Strategy: strategy, Message: fmt.Sprintf("prerequisites cycle: %s", strings.Join(cycle, " -> "))})
+		}
+	}
+	return problems
+}
+
+// prerequisitesStrategy returns the name of the first strategy of the feature which declares prerequisites
+func prerequisitesStrategy(feature unleashapi.Feature) string {
+	for _, s := range feature.Strategies {
+		if _, found := s.Parameters[PrerequisitesParameter]; found {
+			return s.Name
+		}
+	}
+	return ""
+}
+
+// findCycle returns the path of the prerequisites from the given feature back to itself, or `nil` if the feature
+// is not part of a cycle. The prerequisites are visited in alphabetical order, so that the result is stable.
+func findCycle(graph map[string][]string, start string) []string {
+	visited := map[string]bool{}
+	var visit func(path []string) []string
+	visit = func(path []string) []string {
+		prerequisites := append([]string{}, graph[path[len(path)-1]]...)
+		sort.Strings(prerequisites)
+		for _, name := range prerequisites {
+			if name == start {
+				return append(path, name)
+			}
+			if visited[name] {
+				continue
+			}
+			visited[name] = true
+			if cycle := visit(append(path, name)); cycle != nil {
+				return cycle
+			}
+		}
+		return nil
+	}
+	return visit([]string{start})
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
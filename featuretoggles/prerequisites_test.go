@@ -0,0 +1,215 @@
+package featuretoggles_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	unleash "github.com/Unleash/unleash-client-go"
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	testfeaturetoggles "github.com/fabric8-services/fabric8-toggles-service/test/featuretoggles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFeatureWithPrerequisites returns a feature released to all users, with the given prerequisites
+func newFeatureWithPrerequisites(name, prerequisites string) unleashapi.Feature {
+	parameters := map[string]interface{}{
+		featuretoggles.LevelParameter: featuretoggles.ReleasedLevel,
+	}
+	if prerequisites != "" {
+		parameters[featuretoggles.PrerequisitesParameter] = prerequisites
+	}
+	return unleashapi.Feature{
+		Name:    name,
+		Enabled: true,
+		Strategies: []unleashapi.Strategy{
+			{
+				Name:       featuretoggles.EnableByLevelStrategyName,
+				Parameters: parameters,
+			},
+		},
+	}
+}
+
+func TestPrerequisites(t *testing.T) {
+	// given
+	feature := newFeatureWithPrerequisites("planner.board.swimlanes", " planner.board, planner,,planner.board")
+	feature.Strategies = append(feature.Strategies, unleashapi.Strategy{
+		Name: featuretoggles.EnableByEmailsStrategyName,
+		Parameters: map[string]interface{}{
+			featuretoggles.EmailsParameter:        "foo@foo.com",
+			featuretoggles.PrerequisitesParameter: "analyze",
+		},
+	})
+	// when
+	prerequisites := featuretoggles.Prerequisites(feature)
+	// then
+	assert.Equal(t, []string{"planner.board", "planner", "analyze"}, prerequisites)
+}
+
+func TestValidatePrerequisites(t *testing.T) {
+
+	t.Run("valid prerequisites", func(t *testing.T) {
+		// when
+		problems := featuretoggles.ValidateFeatures([]unleashapi.Feature{
+			newFeatureWithPrerequisites("planner", ""),
+			newFeatureWithPrerequisites("planner.board", "planner"),
+			newFeatureWithPrerequisites("planner.board.swimlanes", "planner.board,planner"),
+		})
+		// then
+		assert.Empty(t, problems)
+	})
+
+	t.Run("unknown prerequisite", func(t *testing.T) {
+		// when
+		problems := featuretoggles.ValidateFeatures([]unleashapi.Feature{
+			newFeatureWithPrerequisites("planner.board", "planner"),
+		})
+		// then
+		require.Len(t, problems, 1)
+		assert.Equal(t, "feature 'planner.board', strategy 'enableByLevel': unknown prerequisite 'planner'", problems[0].String())
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		// when
+		problems := featuretoggles.ValidateFeatures([]unleashapi.Feature{
+			newFeatureWithPrerequisites("a", "b"),
+			newFeatureWithPrerequisites("b", "c"),
+			newFeatureWithPrerequisites("c", "a"),
+			newFeatureWithPrerequisites("d", "a"),
+			newFeatureWithPrerequisites("e", "e"),
+		})
+		// then the features which depend on a cycle are not part of it
+		require.Len(t, problems, 4)
+		assert.Equal(t, "prerequisites cycle: a -> b -> c -> a", problems[0].Message)
+		assert.Equal(t, "prerequisites cycle: b -> c -> a -> b", problems[1].Message)
+		assert.Equal(t, "prerequisites cycle: c -> a -> b -> c", problems[2].Message)
+		assert.Equal(t, "prerequisites cycle: e -> e", problems[3].Message)
+	})
+
+	t.Run("invalid parameter", func(t *testing.T) {
+		// given
+		feature := newFeatureWithPrerequisites("planner.board", "")
+		feature.Strategies[0].Parameters[featuretoggles.PrerequisitesParameter] = []string{"planner"}
+		// when
+		problems := featuretoggles.ValidateFeatures([]unleashapi.Feature{feature})
+		// then
+		require.Len(t, problems, 1)
+		assert.Contains(t, problems[0].Message, "invalid 'prerequisites' parameter")
+	})
+}
+
+func TestGetFeatureWithPrerequisites(t *testing.T) {
+	// given
+	features := map[string]unleashapi.Feature{}
+	// the 'planner' feature is disabled for the user
+	planner := newFeatureWithPrerequisites("planner", "")
+	planner.Strategies[0].Parameters[featuretoggles.LevelParameter] = featuretoggles.BetaLevel
+	for _, f := range []unleashapi.Feature{
+		planner,
+		newFeatureWithPrerequisites("planner.board", "planner"),
+		newFeatureWithPrerequisites("planner.board.swimlanes", "planner.board"),
+		newFeatureWithPrerequisites("deploy", ""),
+		newFeatureWithPrerequisites("deploy.pipelines", "deploy"),
+		newFeatureWithPrerequisites("orphan", "unknown"),
+		newFeatureWithPrerequisites("a", "b"),
+		newFeatureWithPrerequisites("b", "a"),
+	} {
+		features[f.Name] = f
+	}
+	// a chain of diamonds: each level depends twice on the next one, through a left and a right feature
+	const depth = 20
+	for i := 0; i < depth; i++ {
+		next := fmt.Sprintf("diamond.%d", i+1)
+		if i == depth-1 {
+			next = "planner"
+		}
+		features[fmt.Sprintf("diamond.%d", i)] = newFeatureWithPrerequisites(fmt.Sprintf("diamond.%d", i), fmt.Sprintf("diamond.%d.left,diamond.%d.right", i, i))
+		features[fmt.Sprintf("diamond.%d.left", i)] = newFeatureWithPrerequisites(fmt.Sprintf("diamond.%d.left", i), next)
+		features[fmt.Sprintf("diamond.%d.right", i)] = newFeatureWithPrerequisites(fmt.Sprintf("diamond.%d.right", i), next)
+	}
+	lookups := map[string]int{}
+	mockUnleashClient := testfeaturetoggles.NewUnleashClientMock(t)
+	mockUnleashClient.GetFeatureFunc = func(name string) *unleashapi.Feature {
+		lookups[name]++
+		if f, found := features[name]; found {
+			return &f
+		}
+		return nil
+	}
+	evaluations := map[string]int{}
+	mockUnleashClient.IsEnabledFunc = func(feature string, options ...unleash.FeatureOption) (enabled bool) {
+		evaluations[feature]++
+		return feature != "planner"
+	}
+	ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+
+	t.Run("enabled prerequisites", func(t *testing.T) {
+		// when
+		f := ft.GetFeature(context.Background(), "deploy.pipelines", nil)
+		// then
+		assert.True(t, f.UserEnabled)
+		assert.Empty(t, f.BlockedBy)
+	})
+
+	t.Run("disabled prerequisite", func(t *testing.T) {
+		// when
+		f := ft.GetFeature(context.Background(), "planner.board", nil)
+		// then
+		assert.False(t, f.UserEnabled)
+		assert.Equal(t, []string{"planner"}, f.BlockedBy)
+	})
+
+	t.Run("disabled transitive prerequisite", func(t *testing.T) {
+		// given
+		evaluations = map[string]int{}
+		// when
+		f := ft.GetFeature(context.Background(), "planner.board.swimlanes", nil)
+		// then the direct prerequisite is reported
+		assert.False(t, f.UserEnabled)
+		assert.Equal(t, []string{"planner.board"}, f.BlockedBy)
+		// and only the usage of the requested feature is recorded by the unleash client
+		assert.Equal(t, map[string]int{"planner.board.swimlanes": 1}, evaluations)
+	})
+
+	t.Run("diamonds", func(t *testing.T) {
+		// given
+		lookups = map[string]int{}
+		// when
+		f := ft.GetFeature(context.Background(), "diamond.0", nil)
+		// then
+		assert.False(t, f.UserEnabled)
+		assert.Equal(t, []string{"diamond.0.left", "diamond.0.right"}, f.BlockedBy)
+		// each prerequisite is evaluated once, instead of once per path
+		for name, count := range lookups {
+			assert.Equal(t, 1, count, name)
+		}
+		assert.Len(t, lookups, 3*depth+1)
+	})
+
+	t.Run("unknown prerequisite", func(t *testing.T) {
+		// when
+		f := ft.GetFeature(context.Background(), "orphan", nil)
+		// then
+		assert.False(t, f.UserEnabled)
+		assert.Equal(t, []string{"unknown"}, f.BlockedBy)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		// when
+		f := ft.GetFeature(context.Background(), "a", nil)
+		// then
+		assert.False(t, f.UserEnabled)
+		assert.Equal(t, []string{"b"}, f.BlockedBy)
+	})
+
+	t.Run("disabled feature", func(t *testing.T) {
+		// when
+		f := ft.GetFeature(context.Background(), "planner", nil)
+		// then the prerequisites are only reported when they disabled the feature
+		assert.False(t, f.UserEnabled)
+		assert.Empty(t, f.BlockedBy)
+	})
+}
@@ -0,0 +1,27 @@
+package featuretoggles
+
+import "context"
+
+// Preview the user attributes to override when evaluating the features, so that admins can see
+// what a beta, experimental or internal user would see
+type Preview struct {
+	Level    *string
+	Email    *string
+	Internal *bool
+}
+
+type previewKey struct{}
+
+// ContextWithPreview returns a new context in which the features are evaluated with the given preview attributes
+func ContextWithPreview(ctx context.Context, preview Preview) context.Context {
+	return context.WithValue(ctx, previewKey{}, preview)
+}
+
+// ContextPreview returns the preview attributes in the given context, if any
+func ContextPreview(ctx context.Context) (Preview, bool) {
+	if ctx == nil {
+		return Preview{}, false
+	}
+	preview, ok := ctx.Value(previewKey{}).(Preview)
+	return preview, ok
+}
@@ -0,0 +1,58 @@
+package featuretoggles_test
+
+import (
+	"context"
+	"testing"
+
+	unleash "github.com/Unleash/unleash-client-go"
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	testfeaturetoggles "github.com/fabric8-services/fabric8-toggles-service/test/featuretoggles"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPreview(t *testing.T) {
+	// given
+	internalFeature := unleashapi.Feature{
+		Name:    "internal",
+		Enabled: true,
+		Strategies: []unleashapi.Strategy{
+			{
+				Name: featuretoggles.EnableByLevelStrategyName,
+				Parameters: map[string]interface{}{
+					featuretoggles.LevelParameter: featuretoggles.InternalLevel,
+				},
+			},
+		},
+	}
+	mockUnleashClient := testfeaturetoggles.NewUnleashClientMock(t)
+	mockUnleashClient.GetFeatureFunc = func(name string) *unleashapi.Feature {
+		if name == internalFeature.Name {
+			return &internalFeature
+		}
+		return nil
+	}
+	mockUnleashClient.IsEnabledFunc = func(feature string, options ...unleash.FeatureOption) (enabled bool) {
+		return false
+	}
+	ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+
+	t.Run("no preview", func(t *testing.T) {
+		// when
+		f := ft.GetFeature(context.Background(), internalFeature.Name, nil)
+		// then
+		assert.False(t, f.Preview)
+		assert.Equal(t, featuretoggles.UnknownLevel, f.EnablementLevel)
+	})
+
+	t.Run("preview as internal user", func(t *testing.T) {
+		// given
+		internal := true
+		ctx := featuretoggles.ContextWithPreview(context.Background(), featuretoggles.Preview{Internal: &internal})
+		// when
+		f := ft.GetFeature(ctx, internalFeature.Name, nil)
+		// then
+		assert.True(t, f.Preview)
+		assert.Equal(t, featuretoggles.InternalLevel, f.EnablementLevel)
+	})
+}
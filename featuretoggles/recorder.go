@@ -0,0 +1,59 @@
+package featuretoggles
+
+import (
+	"context"
+	"time"
+)
+
+// Recorder records the metrics of the toggles client. The client does not depend on any metrics library: the service
+// injects its own recorder with `WithRecorder`, while the other users of the package (e.g., the `evaluator`) record nothing.
+type Recorder interface {
+	// RecordUnleashRefresh records a successful refresh of the features by the Unleash client at the given time
+	RecordUnleashRefresh(t time.Time)
+	// SetUnleashReady records whether the Unleash client is ready
+	SetUnleashReady(ready bool)
+	// RecordUnleashError records an error reported by the Unleash client
+	RecordUnleashError()
+	// RecordUnleashWarning records a warning reported by the Unleash client
+	RecordUnleashWarning()
+	// RecordEvaluation records the evaluation of the feature with the given name for a user (but not for a preview)
+	RecordEvaluation(feature string, enabled bool)
+}
+
+// nopRecorder the default recorder, which records nothing
+type nopRecorder struct{}
+
+func (nopRecorder) RecordUnleashRefresh(time.Time) {}
+func (nopRecorder) SetUnleashReady(bool)           {}
+func (nopRecorder) RecordUnleashError()            {}
+func (nopRecorder) RecordUnleashWarning()          {}
+func (nopRecorder) RecordEvaluation(string, bool)  {}
+
+// WithRecorder configures the client with the recorder of its metrics
+func WithRecorder(recorder Recorder) ClientOption {
+	return func(c *ClientImpl) {
+		c.recorder = recorder
+	}
+}
+
+// Tracer starts the spans of the toggles client. As for the `Recorder`, the client does not depend on any tracing
+// library: the service injects its own tracer with `WithTracer`.
+type Tracer interface {
+	// StartSpan starts a new span with the given name and attributes, as a child of the span in the given context (if any).
+	// Returns the context of the new span and the function to call to end it.
+	StartSpan(ctx context.Context, name string, attributes map[string]string) (context.Context, func())
+}
+
+// nopTracer the default tracer, which starts no span
+type nopTracer struct{}
+
+func (nopTracer) StartSpan(ctx context.Context, _ string, _ map[string]string) (context.Context, func()) {
+	return ctx, func() {}
+}
+
+// WithTracer configures the client with the tracer of its operations
+func WithTracer(tracer Tracer) ClientOption {
+	return func(c *ClientImpl) {
+		c.tracer = tracer
+	}
+}
@@ -0,0 +1,212 @@
+package featuretoggles
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"sync"
+
+	"github.com/Unleash/unleash-client-go"
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	unleashcontext "github.com/Unleash/unleash-client-go/context"
+	"github.com/Unleash/unleash-client-go/strategy"
+	"github.com/fabric8-services/fabric8-auth/log"
+	errs "github.com/pkg/errors"
+)
+
+// strategies the custom strategies registered on the underlying unleash client, also used when evaluating features locally
+var strategies = []strategy.Strategy{EnableByLevelStrategy{}, EnableByEmailsStrategy{}}
+
+// localEvaluator the interface implemented by the unleash clients which can evaluate a feature with an explicit context,
+// since the context passed in an `unleash.FeatureOption` cannot be read outside of the unleash package
+type localEvaluator interface {
+	IsEnabledWithContext(name string, ctx unleashcontext.Context) bool
+}
+
+// Snapshot an immutable copy of the features of the Unleash server, which can be evaluated in-process using
+// the same strategies as the service. Snapshot implements the `UnleashClient` interface and is always ready.
+type Snapshot struct {
+	features []unleashapi.Feature
+	byName   map[string]unleashapi.Feature
+	version  string
+	// the problems with the strategies of the features, validated once
+	validation sync.Once
+	problems   []Problem
+}
+
+// verify that `Snapshot` is a valid impl of the `UnleashClient` interface
+var _ UnleashClient = &Snapshot{}
+
+// NewSnapshot returns a new snapshot of the given features
+func NewSnapshot(features []unleashapi.Feature) *Snapshot {
+	s := Snapshot{
+		features: make([]unleashapi.Feature, len(features)),
+		byName:   make(map[string]unleashapi.Feature, len(features)),
+	}
+	copy(s.features, features)
+	for _, f := range s.features {
+		s.byName[f.Name] = f
+	}
+	s.version = snapshotVersion(s.features)
+	return &s
+}
+
+// snapshotVersion returns a hash of the given features, regardless of their order. Since it only depends on the content of
+// the features, all the replicas of the service which loaded the same features compute the same version.
+func snapshotVersion(features []unleashapi.Feature) string {
+	sorted := make([]unleashapi.Feature, len(features))
+	copy(sorted, features)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	h := sha256.New()
+	if err := json.NewEncoder(h).Encode(sorted); err != nil {
+		// cannot happen with the features decoded from the Unleash server, but better safe than sorry
+		log.Error(nil, map[string]interface{}{"err": err}, "unable to compute the version of the features snapshot")
+		return ""
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+type snapshotKey struct{}
+
+// ContextWithSnapshot returns a new context in which the features are evaluated against the given snapshot
+func ContextWithSnapshot(ctx context.Context, snapshot *Snapshot) context.Context {
+	return context.WithValue(ctx, snapshotKey{}, snapshot)
+}
+
+// ContextSnapshot returns the snapshot in the given context, if any
+func ContextSnapshot(ctx context.Context) (*Snapshot, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	snapshot, ok := ctx.Value(snapshotKey{}).(*Snapshot)
+	return snapshot, ok && snapshot != nil
+}
+
+// ReadSnapshot returns a new snapshot from the given content, in the format returned by the
+// `/api/client/features` endpoint of the Unleash server (or stored in the unleash client's backup file)
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	var response unleashapi.FeatureResponse
+	if err := json.NewDecoder(r).Decode(&response); err != nil {
+		return nil, errs.Wrap(err, "unable to read the features snapshot")
+	}
+	return NewSnapshot(response.Features), nil
+}
+
+// Version returns the version of this snapshot, i.e., a hash of its features
+func (s *Snapshot) Version() string {
+	return s.version
+}
+
+// Problems returns the problems with the strategies of the features in this snapshot (see `ValidateFeatures`).
+// The features are only validated on the first call.
+func (s *Snapshot) Problems() []Problem {
+	s.validation.Do(func() {
+		s.problems = ValidateFeatures(s.features)
+	})
+	return append([]Problem{}, s.problems...)
+}
+
+// Features returns a copy of the features in this snapshot
+func (s *Snapshot) Features() []unleashapi.Feature {
+	result := make([]unleashapi.Feature, len(s.features))
+	copy(result, s.features)
+	return result
+}
+
+// Ready returns a channel which is already closed, since a snapshot is always ready
+func (s *Snapshot) Ready() <-chan bool {
+	ready := make(chan bool)
+	close(ready)
+	return ready
+}
+
+// GetFeature returns the feature with the given name, or `nil` if it does not exist in the snapshot
+func (s *Snapshot) GetFeature(name string) *unleashapi.Feature {
+	if f, found := s.byName[name]; found {
+		return &f
+	}
+	return nil
+}
+
+// IsEnabled returns `true` if the feature with the given name is enabled for an empty context.
+// Note: the context in the given options is ignored, use `IsEnabledWithContext` instead.
+func (s *Snapshot) IsEnabled(name string, options ...unleash.FeatureOption) bool {
+	return s.IsEnabledWithContext(name, unleashcontext.Context{})
+}
+
+// IsEnabledWithContext returns `true` if the feature with the given name is enabled for the given context
+func (s *Snapshot) IsEnabledWithContext(name string, ctx unleashcontext.Context) bool {
+	f, found := s.byName[name]
+	if !found {
+		return false
+	}
+	return EvaluateStrategies(f, &ctx)
+}
+
+// GetFeaturesByPattern returns the features whose name matches the given regular expression
+func (s *Snapshot) GetFeaturesByPattern(pattern string) []unleashapi.Feature {
+	result := make([]unleashapi.Feature, 0)
+	r, err := regexp.Compile(pattern)
+	if err != nil {
+		log.Error(nil, map[string]interface{}{"err": err, "pattern": pattern}, "invalid feature name pattern")
+		return result
+	}
+	for _, f := range s.features {
+		if r.MatchString(f.Name) {
+			result = append(result, f)
+		}
+	}
+	return result
+}
+
+// GetFeaturesByStrategy returns the features which have a strategy with the given name
+func (s *Snapshot) GetFeaturesByStrategy(strategyName string) []unleashapi.Feature {
+	result := make([]unleashapi.Feature, 0)
+	for _, f := range s.features {
+		for _, st := range f.Strategies {
+			if st.Name == strategyName {
+				result = append(result, f)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// Close does nothing, since a snapshot holds no resource
+func (s *Snapshot) Close() error {
+	return nil
+}
+
+// EvaluateStrategies returns `true` if the given feature is enabled for the given context, using the same semantics
+// as the Unleash client: the feature must be enabled and at least one of its strategies must match the context.
+// The strategies are the built-in strategies of the Unleash client and the custom strategies of this service,
+// while the unknown strategies are never enabled.
+func EvaluateStrategies(feature unleashapi.Feature, ctx *unleashcontext.Context) bool {
+	if !feature.Enabled {
+		return false
+	}
+	for _, st := range feature.Strategies {
+		s, found := lookupEvaluationStrategy(st.Name)
+		if found && s.IsEnabled(st.Parameters, ctx) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupEvaluationStrategy returns the built-in or custom strategy with the given name, if any
+func lookupEvaluationStrategy(name string) (strategy.Strategy, bool) {
+	for _, s := range builtinStrategies {
+		if s.Name() == name {
+			return s, true
+		}
+	}
+	return lookupStrategy(name)
+}
@@ -0,0 +1,236 @@
+package featuretoggles_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	unleashcontext "github.com/Unleash/unleash-client-go/context"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	testfeaturetoggles "github.com/fabric8-services/fabric8-toggles-service/test/featuretoggles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const snapshotContent = `{
+  "version": 1,
+  "features": [
+    {
+      "name": "foo",
+      "description": "Foo",
+      "enabled": true,
+      "strategies": [{"name": "enableByLevel", "parameters": {"level": "beta"}}]
+    },
+    {
+      "name": "foo.bar",
+      "description": "Foo Bar",
+      "enabled": true,
+      "strategies": [{"name": "enableByEmails", "parameters": {"emails": "user@foo.com, other@foo.com"}}]
+    },
+    {
+      "name": "foo.baz",
+      "description": "Foo Baz",
+      "enabled": true,
+      "strategies": [{"name": "default"}]
+    },
+    {
+      "name": "disabled",
+      "description": "Disabled",
+      "enabled": false,
+      "strategies": [{"name": "default"}]
+    },
+    {
+      "name": "random",
+      "description": "Built-in strategy",
+      "enabled": true,
+      "strategies": [{"name": "gradualRolloutRandom", "parameters": {"percentage": "100"}}]
+    },
+    {
+      "name": "unsupported",
+      "description": "Unsupported strategy",
+      "enabled": true,
+      "strategies": [{"name": "unknownStrategy"}]
+    }
+  ]
+}`
+
+func TestSnapshot(t *testing.T) {
+	// given
+	s, err := featuretoggles.ReadSnapshot(strings.NewReader(snapshotContent))
+	require.NoError(t, err)
+
+	t.Run("get feature", func(t *testing.T) {
+		// when
+		f := s.GetFeature("foo.bar")
+		// then
+		require.NotNil(t, f)
+		assert.Equal(t, "Foo Bar", f.Description)
+		assert.Nil(t, s.GetFeature("unknown"))
+	})
+
+	t.Run("get features by pattern", func(t *testing.T) {
+		// when
+		features := s.GetFeaturesByPattern(`^foo$|^foo\.(.*)`)
+		// then
+		assert.Equal(t, []string{"foo", "foo.bar", "foo.baz"}, names(features))
+	})
+
+	t.Run("get features by strategy", func(t *testing.T) {
+		// when
+		features := s.GetFeaturesByStrategy(featuretoggles.EnableByLevelStrategyName)
+		// then
+		assert.Equal(t, []string{"foo"}, names(features))
+	})
+
+	t.Run("problems", func(t *testing.T) {
+		// when
+		problems := s.Problems()
+		// then the built-in strategies are not reported
+		assert.Equal(t, []featuretoggles.Problem{
+			{Feature: "unsupported", Strategy: "unknownStrategy", Message: "unknown strategy"},
+		}, problems)
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		select {
+		case <-s.Ready():
+		default:
+			t.Fatal("expected snapshot to be ready")
+		}
+	})
+
+	t.Run("version", func(t *testing.T) {
+		// given
+		features := s.Features()
+		reversed := make([]unleashapi.Feature, len(features))
+		for i, f := range features {
+			reversed[len(features)-1-i] = f
+		}
+		// when/then
+		require.NotEmpty(t, s.Version())
+		assert.Equal(t, s.Version(), featuretoggles.NewSnapshot(reversed).Version())
+		features[0].Description = "changed"
+		assert.NotEqual(t, s.Version(), featuretoggles.NewSnapshot(features).Version())
+	})
+}
+
+func TestWithSnapshot(t *testing.T) {
+	// given
+	initialFeatures := []unleashapi.Feature{
+		{
+			Name:       "foo",
+			Enabled:    true,
+			Strategies: []unleashapi.Strategy{{Name: featuretoggles.DefaultStrategyName}},
+		},
+		{
+			Name:       "foo.bar",
+			Enabled:    true,
+			Strategies: []unleashapi.Strategy{{Name: featuretoggles.DefaultStrategyName}},
+		},
+	}
+	refreshedFeatures := []unleashapi.Feature{
+		{
+			Name:       "foo",
+			Enabled:    false,
+			Strategies: []unleashapi.Strategy{{Name: featuretoggles.DefaultStrategyName}},
+		},
+	}
+	features := initialFeatures
+	mockUnleashClient := testfeaturetoggles.NewUnleashClientMock(t)
+	mockUnleashClient.GetFeaturesByPatternFunc = func(pattern string) []unleashapi.Feature {
+		return features
+	}
+
+	t.Run("features refreshed during the request", func(t *testing.T) {
+		// given
+		features = initialFeatures
+		ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+		ctx, version := ft.WithSnapshot(context.Background())
+		require.NotEmpty(t, version)
+		features = refreshedFeatures
+		// when
+		result := ft.GetFeaturesByName(ctx, []string{"foo", "foo.bar"}, nil)
+		// then
+		require.Len(t, result, 2)
+		for _, f := range result {
+			assert.True(t, f.UserEnabled)
+			assert.Equal(t, version, f.Version)
+		}
+	})
+
+	t.Run("new version after a refresh", func(t *testing.T) {
+		// given
+		features = initialFeatures
+		ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+		_, version := ft.WithSnapshot(context.Background())
+		features = refreshedFeatures
+		// when
+		_, refreshedVersion := ft.WithSnapshot(context.Background())
+		// then
+		assert.NotEqual(t, version, refreshedVersion)
+	})
+
+	t.Run("existing snapshot", func(t *testing.T) {
+		// given
+		features = initialFeatures
+		ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+		ctx, version := ft.WithSnapshot(context.Background())
+		features = refreshedFeatures
+		// when
+		ctx2, version2 := ft.WithSnapshot(ctx)
+		// then
+		assert.Equal(t, ctx, ctx2)
+		assert.Equal(t, version, version2)
+	})
+
+	t.Run("client not ready", func(t *testing.T) {
+		// given
+		ft := featuretoggles.NewClientWithState(mockUnleashClient, false)
+		// when
+		ctx, version := ft.WithSnapshot(context.Background())
+		// then
+		assert.Empty(t, version)
+		_, found := featuretoggles.ContextSnapshot(ctx)
+		assert.False(t, found)
+	})
+}
+
+func TestEvaluateStrategies(t *testing.T) {
+	// given
+	s, err := featuretoggles.ReadSnapshot(strings.NewReader(snapshotContent))
+	require.NoError(t, err)
+	userCtx := func(level, email string) unleashcontext.Context {
+		return unleashcontext.Context{
+			Properties: map[string]string{
+				featuretoggles.LevelParameter:  level,
+				featuretoggles.EmailsParameter: email,
+			},
+		}
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		assert.True(t, s.IsEnabledWithContext("foo", userCtx(featuretoggles.BetaLevel, "")))
+		assert.True(t, s.IsEnabledWithContext("foo", userCtx(featuretoggles.ExperimentalLevel, "")))
+		assert.True(t, s.IsEnabledWithContext("foo.bar", userCtx(featuretoggles.ReleasedLevel, "other@foo.com")))
+		assert.True(t, s.IsEnabledWithContext("foo.baz", userCtx(featuretoggles.ReleasedLevel, "")))
+		assert.True(t, s.IsEnabledWithContext("random", userCtx("", "")))
+	})
+
+	t.Run("not enabled", func(t *testing.T) {
+		assert.False(t, s.IsEnabledWithContext("foo", userCtx(featuretoggles.ReleasedLevel, "")))
+		assert.False(t, s.IsEnabledWithContext("foo", userCtx("", "")))
+		assert.False(t, s.IsEnabledWithContext("foo.bar", userCtx(featuretoggles.ReleasedLevel, "unknown@foo.com")))
+		assert.False(t, s.IsEnabledWithContext("disabled", userCtx(featuretoggles.InternalLevel, "")))
+		assert.False(t, s.IsEnabledWithContext("unsupported", userCtx(featuretoggles.InternalLevel, "")))
+		assert.False(t, s.IsEnabledWithContext("unknown", userCtx(featuretoggles.InternalLevel, "")))
+	})
+}
+
+func names(features []unleashapi.Feature) []string {
+	result := make([]string, len(features))
+	for i, f := range features {
+		result[i] = f.Name
+	}
+	return result
+}
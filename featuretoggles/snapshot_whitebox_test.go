@@ -0,0 +1,86 @@
+package featuretoggles
+
+import (
+	"context"
+	"testing"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingClient an unleash client which counts the calls to `GetFeaturesByPattern`, and calls `onRead` (if set)
+// once the features are read
+type countingClient struct {
+	*Snapshot
+	calls  int
+	onRead func()
+}
+
+func (c *countingClient) GetFeaturesByPattern(pattern string) []unleashapi.Feature {
+	c.calls++
+	features := c.Snapshot.GetFeaturesByPattern(pattern)
+	if c.onRead != nil {
+		c.onRead()
+	}
+	return features
+}
+
+func TestCurrentSnapshot(t *testing.T) {
+	// given
+	unleashClient := &countingClient{
+		Snapshot: NewSnapshot([]unleashapi.Feature{{Name: "foo", Enabled: true, Strategies: []unleashapi.Strategy{{Name: DefaultStrategyName}}}}),
+	}
+	c := newClient(unleashClient, &UnleashClientListener{ready: true})
+	c.transport = &refreshTransport{onUpdate: c.invalidateSnapshot}
+
+	t.Run("shared until the next refresh", func(t *testing.T) {
+		// when
+		ctx1, version1 := c.WithSnapshot(context.Background())
+		ctx2, version2 := c.WithSnapshot(context.Background())
+		// then
+		assert.Equal(t, 1, unleashClient.calls)
+		assert.Equal(t, version1, version2)
+		s1, _ := ContextSnapshot(ctx1)
+		s2, _ := ContextSnapshot(ctx2)
+		assert.True(t, s1 == s2)
+	})
+
+	t.Run("rebuilt after a refresh", func(t *testing.T) {
+		// given
+		ctx1, _ := c.WithSnapshot(context.Background())
+		calls := unleashClient.calls
+		// when
+		c.transport.onUpdate()
+		ctx2, _ := c.WithSnapshot(context.Background())
+		// then
+		assert.Equal(t, calls+1, unleashClient.calls)
+		s1, _ := ContextSnapshot(ctx1)
+		s2, _ := ContextSnapshot(ctx2)
+		assert.False(t, s1 == s2)
+	})
+
+	t.Run("not shared if refreshed while built", func(t *testing.T) {
+		// given a refresh which completes after a request read the previous features, but before it stored its snapshot
+		c.invalidateSnapshot()
+		previous := unleashClient.Snapshot
+		refreshed := NewSnapshot([]unleashapi.Feature{{Name: "foo", Enabled: false, Strategies: []unleashapi.Strategy{{Name: DefaultStrategyName}}}})
+		unleashClient.onRead = func() {
+			unleashClient.onRead = nil
+			unleashClient.Snapshot = refreshed
+			c.transport.onUpdate()
+		}
+		defer func() {
+			unleashClient.Snapshot = previous
+		}()
+		// when
+		_, staleVersion := c.WithSnapshot(context.Background())
+		_, version := c.WithSnapshot(context.Background())
+		// then the stale snapshot is only used by the first request
+		assert.Equal(t, previous.Version(), staleVersion)
+		assert.Equal(t, refreshed.Version(), version)
+		s := c.loadSnapshot()
+		require.NotNil(t, s)
+		assert.Equal(t, refreshed.Version(), s.Version())
+	})
+}
@@ -3,8 +3,11 @@ package featuretoggles
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/Unleash/unleash-client-go"
@@ -12,6 +15,8 @@ import (
 	unleashcontext "github.com/Unleash/unleash-client-go/context"
 	"github.com/fabric8-services/fabric8-auth/log"
 	authclient "github.com/fabric8-services/fabric8-toggles-service/auth/client"
+	"github.com/fabric8-services/fabric8-toggles-service/errors"
+	errs "github.com/pkg/errors"
 )
 
 // UnleashClient the interface to the unleash client
@@ -29,15 +34,78 @@ type Client interface {
 	GetFeature(ctx context.Context, name string, user *authclient.User) UserFeature
 	GetFeaturesByName(ctx context.Context, names []string, user *authclient.User) []UserFeature
 	GetFeaturesByPattern(ctx context.Context, pattern string, user *authclient.User) []UserFeature
+	GetFeaturesByGroups(ctx context.Context, groups []string, glob bool, user *authclient.User) []UserFeature
 	GetFeaturesByStrategy(ctx context.Context, strategy string, user *authclient.User) []UserFeature
+	GetFeaturesByTags(ctx context.Context, query TagQuery, user *authclient.User) []UserFeature
+	GetFeaturesByLevel(ctx context.Context, levels []string, user *authclient.User) []UserFeature
+	WithSnapshot(ctx context.Context) (context.Context, string)
 	// IsFeatureEnabled(ctx context.Context, feature UserFeature, user *authclient.User) (bool, string)
+	Enroll(ctx context.Context, name string, user *authclient.User, enrollment Enrollment) error
+	Unenroll(ctx context.Context, name string, user *authclient.User) error
+	State() State
+	Problems() []Problem
+	Features() []unleashapi.Feature
 	Close() error
 }
 
+// State the state of the toggle client
+type State struct {
+	// Ready `true` if the client received the features from the Unleash server
+	Ready bool
+	// LastRefresh the time when the client last refreshed the features (zero if unknown)
+	LastRefresh time.Time
+	// FeatureCount the number of features loaded in the client
+	FeatureCount int
+}
+
 // ClientImpl the toggle client default impl
 type ClientImpl struct {
 	UnleashClient  UnleashClient
 	clientListener *UnleashClientListener
+	enrollments    EnrollmentStore
+	transport      *refreshTransport
+	internalUsers  InternalUserConfiguration
+	metadata       MetadataProvider
+	recorder       Recorder
+	tracer         Tracer
+	// mu protects the snapshot of the features currently loaded in the unleash client, and the generation of these
+	// features, incremented on each refresh
+	mu         sync.RWMutex
+	snapshot   *Snapshot
+	generation uint64
+}
+
+// ClientOption a function to customize the ClientImpl during its initialization
+type ClientOption func(*ClientImpl)
+
+// WithEnrollmentStore configures the client with a custom store for the users' enrollments
+func WithEnrollmentStore(store EnrollmentStore) ClientOption {
+	return func(c *ClientImpl) {
+		c.enrollments = store
+	}
+}
+
+// DefaultInternalEmailDomains the domains of the email addresses of the internal users, unless configured otherwise
+var DefaultInternalEmailDomains = []string{"redhat.com"}
+
+// InternalUserConfiguration the configuration of the internal users
+type InternalUserConfiguration interface {
+	GetInternalEmailDomains() []string
+}
+
+// WithInternalUserConfiguration configures the client with the domains of the email addresses of the internal users.
+// The domains are read from the given configuration on each evaluation, so they can be changed at runtime.
+func WithInternalUserConfiguration(config InternalUserConfiguration) ClientOption {
+	return func(c *ClientImpl) {
+		c.internalUsers = config
+	}
+}
+
+// WithMetadataProvider configures the client with the provider of the metadata of the features (e.g., their tags)
+func WithMetadataProvider(provider MetadataProvider) ClientOption {
+	return func(c *ClientImpl) {
+		c.metadata = provider
+	}
 }
 
 // verify that `ClientImpl`` is a valid impl of the `Client`` interface
@@ -47,35 +115,79 @@ var _ Client = &ClientImpl{}
 type ToggleServiceConfiguration interface {
 	// GetToggleServiceAppName() string
 	GetTogglesURL() string
+	// GetTogglesAPIToken returns the API token to send in the `Authorization` header (none if empty)
+	GetTogglesAPIToken() string
+	// GetTogglesCustomHeaders returns the additional headers to send in the requests
+	GetTogglesCustomHeaders() map[string]string
+	// GetTogglesCABundleFile returns the path to the bundle of additional trusted CAs (none if empty)
+	GetTogglesCABundleFile() string
+	// GetTogglesProxyURL returns the URL of the HTTP proxy (if empty, the proxy set in the environment is used)
+	GetTogglesProxyURL() string
+	// GetTogglesTimeout returns the timeout of the requests
+	GetTogglesTimeout() time.Duration
+	// GetTogglesInstanceID returns the ID of this instance (if empty, the `HOSTNAME` environment variable is used)
+	GetTogglesInstanceID() string
 }
 
 // NewDefaultClient returns a new client to the toggle feature service including the default underlying unleash client initialized
-func NewDefaultClient(serviceName string, config ToggleServiceConfiguration) (Client, error) {
+func NewDefaultClient(serviceName string, config ToggleServiceConfiguration, options ...ClientOption) (Client, error) {
 	l := UnleashClientListener{ready: false}
+	t, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	// the options are applied first, since the transport and the listener use the recorder of the client
+	c := newClient(nil, &l, options...)
+	transport := refreshTransport{transport: t, recorder: c.recorder, onUpdate: c.invalidateSnapshot}
+	instanceID := config.GetTogglesInstanceID()
+	if instanceID == "" {
+		instanceID = os.Getenv("HOSTNAME")
+	}
 	unleashclient, err := unleash.NewClient(
 		unleash.WithAppName(serviceName),
-		unleash.WithInstanceId(os.Getenv("HOSTNAME")),
+		unleash.WithInstanceId(instanceID),
 		unleash.WithUrl(config.GetTogglesURL()),
-		unleash.WithStrategies(EnableByLevelStrategy{}, EnableByEmailsStrategy{}),
+		unleash.WithStrategies(strategies...),
 		unleash.WithMetricsInterval(1*time.Minute),
 		unleash.WithRefreshInterval(10*time.Second),
 		unleash.WithListener(&l),
+		unleash.WithHttpClient(&http.Client{
+			Transport: &transport,
+			Timeout:   config.GetTogglesTimeout(),
+		}),
 	)
 	if err != nil {
 		return nil, err
 	}
-	return &ClientImpl{
-		UnleashClient:  unleashclient,
-		clientListener: &l,
-	}, nil
+	c.UnleashClient = unleashclient
+	c.transport = &transport
+	return c, nil
 }
 
 // NewClientWithState returns a new client to the toggle feature service with a pre-initialized unleash client listener
-func NewClientWithState(unleashclient UnleashClient, ready bool) Client {
-	return &ClientImpl{
+func NewClientWithState(unleashclient UnleashClient, ready bool, options ...ClientOption) Client {
+	return newClient(unleashclient, &UnleashClientListener{ready: ready}, options...)
+}
+
+func newClient(unleashclient UnleashClient, l *UnleashClientListener, options ...ClientOption) *ClientImpl {
+	c := ClientImpl{
 		UnleashClient:  unleashclient,
-		clientListener: &UnleashClientListener{ready: ready},
+		clientListener: l,
+	}
+	for _, opt := range options {
+		opt(&c)
 	}
+	if c.enrollments == nil {
+		c.enrollments = NewInMemoryEnrollmentStore()
+	}
+	if c.recorder == nil {
+		c.recorder = nopRecorder{}
+	}
+	if c.tracer == nil {
+		c.tracer = nopTracer{}
+	}
+	l.recorder = c.recorder
+	return &c
 }
 
 // Close closes the underlying Unleash client
@@ -83,13 +195,129 @@ func (c *ClientImpl) Close() error {
 	return c.UnleashClient.Close()
 }
 
+// State returns the state of the client. The last refresh is only known for the clients initialized with `NewDefaultClient`.
+func (c *ClientImpl) State() State {
+	state := State{
+		Ready: c.clientListener.ready,
+	}
+	if c.transport != nil {
+		state.LastRefresh = c.transport.LastRefresh()
+	}
+	if state.Ready {
+		state.FeatureCount = len(c.UnleashClient.GetFeaturesByPattern(".*"))
+	}
+	return state
+}
+
+// Problems returns the problems with the strategies of the features currently loaded in the client. The features are
+// validated once per refresh, along with the snapshot of the features.
+func (c *ClientImpl) Problems() []Problem {
+	if !c.clientListener.ready {
+		return []Problem{}
+	}
+	return c.currentSnapshot().Problems()
+}
+
+// Features returns all the features currently loaded in the client, sorted by name (e.g., to export them)
+func (c *ClientImpl) Features() []unleashapi.Feature {
+	if !c.clientListener.ready {
+		return []unleashapi.Feature{}
+	}
+	features := c.UnleashClient.GetFeaturesByPattern(".*")
+	sort.Slice(features, func(i, j int) bool {
+		return features[i].Name < features[j].Name
+	})
+	return features
+}
+
+// WithSnapshot returns a new context in which the features are evaluated against an immutable snapshot of the features
+// currently loaded in the client, along with the version of this snapshot. This guarantees that all the features returned
+// in a single response are consistent, even if the client refreshes the features in the meantime.
+// The context is returned as-is if it already has a snapshot, or with an empty version if the client is not ready.
+func (c *ClientImpl) WithSnapshot(ctx context.Context) (context.Context, string) {
+	if s, found := ContextSnapshot(ctx); found {
+		return ctx, s.Version()
+	}
+	if !c.clientListener.ready {
+		return ctx, ""
+	}
+	s := c.currentSnapshot()
+	return ContextWithSnapshot(ctx, s), s.Version()
+}
+
+// currentSnapshot returns the snapshot of the features currently loaded in the client. The snapshot is built once after
+// each refresh of the features and shared by all the requests until the next refresh. The clients which are not
+// initialized with `NewDefaultClient` are not notified of the refreshes, so their snapshot is built on each call.
+func (c *ClientImpl) currentSnapshot() *Snapshot {
+	if s, ok := c.UnleashClient.(*Snapshot); ok {
+		return s
+	}
+	// the generation is read before the features, so that a snapshot built from the features of a previous refresh is not shared
+	c.mu.RLock()
+	s, generation := c.snapshot, c.generation
+	c.mu.RUnlock()
+	if s != nil {
+		return s
+	}
+	s = NewSnapshot(c.UnleashClient.GetFeaturesByPattern(".*"))
+	if c.transport != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.snapshot != nil {
+			// built by a concurrent request in the meantime
+			return c.snapshot
+		}
+		if c.generation != generation {
+			// the features were refreshed while the snapshot was built: it is only used by this request, and
+			// the next request builds a new one from the refreshed features
+			return s
+		}
+		c.snapshot = s
+		// the problems are only reported once per refresh, instead of on each evaluation of the features
+		for _, p := range s.Problems() {
+			log.Warn(nil, map[string]interface{}{
+				"feature_name":  p.Feature,
+				"strategy_name": p.Strategy,
+				"problem":       p.Message,
+			}, "misconfigured feature strategy")
+		}
+	}
+	return s
+}
+
+// loadSnapshot returns the snapshot of the features currently loaded in the client, or `nil` if it was not built since the
+// last refresh
+func (c *ClientImpl) loadSnapshot() *Snapshot {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.snapshot
+}
+
+// invalidateSnapshot discards the snapshot of the features when the unleash client refreshes its features
+func (c *ClientImpl) invalidateSnapshot() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.snapshot = nil
+	c.generation++
+}
+
+// repository returns the snapshot of the given context if any, or the underlying unleash client otherwise
+func (c *ClientImpl) repository(ctx context.Context) UnleashClient {
+	if s, found := ContextSnapshot(ctx); found {
+		return s
+	}
+	return c.UnleashClient
+}
+
 // GetFeature returns the feature given its name
 func (c *ClientImpl) GetFeature(ctx context.Context, name string, user *authclient.User) UserFeature {
+	ctx, end := c.tracer.StartSpan(ctx, "featuretoggles.GetFeature", map[string]string{"feature.name": name})
+	defer end()
 	if !c.clientListener.ready {
 		log.Error(ctx, map[string]interface{}{"error": "client is not ready"}, "unable to list features by name")
 		return UserFeature{}
 	}
-	f := c.UnleashClient.GetFeature(name)
+	f := c.repository(ctx).GetFeature(name)
 	if f == nil {
 		return UserFeature{}
 	}
@@ -97,18 +325,89 @@ func (c *ClientImpl) GetFeature(ctx context.Context, name string, user *authclie
 }
 
 func (c *ClientImpl) toUserFeature(ctx context.Context, f unleashapi.Feature, user *authclient.User) UserFeature {
-	userEnabled, enablementLevel := c.isFeatureEnabled(ctx, f, user)
+	userEnabled, enablementLevel, override := c.isFeatureEnabled(ctx, f, user)
+	var blockedBy []string
+	if userEnabled {
+		// a feature is never enabled if one of its prerequisites is not, regardless of the user's enrollment
+		userCtx, _ := c.userContext(ctx, user)
+		if blockedBy = c.blockingPrerequisites(ctx, f, userCtx, user, []string{f.Name}, map[string]bool{}); len(blockedBy) > 0 {
+			userEnabled = false
+		}
+	}
+	_, preview := ContextPreview(ctx)
+	if !preview {
+		// all the evaluations for the users are recorded, whatever the endpoint, to report the features which are not used anymore
+		c.recorder.RecordEvaluation(f.Name, userEnabled)
+	}
+	var version string
+	if s, found := ContextSnapshot(ctx); found {
+		version = s.Version()
+	}
 	return UserFeature{
 		Name:            f.Name,
 		Description:     f.Description,
 		Enabled:         f.Enabled,
 		UserEnabled:     userEnabled,
 		EnablementLevel: enablementLevel,
+		Override:        override,
+		Preview:         preview,
+		Tags:            c.tags(f.Name),
+		BlockedBy:       blockedBy,
+		Version:         version,
+	}
+}
+
+// blockingPrerequisites returns the prerequisites of the given feature which are not enabled for the user, either because
+// they are unknown or disabled for the user, or because one of their own prerequisites is not enabled. The `path` is the
+// chain of features being evaluated, so that a cycle of prerequisites blocks the features instead of looping forever.
+// The prerequisites are evaluated in-process, with the same strategies as the unleash client but without recording their
+// usage (the user did not ask for them), and memoized in `enabled` so that a prerequisite shared by several features
+// of the chain (e.g., in a diamond) is only evaluated once per feature.
+func (c *ClientImpl) blockingPrerequisites(ctx context.Context, f unleashapi.Feature, userCtx unleashcontext.Context, user *authclient.User, path []string, enabled map[string]bool) []string {
+	var blocking []string
+	for _, name := range Prerequisites(f) {
+		if contains(path, name) {
+			log.Warn(ctx, map[string]interface{}{"feature_name": f.Name, "prerequisite": name}, "cycle in the feature prerequisites: failing closed")
+			blocking = append(blocking, name)
+			continue
+		}
+		if _, found := enabled[name]; !found {
+			enabled[name] = c.isPrerequisiteEnabled(ctx, f, name, userCtx, user, append(path[:len(path):len(path)], name), enabled)
+		}
+		if !enabled[name] {
+			blocking = append(blocking, name)
+		}
+	}
+	return blocking
+}
+
+// isPrerequisiteEnabled returns `true` if the prerequisite with the given name of the given feature is known and enabled
+// for the user, along with its own prerequisites (see `blockingPrerequisites`)
+func (c *ClientImpl) isPrerequisiteEnabled(ctx context.Context, f unleashapi.Feature, name string, userCtx unleashcontext.Context, user *authclient.User, path []string, enabled map[string]bool) bool {
+	prerequisite := c.repository(ctx).GetFeature(name)
+	if prerequisite == nil {
+		log.Warn(ctx, map[string]interface{}{"feature_name": f.Name, "prerequisite": name}, "unknown feature prerequisite: failing closed")
+		return false
+	}
+	prerequisiteEnabled, _ := c.applyEnrollment(ctx, *prerequisite, user, EvaluateStrategies(*prerequisite, &userCtx))
+	return prerequisiteEnabled && len(c.blockingPrerequisites(ctx, *prerequisite, userCtx, user, path, enabled)) == 0
+}
+
+// tags returns the tags of the feature with the given name, if any
+func (c *ClientImpl) tags(name string) []string {
+	if c.metadata == nil {
+		return nil
+	}
+	if m, found := c.metadata.GetMetadata(name); found {
+		return m.Tags
 	}
+	return nil
 }
 
 // GetFeaturesByName returns the features from their names
 func (c *ClientImpl) GetFeaturesByName(ctx context.Context, names []string, user *authclient.User) []UserFeature {
+	ctx, end := c.tracer.StartSpan(ctx, "featuretoggles.GetFeaturesByName", map[string]string{"feature.names": strings.Join(names, ",")})
+	defer end()
 	result := make([]UserFeature, 0)
 	if !c.clientListener.ready {
 		log.Error(ctx, map[string]interface{}{"error": "client is not ready"}, "unable to list features by name")
@@ -116,74 +415,251 @@ func (c *ClientImpl) GetFeaturesByName(ctx context.Context, names []string, user
 	}
 	for _, name := range names {
 		f := c.GetFeature(ctx, name, user)
-		if f != ZeroUserFeature {
+		if !f.IsZero() {
 			result = append(result, f)
 		}
 	}
 	return result
 }
 
-// GetFeaturesByPattern returns the features whose ID matches the given pattern
+// GetFeaturesByPattern returns the features of the given group, i.e., the feature named after the group and its sub-features.
+// The group is escaped, so that it is not interpreted as a regular expression.
 func (c *ClientImpl) GetFeaturesByPattern(ctx context.Context, pattern string, user *authclient.User) []UserFeature {
+	return c.GetFeaturesByGroups(ctx, []string{pattern}, false, user)
+}
+
+// GetFeaturesByGroups returns the features of the given groups, with `*` wildcards in the groups if `glob` is `true`
+// (see `GroupsPattern`)
+func (c *ClientImpl) GetFeaturesByGroups(ctx context.Context, groups []string, glob bool, user *authclient.User) []UserFeature {
+	ctx, end := c.tracer.StartSpan(ctx, "featuretoggles.GetFeaturesByGroups", map[string]string{"feature.groups": strings.Join(groups, ",")})
+	defer end()
 	result := make([]UserFeature, 0)
 	if !c.clientListener.ready {
-		log.Error(ctx, map[string]interface{}{"error": "client is not ready"}, "unable to list features by pattern")
+		log.Error(ctx, map[string]interface{}{"error": "client is not ready"}, "unable to list features by group")
 		return result
 	}
-	feats := c.UnleashClient.GetFeaturesByPattern(fmt.Sprintf("^%[1]s$|^%[1]s\\.(.*)", pattern))
-	for _, f := range feats {
+	for _, f := range c.repository(ctx).GetFeaturesByPattern(GroupsPattern(groups, glob)) {
 		result = append(result, c.toUserFeature(ctx, f, user))
 	}
 	return result
 }
 
+// GetFeaturesByTags returns the features whose tags match the given query
+func (c *ClientImpl) GetFeaturesByTags(ctx context.Context, query TagQuery, user *authclient.User) []UserFeature {
+	ctx, end := c.tracer.StartSpan(ctx, "featuretoggles.GetFeaturesByTags", map[string]string{"feature.tags": fmt.Sprintf("%v", query)})
+	defer end()
+	result := make([]UserFeature, 0)
+	if !c.clientListener.ready {
+		log.Error(ctx, map[string]interface{}{"error": "client is not ready"}, "unable to list features by tags")
+		return result
+	}
+	for _, f := range c.repository(ctx).GetFeaturesByPattern(".*") {
+		// only evaluate the matching features
+		if query.Matches(c.tags(f.Name)) {
+			result = append(result, c.toUserFeature(ctx, f, user))
+		}
+	}
+	return result
+}
+
+// GetFeaturesByLevel returns the features whose enablement level for the user is one of the given levels
+func (c *ClientImpl) GetFeaturesByLevel(ctx context.Context, levels []string, user *authclient.User) []UserFeature {
+	ctx, end := c.tracer.StartSpan(ctx, "featuretoggles.GetFeaturesByLevel", map[string]string{"feature.levels": strings.Join(levels, ",")})
+	defer end()
+	result := make([]UserFeature, 0)
+	if !c.clientListener.ready {
+		log.Error(ctx, map[string]interface{}{"error": "client is not ready"}, "unable to list features by level")
+		return result
+	}
+	for _, f := range c.repository(ctx).GetFeaturesByPattern(".*") {
+		result = append(result, c.toUserFeature(ctx, f, user))
+	}
+	return FilterByLevel(result, levels)
+}
+
 // GetFeaturesByPattern returns the features whose ID matches the given pattern
 func (c *ClientImpl) GetFeaturesByStrategy(ctx context.Context, strategy string, user *authclient.User) []UserFeature {
+	ctx, end := c.tracer.StartSpan(ctx, "featuretoggles.GetFeaturesByStrategy", map[string]string{"feature.strategy": strategy})
+	defer end()
 	result := make([]UserFeature, 0)
 	if !c.clientListener.ready {
 		log.Error(ctx, map[string]interface{}{"error": "client is not ready"}, "unable to list features by pattern")
 		return result
 	}
-	feats := c.UnleashClient.GetFeaturesByStrategy(strategy)
+	feats := c.repository(ctx).GetFeaturesByStrategy(strategy)
 	for _, f := range feats {
 		result = append(result, c.toUserFeature(ctx, f, user))
 	}
 	return result
 }
 
-// isFeatureEnabled returns a boolean to specify whether on feature is enabled for a given user level
-func (c *ClientImpl) isFeatureEnabled(ctx context.Context, feature unleashapi.Feature, user *authclient.User) (bool, string) {
+// Enroll records the user's choice to join or leave the feature with the given name.
+// Users can only opt-in for features which allow self-enrollment.
+func (c *ClientImpl) Enroll(ctx context.Context, name string, user *authclient.User, enrollment Enrollment) error {
+	userID, err := c.lookupEnrollment(ctx, name, user, enrollment)
+	if err != nil {
+		return err
+	}
+	if err := c.enrollments.Set(userID, name, enrollment); err != nil {
+		log.Error(ctx, map[string]interface{}{"err": err, "feature_name": name}, "unable to record enrollment")
+		return errors.NewInternalError(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{"feature_name": name, "enrollment": enrollment}, "recorded enrollment")
+	return nil
+}
+
+// Unenroll removes the user's choice to join or leave the feature with the given name.
+func (c *ClientImpl) Unenroll(ctx context.Context, name string, user *authclient.User) error {
+	userID, err := c.lookupEnrollment(ctx, name, user, NoEnrollment)
+	if err != nil {
+		return err
+	}
+	if err := c.enrollments.Delete(userID, name); err != nil {
+		log.Error(ctx, map[string]interface{}{"err": err, "feature_name": name}, "unable to remove enrollment")
+		return errors.NewInternalError(ctx, err)
+	}
+	log.Info(ctx, map[string]interface{}{"feature_name": name}, "removed enrollment")
+	return nil
+}
+
+// lookupEnrollment verifies that the given user can change his/her enrollment for the feature with the given name,
+// and returns the user's ID
+func (c *ClientImpl) lookupEnrollment(ctx context.Context, name string, user *authclient.User, enrollment Enrollment) (string, error) {
+	userID := getUserID(user)
+	if userID == "" {
+		return "", errors.NewUnauthorizedError("missing user identity")
+	}
+	if !c.clientListener.ready {
+		return "", errors.NewInternalError(ctx, errs.New("client is not ready"))
+	}
+	f := c.UnleashClient.GetFeature(name)
+	if f == nil {
+		return "", errors.NewNotFoundError("feature", name)
+	}
+	if enrollment == OptIn && !AllowsSelfEnrollment(*f) {
+		return "", errors.NewForbiddenError(fmt.Sprintf("feature '%s' does not allow self-enrollment", name))
+	}
+	return userID, nil
+}
+
+// IsInternalUser returns `true` if the given user is an internal user, i.e., a user with a verified email address
+// in one of the given domains (e.g. `redhat.com`).
+// Internal users may be able to access the features by opting-in to the `internal` level of features.
+func IsInternalUser(user *authclient.User, domains []string) bool {
+	if user == nil || user.Data == nil || user.Data.Attributes == nil {
+		return false
+	}
+	attrs := user.Data.Attributes
+	if attrs.Email == nil || attrs.EmailVerified == nil || !*attrs.EmailVerified {
+		return false
+	}
+	email := strings.ToLower(*attrs.Email)
+	for _, domain := range domains {
+		if strings.HasSuffix(email, "@"+strings.ToLower(domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// internalEmailDomains returns the domains of the email addresses of the internal users
+func (c *ClientImpl) internalEmailDomains() []string {
+	if c.internalUsers == nil {
+		return DefaultInternalEmailDomains
+	}
+	return c.internalUsers.GetInternalEmailDomains()
+}
+
+// getUserID returns the ID of the given user, or an empty string if the user is unknown
+func getUserID(user *authclient.User) string {
+	if user != nil && user.Data != nil && user.Data.ID != nil {
+		return *user.Data.ID
+	}
+	return ""
+}
+
+// applyEnrollment overrides the user enablement with the user's enrollment for the given feature, if any.
+// An opt-out always wins, while an opt-in is only honoured if the feature is enabled and allows self-enrollment.
+func (c *ClientImpl) applyEnrollment(ctx context.Context, feature unleashapi.Feature, user *authclient.User, userEnabled bool) (bool, Enrollment) {
+	userID := getUserID(user)
+	if userID == "" {
+		return userEnabled, NoEnrollment
+	}
+	enrollment, err := c.enrollments.Get(userID, feature.Name)
+	if err != nil {
+		log.Error(ctx, map[string]interface{}{"err": err, "feature_name": feature.Name}, "unable to look-up enrollment")
+		return userEnabled, NoEnrollment
+	}
+	switch enrollment {
+	case OptOut:
+		return false, OptOut
+	case OptIn:
+		if feature.Enabled && AllowsSelfEnrollment(feature) {
+			return true, OptIn
+		}
+		log.Debug(ctx, map[string]interface{}{"feature_name": feature.Name}, "ignoring opt-in since feature does not allow self-enrollment")
+	}
+	return userEnabled, NoEnrollment
+}
+
+// isFeatureEnabled returns a boolean to specify whether on feature is enabled for a given user level,
+// along with the enablement level and the user's enrollment if it overrode the result
+func (c *ClientImpl) isFeatureEnabled(ctx context.Context, feature unleashapi.Feature, user *authclient.User) (bool, string, Enrollment) {
 	if !c.clientListener.ready {
 		log.Warn(ctx, nil, "unable to check if feature is enabled due to: client is not ready")
-		return false, UnknownLevel
+		return false, UnknownLevel, NoEnrollment
+	}
+	userCtx, internalUser := c.userContext(ctx, user)
+	log.Debug(ctx, map[string]interface{}{"user_level": userCtx.Properties[LevelParameter], "user_email": userCtx.Properties[EmailsParameter]}, "checking if feature is enabled for user...")
+	var userEnabled bool
+	if s, found := ContextSnapshot(ctx); found && s != c.loadSnapshot() {
+		// the features were refreshed since the snapshot was taken (or are not snapshotted by the client):
+		// evaluate the frozen features with the same strategies as the unleash client
+		userEnabled = s.IsEnabledWithContext(feature.Name, userCtx)
+	} else if e, ok := c.UnleashClient.(localEvaluator); ok {
+		userEnabled = e.IsEnabledWithContext(feature.Name, userCtx)
+	} else {
+		// the snapshot has the same features as the unleash client, which also records the usage of the feature
+		userEnabled = c.UnleashClient.IsEnabled(feature.Name, unleash.WithContext(userCtx))
 	}
-	internalUser := false
+	userEnabled, override := c.applyEnrollment(ctx, feature, user, userEnabled)
+	enablementLevel := ComputeEnablementLevel(ctx, feature, internalUser)
+	return userEnabled, enablementLevel, override
+}
+
+// userContext returns the unleash context in which the features are evaluated for the given user (or for the user
+// previewed by an admin), along with whether the user is an internal user
+func (c *ClientImpl) userContext(ctx context.Context, user *authclient.User) (unleashcontext.Context, bool) {
+	internalUser := IsInternalUser(user, c.internalEmailDomains())
 	userLevel := ReleasedLevel // default level of features that the user can use
 	userEmail := ""            // default email: empty
 	if user != nil {
 		if user.Data.Attributes.Email != nil {
 			userEmail = *user.Data.Attributes.Email
 		}
-		// internal users have may be able to access the feature by opting-in to the `internal` level of features.
-		if user.Data.Attributes.EmailVerified != nil && *user.Data.Attributes.EmailVerified && strings.HasSuffix(userEmail, "@redhat.com") {
-			internalUser = true
-		}
 		// do not override the userLevel if the value is nil or empty. Any other value is accepted,
 		// but will be converted (with a fallback to `unknown` if needed)
 		if user.Data.Attributes.FeatureLevel != nil && *user.Data.Attributes.FeatureLevel != "" {
 			userLevel = *user.Data.Attributes.FeatureLevel
 		}
 	}
-	log.Debug(ctx, map[string]interface{}{"user_level": userLevel, "user_email": userEmail}, "checking if feature is enabled for user...")
-	userEnabled := c.UnleashClient.IsEnabled(
-		feature.Name,
-		unleash.WithContext(unleashcontext.Context{
-			Properties: map[string]string{
-				LevelParameter:  userLevel,
-				EmailsParameter: userEmail,
-			},
-		}),
-	)
-	enablementLevel := ComputeEnablementLevel(ctx, feature, internalUser)
-	return userEnabled, enablementLevel
+	// admins may evaluate the features as another user
+	if preview, ok := ContextPreview(ctx); ok {
+		if preview.Level != nil {
+			userLevel = *preview.Level
+		}
+		if preview.Email != nil {
+			userEmail = *preview.Email
+		}
+		if preview.Internal != nil {
+			internalUser = *preview.Internal
+		}
+		log.Debug(ctx, map[string]interface{}{"user_level": userLevel, "user_email": userEmail, "internal_user": internalUser}, "previewing feature")
+	}
+	return unleashcontext.Context{
+		Properties: map[string]string{
+			LevelParameter:  userLevel,
+			EmailsParameter: userEmail,
+		},
+	}, internalUser
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -354,3 +355,210 @@ func TestGetFeaturesByStrategy(t *testing.T) {
 		require.Empty(t, f)
 	})
 }
+
+// metadataProvider a provider of metadata backed by a map
+type metadataProvider map[string]featuretoggles.Metadata
+
+func (p metadataProvider) GetMetadata(name string) (featuretoggles.Metadata, bool) {
+	m, found := p[name]
+	return m, found
+}
+
+func TestGetFeaturesByTags(t *testing.T) {
+	// given
+	newFeature := func(name string) unleashapi.Feature {
+		return unleashapi.Feature{
+			Name:    name,
+			Enabled: true,
+			Strategies: []unleashapi.Strategy{
+				{
+					Name: featuretoggles.EnableByLevelStrategyName,
+					Parameters: map[string]interface{}{
+						featuretoggles.LevelParameter: featuretoggles.BetaLevel,
+					},
+				},
+			},
+		}
+	}
+	mockUnleashClient := testfeaturetoggles.NewUnleashClientMock(t)
+	mockUnleashClient.GetFeaturesByPatternFunc = func(pattern string) []unleashapi.Feature {
+		return []unleashapi.Feature{newFeature("planner.board"), newFeature("planner.list"), newFeature("analyze")}
+	}
+	mockUnleashClient.IsEnabledFunc = func(feature string, options ...unleash.FeatureOption) (enabled bool) {
+		return true
+	}
+	metadata := metadataProvider{
+		"planner.board": {Tags: []string{"planner", "ui"}},
+		"planner.list":  {Tags: []string{"planner"}},
+	}
+	betaLevel := featuretoggles.BetaLevel
+	user := &authclient.User{
+		Data: &authclient.UserData{
+			Attributes: &authclient.UserDataAttributes{
+				FeatureLevel: &betaLevel,
+			},
+		},
+	}
+
+	t.Run("matches", func(t *testing.T) {
+		// given
+		ft := featuretoggles.NewClientWithState(mockUnleashClient, true, featuretoggles.WithMetadataProvider(metadata))
+		// when
+		f := ft.GetFeaturesByTags(context.Background(), featuretoggles.ParseTagQuery([]string{"planner", "ui"}), user)
+		// then
+		require.Len(t, f, 1)
+		assert.Equal(t, "planner.board", f[0].Name)
+		assert.Equal(t, []string{"planner", "ui"}, f[0].Tags)
+	})
+
+	t.Run("no metadata", func(t *testing.T) {
+		// given
+		ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+		// when
+		f := ft.GetFeaturesByTags(context.Background(), featuretoggles.ParseTagQuery([]string{"planner"}), user)
+		// then
+		require.Empty(t, f)
+	})
+
+	t.Run("client not ready", func(t *testing.T) {
+		// given
+		ft := featuretoggles.NewClientWithState(mockUnleashClient, false, featuretoggles.WithMetadataProvider(metadata))
+		// when
+		f := ft.GetFeaturesByTags(context.Background(), featuretoggles.ParseTagQuery([]string{"planner"}), user)
+		// then
+		require.Empty(t, f)
+	})
+}
+
+func TestGetFeaturesByLevel(t *testing.T) {
+	// given
+	newFeature := func(name, level string) unleashapi.Feature {
+		return unleashapi.Feature{
+			Name:    name,
+			Enabled: true,
+			Strategies: []unleashapi.Strategy{
+				{
+					Name: featuretoggles.EnableByLevelStrategyName,
+					Parameters: map[string]interface{}{
+						featuretoggles.LevelParameter: level,
+					},
+				},
+			},
+		}
+	}
+	mockUnleashClient := testfeaturetoggles.NewUnleashClientMock(t)
+	mockUnleashClient.GetFeaturesByPatternFunc = func(pattern string) []unleashapi.Feature {
+		return []unleashapi.Feature{
+			newFeature("planner", featuretoggles.ReleasedLevel),
+			newFeature("planner.board", featuretoggles.BetaLevel),
+			newFeature("chat", featuretoggles.InternalLevel),
+		}
+	}
+	mockUnleashClient.IsEnabledFunc = func(feature string, options ...unleash.FeatureOption) (enabled bool) {
+		return true
+	}
+	ft := featuretoggles.NewClientWithState(mockUnleashClient, true)
+
+	t.Run("external user", func(t *testing.T) {
+		// when the internal features have no enablement level for an external user
+		f := ft.GetFeaturesByLevel(context.Background(), []string{featuretoggles.BetaLevel, featuretoggles.InternalLevel}, nil)
+		// then
+		require.Len(t, f, 1)
+		assert.Equal(t, "planner.board", f[0].Name)
+	})
+
+	t.Run("client not ready", func(t *testing.T) {
+		// given
+		ft := featuretoggles.NewClientWithState(mockUnleashClient, false)
+		// when
+		f := ft.GetFeaturesByLevel(context.Background(), featuretoggles.Levels(), nil)
+		// then
+		require.Empty(t, f)
+	})
+}
+
+// evaluationRecorder a recorder which keeps the recorded evaluations
+type evaluationRecorder struct {
+	evaluations map[string]bool
+}
+
+func (r *evaluationRecorder) RecordUnleashRefresh(time.Time) {}
+func (r *evaluationRecorder) SetUnleashReady(bool)           {}
+func (r *evaluationRecorder) RecordUnleashError()            {}
+func (r *evaluationRecorder) RecordUnleashWarning()          {}
+func (r *evaluationRecorder) RecordEvaluation(feature string, enabled bool) {
+	r.evaluations[feature] = enabled
+}
+
+func TestRecordEvaluations(t *testing.T) {
+	// given
+	plannerFeature := unleashapi.Feature{Name: "planner", Enabled: true}
+	boardFeature := unleashapi.Feature{Name: "planner.board", Enabled: true}
+	mockUnleashClient := testfeaturetoggles.NewUnleashClientMock(t)
+	mockUnleashClient.GetFeatureFunc = func(name string) *unleashapi.Feature {
+		if name == plannerFeature.Name {
+			return &plannerFeature
+		}
+		return nil
+	}
+	mockUnleashClient.GetFeaturesByPatternFunc = func(pattern string) []unleashapi.Feature {
+		return []unleashapi.Feature{plannerFeature, boardFeature}
+	}
+	mockUnleashClient.IsEnabledFunc = func(feature string, options ...unleash.FeatureOption) (enabled bool) {
+		return feature == plannerFeature.Name
+	}
+	recorder := &evaluationRecorder{}
+	ft := featuretoggles.NewClientWithState(mockUnleashClient, true, featuretoggles.WithRecorder(recorder))
+
+	t.Run("single feature", func(t *testing.T) {
+		// given
+		recorder.evaluations = map[string]bool{}
+		// when
+		ft.GetFeature(context.Background(), plannerFeature.Name, nil)
+		// then
+		assert.Equal(t, map[string]bool{"planner": true}, recorder.evaluations)
+	})
+
+	t.Run("all features", func(t *testing.T) {
+		// given
+		recorder.evaluations = map[string]bool{}
+		// when the features are evaluated for the catalog, the tree, etc.
+		ft.GetFeaturesByLevel(context.Background(), featuretoggles.Levels(), nil)
+		// then
+		assert.Equal(t, map[string]bool{"planner": true, "planner.board": false}, recorder.evaluations)
+	})
+
+	t.Run("preview", func(t *testing.T) {
+		// given
+		recorder.evaluations = map[string]bool{}
+		level := featuretoggles.InternalLevel
+		ctx := featuretoggles.ContextWithPreview(context.Background(), featuretoggles.Preview{Level: &level})
+		// when
+		ft.GetFeaturesByGroups(ctx, []string{"planner"}, false, nil)
+		ft.GetFeature(ctx, plannerFeature.Name, nil)
+		// then
+		assert.Empty(t, recorder.evaluations)
+	})
+}
+
+func TestIsInternalUser(t *testing.T) {
+	// given
+	newUser := func(email string, verified bool) *authclient.User {
+		return &authclient.User{
+			Data: &authclient.UserData{
+				Attributes: &authclient.UserDataAttributes{
+					Email:         &email,
+					EmailVerified: &verified,
+				},
+			},
+		}
+	}
+	domains := []string{"redhat.com", "IBM.com"}
+	// then
+	assert.True(t, featuretoggles.IsInternalUser(newUser("user@redhat.com", true), domains))
+	assert.True(t, featuretoggles.IsInternalUser(newUser("user@ibm.com", true), domains))
+	assert.False(t, featuretoggles.IsInternalUser(newUser("user@redhat.com", false), domains))
+	assert.False(t, featuretoggles.IsInternalUser(newUser("user@notredhat.com", true), domains))
+	assert.False(t, featuretoggles.IsInternalUser(newUser("user@redhat.com", true), []string{}))
+	assert.False(t, featuretoggles.IsInternalUser(nil, domains))
+}
@@ -0,0 +1,155 @@
+package featuretoggles
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	errs "github.com/pkg/errors"
+)
+
+// headerTransport an HTTP transport which sets the API token and the custom headers on the requests to the Toggle service
+type headerTransport struct {
+	transport http.RoundTripper
+	config    ToggleServiceConfiguration
+}
+
+// RoundTrip sets the headers on a copy of the given request (the request must not be modified by a transport),
+// then executes it with the underlying transport. The API token is read on each request, so it can be rotated at runtime.
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	headers := t.config.GetTogglesCustomHeaders()
+	token := t.config.GetTogglesAPIToken()
+	if len(headers) == 0 && token == "" {
+		return t.transport.RoundTrip(req)
+	}
+	r := new(http.Request)
+	*r = *req
+	r.Header = make(http.Header, len(req.Header)+len(headers)+1)
+	for k, v := range req.Header {
+		r.Header[k] = append([]string(nil), v...)
+	}
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	// do not override the token set by the caller (e.g., an admin API token)
+	if token != "" && r.Header.Get("Authorization") == "" {
+		r.Header.Set("Authorization", token)
+	}
+	return t.transport.RoundTrip(r)
+}
+
+// NewHTTPClient returns a new HTTP client to the Toggle service, configured like the one of the underlying unleash client
+// (e.g., to call the admin API of the Toggle service)
+func NewHTTPClient(config ToggleServiceConfiguration) (*http.Client, error) {
+	transport, err := newTransport(config)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.GetTogglesTimeout(),
+	}, nil
+}
+
+// newTransport returns a new HTTP transport to the Toggle service, which trusts the configured CA bundle (on top of the
+// system CAs), uses the configured proxy (or the proxy set in the environment) and sets the API token and the custom headers
+func newTransport(config ToggleServiceConfiguration) (http.RoundTripper, error) {
+	proxy := http.ProxyFromEnvironment
+	if proxyURL := config.GetTogglesProxyURL(); proxyURL != "" {
+		u, err := url.Parse(proxyURL)
+		if err != nil {
+			return nil, errs.Wrapf(err, "invalid toggles proxy URL '%s'", proxyURL)
+		}
+		proxy = http.ProxyURL(u)
+	}
+	tlsConfig := &tls.Config{}
+	if caBundleFile := config.GetTogglesCABundleFile(); caBundleFile != "" {
+		pem, err := ioutil.ReadFile(caBundleFile)
+		if err != nil {
+			return nil, errs.Wrapf(err, "unable to read the toggles CA bundle '%s'", caBundleFile)
+		}
+		roots, err := x509.SystemCertPool()
+		if err != nil || roots == nil {
+			roots = x509.NewCertPool()
+		}
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, errs.Errorf("no valid certificate in the toggles CA bundle '%s'", caBundleFile)
+		}
+		tlsConfig.RootCAs = roots
+	}
+	// same settings as the `http.DefaultTransport`
+	transport := &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		TLSClientConfig:       tlsConfig,
+	}
+	return &headerTransport{
+		transport: transport,
+		config:    config,
+	}, nil
+}
+
+// refreshTransport an HTTP transport for the Unleash client, which records the successful refreshes of the features
+type refreshTransport struct {
+	transport http.RoundTripper
+	recorder  Recorder
+	// onUpdate the function called when the features were changed on the Unleash server: once when the response is
+	// received, and once again when the unleash client closes its body, i.e., after it replaced its features
+	onUpdate    func()
+	lastRefresh int64
+}
+
+// RoundTrip executes the given request using the underlying transport, and records a refresh if the request fetched
+// the features and succeeded
+func (t *refreshTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	res, err := t.transport.RoundTrip(req)
+	if err == nil && strings.HasSuffix(req.URL.Path, "/client/features") &&
+		(res.StatusCode == http.StatusOK || res.StatusCode == http.StatusNotModified) {
+		now := time.Now()
+		atomic.StoreInt64(&t.lastRefresh, now.UnixNano())
+		t.recorder.RecordUnleashRefresh(now)
+		// the features are unchanged if the response is `304 Not Modified`
+		if res.StatusCode == http.StatusOK && t.onUpdate != nil {
+			t.onUpdate()
+			res.Body = &updateBody{ReadCloser: res.Body, onClose: t.onUpdate}
+		}
+	}
+	return res, err
+}
+
+// updateBody the body of a response with new features, which calls a function once closed
+type updateBody struct {
+	io.ReadCloser
+	onClose func()
+}
+
+// Close closes the underlying body, then calls the function
+func (b *updateBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.onClose()
+	return err
+}
+
+// LastRefresh returns the time of the last successful refresh of the features through this transport,
+// or the zero time if the features were never refreshed
+func (t *refreshTransport) LastRefresh() time.Time {
+	last := atomic.LoadInt64(&t.lastRefresh)
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
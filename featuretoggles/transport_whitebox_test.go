@@ -0,0 +1,157 @@
+package featuretoggles
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testToggleServiceConfig struct {
+	token        string
+	headers      map[string]string
+	caBundleFile string
+	proxyURL     string
+}
+
+func (c testToggleServiceConfig) GetTogglesURL() string                      { return "" }
+func (c testToggleServiceConfig) GetTogglesAPIToken() string                 { return c.token }
+func (c testToggleServiceConfig) GetTogglesCustomHeaders() map[string]string { return c.headers }
+func (c testToggleServiceConfig) GetTogglesCABundleFile() string             { return c.caBundleFile }
+func (c testToggleServiceConfig) GetTogglesProxyURL() string                 { return c.proxyURL }
+func (c testToggleServiceConfig) GetTogglesTimeout() time.Duration           { return 10 * time.Second }
+func (c testToggleServiceConfig) GetTogglesInstanceID() string               { return "" }
+
+func TestTransport(t *testing.T) {
+
+	var received http.Header
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		received = req.Header
+	}))
+	defer server.Close()
+	// CA bundle with the certificate of the test server
+	f, err := ioutil.TempFile("", "ca-bundle")
+	require.NoError(t, err)
+	defer os.Remove(f.Name())
+	require.NoError(t, pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+	require.NoError(t, f.Close())
+
+	t.Run("ok", func(t *testing.T) {
+
+		t.Run("with token and custom headers", func(t *testing.T) {
+			// given
+			transport, err := newTransport(testToggleServiceConfig{
+				token:        "secret",
+				headers:      map[string]string{"x-custom-header": "foo"},
+				caBundleFile: f.Name(),
+			})
+			require.NoError(t, err)
+			req, err := http.NewRequest(http.MethodGet, server.URL+"/client/features", nil)
+			require.NoError(t, err)
+			req.Header.Set("Accept", "application/json")
+			// when
+			res, err := (&http.Client{Transport: transport}).Do(req)
+			// then
+			require.NoError(t, err)
+			res.Body.Close()
+			assert.Equal(t, "secret", received.Get("Authorization"))
+			assert.Equal(t, "foo", received.Get("X-Custom-Header"))
+			assert.Equal(t, "application/json", received.Get("Accept"))
+			// original request must not be modified
+			assert.Empty(t, req.Header.Get("Authorization"))
+		})
+
+		t.Run("without token", func(t *testing.T) {
+			// given
+			transport, err := newTransport(testToggleServiceConfig{
+				caBundleFile: f.Name(),
+			})
+			require.NoError(t, err)
+			// when
+			res, err := (&http.Client{Transport: transport}).Get(server.URL + "/client/features")
+			// then
+			require.NoError(t, err)
+			res.Body.Close()
+			assert.Empty(t, received.Get("Authorization"))
+		})
+	})
+
+	t.Run("fail", func(t *testing.T) {
+
+		t.Run("untrusted server certificate", func(t *testing.T) {
+			// given
+			transport, err := newTransport(testToggleServiceConfig{})
+			require.NoError(t, err)
+			// when
+			_, err = (&http.Client{Transport: transport}).Get(server.URL + "/client/features")
+			// then
+			require.Error(t, err)
+		})
+
+		t.Run("invalid CA bundle", func(t *testing.T) {
+			// given
+			invalid, err := ioutil.TempFile("", "ca-bundle")
+			require.NoError(t, err)
+			defer os.Remove(invalid.Name())
+			_, err = invalid.WriteString("not a certificate")
+			require.NoError(t, err)
+			require.NoError(t, invalid.Close())
+			// when
+			_, err = newTransport(testToggleServiceConfig{caBundleFile: invalid.Name()})
+			// then
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "no valid certificate in the toggles CA bundle")
+		})
+
+		t.Run("invalid proxy URL", func(t *testing.T) {
+			// when
+			_, err := newTransport(testToggleServiceConfig{proxyURL: "://proxy"})
+			// then
+			require.Error(t, err)
+		})
+	})
+}
+
+type testRecorder struct {
+	nopRecorder
+	refreshes int
+}
+
+func (r *testRecorder) RecordUnleashRefresh(time.Time) {
+	r.refreshes++
+}
+
+func TestRefreshTransport(t *testing.T) {
+	// given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	recorder := &testRecorder{}
+	updates := 0
+	transport := &refreshTransport{transport: http.DefaultTransport, recorder: recorder, onUpdate: func() { updates++ }}
+	client := http.Client{Transport: transport}
+	before := time.Now()
+	// when
+	res, err := client.Get(server.URL + "/api/client/register")
+	require.NoError(t, err)
+	res.Body.Close()
+	// then
+	assert.True(t, transport.LastRefresh().IsZero())
+	assert.Equal(t, 0, updates)
+	// when
+	res, err = client.Get(server.URL + "/api/client/features")
+	require.NoError(t, err)
+	// then the features are updated when the response is received, and once again after its body was read
+	assert.Equal(t, 1, updates)
+	res.Body.Close()
+	assert.Equal(t, 2, updates)
+	assert.False(t, transport.LastRefresh().Before(before))
+	assert.Equal(t, 1, recorder.refreshes)
+}
@@ -0,0 +1,95 @@
+package featuretoggles
+
+import (
+	"sort"
+	"strings"
+)
+
+// FeatureTreeNode a node of the hierarchy of the features, in which the features are grouped by the dot-separated
+// segments of their names (e.g., the `planner.board` node is a child of the `planner` node)
+type FeatureTreeNode struct {
+	// Path the full name of the node, e.g. `planner.board`
+	Path string
+	// Name the last segment of the path, e.g. `board`
+	Name string
+	// Feature the feature named after the node, if any (a node may only group other features)
+	Feature *UserFeature
+	// Children the child nodes, sorted by name
+	Children []*FeatureTreeNode
+	// FeatureCount the number of features in the sub-tree of the node, including the feature of the node itself
+	FeatureCount int
+	// EnabledCount the number of features enabled for the user in the sub-tree of the node, including the feature of the node itself
+	EnabledCount int
+}
+
+// GetETagData returns the field values to use to generate the ETag
+func (n FeatureTreeNode) GetETagData() []interface{} {
+	data := []interface{}{n.Path}
+	if n.Feature != nil {
+		data = append(data, n.Feature.GetETagData())
+	}
+	for _, c := range n.Children {
+		data = append(data, c.GetETagData())
+	}
+	return data
+}
+
+// NewFeatureTree returns the root nodes of the hierarchy of the given features, sorted by name
+func NewFeatureTree(features []UserFeature) []*FeatureTreeNode {
+	root := &FeatureTreeNode{}
+	for i := range features {
+		f := features[i]
+		node := root
+		for _, segment := range strings.Split(f.Name, ".") {
+			node = node.child(segment)
+			node.FeatureCount++
+			if f.UserEnabled {
+				node.EnabledCount++
+			}
+		}
+		node.Feature = &f
+	}
+	root.sort()
+	return root.Children
+}
+
+// FindFeatureTreeNode returns the node with the given path in the given tree, or `nil` if none matches
+func FindFeatureTreeNode(nodes []*FeatureTreeNode, path string) *FeatureTreeNode {
+	for _, n := range nodes {
+		if n.Path == path {
+			return n
+		}
+		if strings.HasPrefix(path, n.Path+".") {
+			return FindFeatureTreeNode(n.Children, path)
+		}
+	}
+	return nil
+}
+
+// child returns the child node with the given name, after creating it if needed
+func (n *FeatureTreeNode) child(name string) *FeatureTreeNode {
+	for _, c := range n.Children {
+		if c.Name == name {
+			return c
+		}
+	}
+	path := name
+	if n.Path != "" {
+		path = n.Path + "." + name
+	}
+	c := &FeatureTreeNode{
+		Path: path,
+		Name: name,
+	}
+	n.Children = append(n.Children, c)
+	return c
+}
+
+func (n *FeatureTreeNode) sort() {
+	sort.Slice(n.Children, func(i, j int) bool {
+		return n.Children[i].Name < n.Children[j].Name
+	})
+	for _, c := range n.Children {
+		c.sort()
+	}
+}
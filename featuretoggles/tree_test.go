@@ -0,0 +1,52 @@
+package featuretoggles_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFeatureTree(t *testing.T) {
+	// given
+	features := []featuretoggles.UserFeature{
+		{Name: "planner.board.dnd", UserEnabled: true},
+		{Name: "planner", UserEnabled: true},
+		{Name: "planner.list", UserEnabled: false},
+		{Name: "analyze", UserEnabled: false},
+	}
+	// when
+	tree := featuretoggles.NewFeatureTree(features)
+	// then
+	require.Len(t, tree, 2)
+	analyze := tree[0]
+	assert.Equal(t, "analyze", analyze.Path)
+	require.NotNil(t, analyze.Feature)
+	assert.Equal(t, 1, analyze.FeatureCount)
+	assert.Equal(t, 0, analyze.EnabledCount)
+	assert.Empty(t, analyze.Children)
+	planner := tree[1]
+	assert.Equal(t, "planner", planner.Path)
+	require.NotNil(t, planner.Feature)
+	assert.Equal(t, 3, planner.FeatureCount)
+	assert.Equal(t, 2, planner.EnabledCount)
+	require.Len(t, planner.Children, 2)
+	// the 'planner.board' node only groups other features
+	board := planner.Children[0]
+	assert.Equal(t, "planner.board", board.Path)
+	assert.Equal(t, "board", board.Name)
+	assert.Nil(t, board.Feature)
+	assert.Equal(t, 1, board.FeatureCount)
+	assert.Equal(t, 1, board.EnabledCount)
+	require.Len(t, board.Children, 1)
+	assert.Equal(t, "planner.board.dnd", board.Children[0].Feature.Name)
+	assert.Equal(t, "planner.list", planner.Children[1].Path)
+
+	t.Run("find", func(t *testing.T) {
+		assert.Equal(t, board, featuretoggles.FindFeatureTreeNode(tree, "planner.board"))
+		assert.Equal(t, "planner.board.dnd", featuretoggles.FindFeatureTreeNode(tree, "planner.board.dnd").Path)
+		assert.Nil(t, featuretoggles.FindFeatureTreeNode(tree, "planner.boards"))
+		assert.Nil(t, featuretoggles.FindFeatureTreeNode(tree, "deploy"))
+	})
+}
@@ -1,5 +1,9 @@
 package featuretoggles
 
+import (
+	"strings"
+)
+
 // UserFeature a feature with the user enablement
 type UserFeature struct {
 	Name            string
@@ -7,16 +11,29 @@ type UserFeature struct {
 	Enabled         bool
 	EnablementLevel string
 	UserEnabled     bool
+	Override        Enrollment
+	Preview         bool
+	Tags            []string
+	// BlockedBy the prerequisites which are not enabled for the user, and which hence disabled the feature for the user
+	BlockedBy []string
+	// Version the version of the snapshot against which the feature was evaluated, if any
+	Version string
 }
 
 // GetETagData returns the field values to use to generate the ETag
 func (f UserFeature) GetETagData() []interface{} {
-	return []interface{}{f.Name, f.Description, f.Enabled, f.EnablementLevel, f.UserEnabled}
+	return []interface{}{f.Name, f.Description, f.Enabled, f.EnablementLevel, f.UserEnabled, string(f.Override), f.Preview, strings.Join(f.Tags, ","), strings.Join(f.BlockedBy, ","), f.Version}
 }
 
-// ZeroUserFeature to check if a feature is empty
+// ZeroUserFeature the empty feature, returned when a feature does not exist
 var ZeroUserFeature UserFeature
 
+// IsZero returns `true` if the feature is empty (i.e., the feature does not exist). Since the features have a slice
+// of tags, they cannot be compared with `ZeroUserFeature` using the `==` operator.
+func (f UserFeature) IsZero() bool {
+	return f.Name == ""
+}
+
 // ByName implements sort.Interface for []UserFeature based on
 // the Name field.
 type ByName []UserFeature
@@ -89,6 +89,39 @@ func TestComputeUserFeatureEtag(t *testing.T) {
 		assert.NotEqual(t, etag2, etag)
 	})
 
+	t.Run("change tags", func(t *testing.T) {
+		// given
+		feature2 := duplicate(feature)
+		feature2.Tags = []string{"ui"}
+		// when
+		etag := app.GenerateEntityTag(feature)
+		etag2 := app.GenerateEntityTag(feature2)
+		// then
+		assert.NotEqual(t, etag2, etag)
+	})
+
+	t.Run("change blocking prerequisites", func(t *testing.T) {
+		// given
+		feature2 := duplicate(feature)
+		feature2.BlockedBy = []string{"planner"}
+		// when
+		etag := app.GenerateEntityTag(feature)
+		etag2 := app.GenerateEntityTag(feature2)
+		// then
+		assert.NotEqual(t, etag2, etag)
+	})
+
+	t.Run("change version", func(t *testing.T) {
+		// given
+		feature2 := duplicate(feature)
+		feature2.Version = "0123456789abcdef"
+		// when
+		etag := app.GenerateEntityTag(feature)
+		etag2 := app.GenerateEntityTag(feature2)
+		// then
+		assert.NotEqual(t, etag2, etag)
+	})
+
 }
 
 func duplicate(f featuretoggles.UserFeature) featuretoggles.UserFeature {
@@ -98,5 +131,8 @@ func duplicate(f featuretoggles.UserFeature) featuretoggles.UserFeature {
 		Enabled:         f.Enabled,
 		EnablementLevel: f.EnablementLevel,
 		UserEnabled:     f.UserEnabled,
+		Tags:            f.Tags,
+		BlockedBy:       f.BlockedBy,
+		Version:         f.Version,
 	}
 }
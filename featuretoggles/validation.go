@@ -0,0 +1,109 @@
+package featuretoggles
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/Unleash/unleash-client-go/strategy"
+	errs "github.com/pkg/errors"
+)
+
+// ParameterValidator the interface implemented by the strategies which can verify the parameters configured on the Unleash server
+type ParameterValidator interface {
+	// ValidateParameters returns an error naming the offending parameter if the given parameters are invalid
+	ValidateParameters(parameters map[string]interface{}) error
+}
+
+// Problem a misconfigured strategy of a feature
+type Problem struct {
+	Feature  string
+	Strategy string
+	Message  string
+}
+
+// String returns a human-readable description of the problem
+func (p Problem) String() string {
+	return fmt.Sprintf("feature '%s', strategy '%s': %s", p.Feature, p.Strategy, p.Message)
+}
+
+// ValidateFeature returns the problems with the strategies of the given feature: unknown strategies, i.e., neither built
+// into the Unleash client nor registered by this service (which are never enabled), and custom strategies with invalid
+// parameters (which are never enabled, or skip the invalid values)
+func ValidateFeature(feature unleashapi.Feature) []Problem {
+	var problems []Problem
+	for _, s := range feature.Strategies {
+		if IsBuiltinStrategy(s.Name) {
+			continue
+		}
+		registered, found := lookupStrategy(s.Name)
+		if !found {
+			problems = append(problems, Problem{Feature: feature.Name, Strategy: s.Name, Message: "unknown strategy"})
+			continue
+		}
+		if v, ok := registered.(ParameterValidator); ok {
+			if err := v.ValidateParameters(s.Parameters); err != nil {
+				problems = append(problems, Problem{Feature: feature.Name, Strategy: s.Name, Message: err.Error()})
+			}
+		}
+	}
+	return problems
+}
+
+// ValidateFeatures returns the problems with the strategies of the given features and with their prerequisites,
+// sorted by feature name
+func ValidateFeatures(features []unleashapi.Feature) []Problem {
+	problems := []Problem{}
+	for _, f := range features {
+		problems = append(problems, ValidateFeature(f)...)
+	}
+	problems = append(problems, ValidatePrerequisites(features)...)
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Feature < problems[j].Feature
+	})
+	return problems
+}
+
+// lookupStrategy returns the custom strategy with the given name, if it is registered
+func lookupStrategy(name string) (strategy.Strategy, bool) {
+	for _, s := range strategies {
+		if s.Name() == name {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// stringParameter returns the value of the given parameter, or an error if it is missing, empty or not a string
+func stringParameter(parameters map[string]interface{}, name string) (string, error) {
+	value, found := parameters[name]
+	if !found || value == nil {
+		return "", errs.Errorf("missing '%s' parameter", name)
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", errs.Errorf("invalid '%s' parameter: expected a string but got '%v' (%T)", name, value, value)
+	}
+	if strings.TrimSpace(s) == "" {
+		return "", errs.Errorf("empty '%s' parameter", name)
+	}
+	return s, nil
+}
+
+// validateBoolParameter returns an error if the given (optional) parameter is set but is not a boolean
+func validateBoolParameter(parameters map[string]interface{}, name string) error {
+	switch value := parameters[name].(type) {
+	case nil, bool:
+		return nil
+	case string:
+		if strings.TrimSpace(value) == "" {
+			return nil
+		}
+		if _, err := strconv.ParseBool(strings.TrimSpace(value)); err == nil {
+			return nil
+		}
+	}
+	return errs.Errorf("invalid '%s' parameter: expected 'true' or 'false' but got '%v'", name, parameters[name])
+}
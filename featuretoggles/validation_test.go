@@ -0,0 +1,106 @@
+package featuretoggles_test
+
+import (
+	"testing"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateFeatures(t *testing.T) {
+
+	t.Run("valid features", func(t *testing.T) {
+		// given
+		features := []unleashapi.Feature{
+			{
+				Name: "foo",
+				Strategies: []unleashapi.Strategy{
+					{
+						Name: featuretoggles.EnableByLevelStrategyName,
+						Parameters: map[string]interface{}{
+							featuretoggles.LevelParameter:          "Beta",
+							featuretoggles.SelfEnrollmentParameter: "true",
+						},
+					},
+					{
+						Name: featuretoggles.EnableByEmailsStrategyName,
+						Parameters: map[string]interface{}{
+							featuretoggles.EmailsParameter: "foo@foo.com, bar@foo.com,",
+						},
+					},
+					{
+						Name: featuretoggles.DefaultStrategyName,
+					},
+					{
+						Name: featuretoggles.UserWithIDStrategyName,
+						Parameters: map[string]interface{}{
+							"userIds": "user-1,user-2",
+						},
+					},
+					{
+						Name: featuretoggles.GradualRolloutRandomStrategyName,
+						Parameters: map[string]interface{}{
+							"percentage": "50",
+						},
+					},
+				},
+			},
+		}
+		// when
+		problems := featuretoggles.ValidateFeatures(features)
+		// then
+		assert.Empty(t, problems)
+	})
+
+	t.Run("invalid features", func(t *testing.T) {
+		// given
+		features := []unleashapi.Feature{
+			{
+				Name: "foo",
+				Strategies: []unleashapi.Strategy{
+					{
+						Name: featuretoggles.EnableByEmailsStrategyName,
+						Parameters: map[string]interface{}{
+							featuretoggles.EmailsParameter: "foo@foo.com,bar",
+						},
+					},
+				},
+			},
+			{
+				Name: "bar",
+				Strategies: []unleashapi.Strategy{
+					{
+						Name: featuretoggles.EnableByLevelStrategyName,
+						Parameters: map[string]interface{}{
+							featuretoggles.LevelParameter: 2,
+						},
+					},
+					{
+						Name: "enableByPlanet",
+					},
+				},
+			},
+		}
+		// when
+		problems := featuretoggles.ValidateFeatures(features)
+		// then
+		require.Len(t, problems, 3)
+		assert.Equal(t, featuretoggles.Problem{
+			Feature:  "bar",
+			Strategy: featuretoggles.EnableByLevelStrategyName,
+			Message:  "invalid 'level' parameter: expected a string but got '2' (int)",
+		}, problems[0])
+		assert.Equal(t, featuretoggles.Problem{
+			Feature:  "bar",
+			Strategy: "enableByPlanet",
+			Message:  "unknown strategy",
+		}, problems[1])
+		assert.Equal(t, featuretoggles.Problem{
+			Feature:  "foo",
+			Strategy: featuretoggles.EnableByEmailsStrategyName,
+			Message:  "invalid 'emails' parameter: 'bar' is not an email address (ignored)",
+		}, problems[2])
+	})
+}
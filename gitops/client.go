@@ -0,0 +1,131 @@
+package gitops
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	errs "github.com/pkg/errors"
+)
+
+// AdminClient a client to the admin API of the Unleash server
+type AdminClient struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+// AdminClientOption a function to customize the AdminClient during its initialization
+type AdminClientOption func(*AdminClient)
+
+// WithAdminToken configures the client with the API token sent in the `Authorization` header
+func WithAdminToken(token string) AdminClientOption {
+	return func(c *AdminClient) {
+		c.token = token
+	}
+}
+
+// WithAdminHTTPClient configures the client with a custom HTTP client
+func WithAdminHTTPClient(httpClient *http.Client) AdminClientOption {
+	return func(c *AdminClient) {
+		c.httpClient = httpClient
+	}
+}
+
+// NewAdminClient returns a new client to the admin API of the Unleash server with the given API URL (e.g. `http://localhost:4242/api`)
+func NewAdminClient(apiURL string, options ...AdminClientOption) *AdminClient {
+	c := AdminClient{
+		url:        strings.TrimSuffix(apiURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range options {
+		opt(&c)
+	}
+	return &c
+}
+
+// Features returns the (non-archived) features of the Unleash server
+func (c *AdminClient) Features(ctx context.Context) ([]Feature, error) {
+	var result struct {
+		Features []Feature `json:"features"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/admin/features", nil, &result); err != nil {
+		return nil, errs.Wrap(err, "unable to list the features")
+	}
+	return result.Features, nil
+}
+
+// Create creates the given feature
+func (c *AdminClient) Create(ctx context.Context, f Feature) error {
+	return errs.Wrapf(c.do(ctx, http.MethodPost, "/admin/features", f, nil), "unable to create the feature '%s'", f.Name)
+}
+
+// Update replaces the feature with the same name
+func (c *AdminClient) Update(ctx context.Context, f Feature) error {
+	return errs.Wrapf(c.do(ctx, http.MethodPut, "/admin/features/"+url.PathEscape(f.Name), f, nil), "unable to update the feature '%s'", f.Name)
+}
+
+// Archive archives the feature with the given name
+func (c *AdminClient) Archive(ctx context.Context, name string) error {
+	return errs.Wrapf(c.do(ctx, http.MethodDelete, "/admin/features/"+url.PathEscape(name), nil, nil), "unable to archive the feature '%s'", name)
+}
+
+// Apply applies the changes of the given plan, in order. Stops at the first error.
+func (c *AdminClient) Apply(ctx context.Context, plan Plan) error {
+	for _, change := range plan.Changes {
+		var err error
+		switch change.Action {
+		case Create:
+			err = c.Create(ctx, *change.Feature)
+		case Update:
+			err = c.Update(ctx, *change.Feature)
+		case Archive:
+			err = c.Archive(ctx, change.Name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *AdminClient) do(ctx context.Context, method, path string, payload, result interface{}) error {
+	var body io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.url+path, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", c.token)
+	}
+	res, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		content, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return errs.Errorf("unexpected status '%s': %s", res.Status, strings.TrimSpace(string(content)))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
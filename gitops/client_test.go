@@ -0,0 +1,66 @@
+package gitops_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/gitops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminClient(t *testing.T) {
+	// given
+	requests := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "secret" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests = append(requests, req.Method+" "+req.URL.Path)
+		switch {
+		case req.Method == http.MethodGet && req.URL.Path == "/api/admin/features":
+			json.NewEncoder(rw).Encode(map[string]interface{}{
+				"features": []gitops.Feature{{Name: "deploy", Enabled: true}},
+			})
+		case req.Method == http.MethodPost:
+			rw.WriteHeader(http.StatusCreated)
+		default:
+			rw.WriteHeader(http.StatusOK)
+		}
+	}))
+	defer server.Close()
+	client := gitops.NewAdminClient(server.URL+"/api/", gitops.WithAdminToken("secret"))
+
+	t.Run("apply plan", func(t *testing.T) {
+		// given
+		current, err := client.Features(context.Background())
+		require.NoError(t, err)
+		plan := gitops.ComputePlan([]gitops.Definition{
+			{Name: "planner", Level: featuretoggles.BetaLevel},
+		}, current, true)
+		// when
+		err = client.Apply(context.Background(), plan)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			"GET /api/admin/features",
+			"POST /api/admin/features",
+			"DELETE /api/admin/features/deploy",
+		}, requests)
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		// given
+		client := gitops.NewAdminClient(server.URL + "/api")
+		// when
+		_, err := client.Features(context.Background())
+		// then
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "401 Unauthorized")
+	})
+}
@@ -0,0 +1,229 @@
+// Package gitops synchronizes the features of the Unleash server with declarative feature files, so that the
+// feature toggles can be reviewed and versioned like the code. The features are compared with their definitions
+// to compute a plan (which also reveals the features changed by hand in Unleash), which can then be applied
+// with the Unleash admin API.
+package gitops
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	errs "github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Definition the declarative definition of a feature, as written in a feature file
+type Definition struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Enabled whether the feature is globally enabled (`true` if not set)
+	Enabled *bool `yaml:"enabled,omitempty"`
+	// Level the level of features from which the feature is enabled (`enableByLevel` strategy)
+	Level string `yaml:"level,omitempty"`
+	// SelfEnrollment whether the users can opt-in for the feature regardless of their level
+	SelfEnrollment bool `yaml:"selfEnrollment,omitempty"`
+	// Emails the email addresses of the users for whom the feature is enabled (`enableByEmails` strategy)
+	Emails   []string  `yaml:"emails,omitempty"`
+	Variants []Variant `yaml:"variants,omitempty"`
+	// Prerequisites the features which must be enabled for the user for the feature to be enabled (`prerequisites` parameter of the strategies)
+	Prerequisites []string `yaml:"prerequisites,omitempty"`
+	// Owner the team or person in charge of the feature, notified when the feature is stale (metadata only)
+	Owner string `yaml:"owner,omitempty"`
+	// Type the type of feature: `release`, `experiment`, `ops` or `permission` (metadata only)
+	Type string `yaml:"type,omitempty"`
+	// Created the date when the feature was created, as `YYYY-MM-DD` (metadata only)
+	Created string `yaml:"created,omitempty"`
+	// Expires the date when the feature is expected to be removed, as `YYYY-MM-DD` (metadata only)
+	Expires string `yaml:"expires,omitempty"`
+	// Tags the tags of the feature, used to query the features across their groups (metadata only)
+	Tags []string `yaml:"tags,omitempty"`
+}
+
+// DateFormat the format of the dates in the feature files
+const DateFormat = "2006-01-02"
+
+// Variant a variant of a feature
+type Variant struct {
+	Name    string   `yaml:"name" json:"name"`
+	Weight  int      `yaml:"weight" json:"weight"`
+	Payload *Payload `yaml:"payload,omitempty" json:"payload,omitempty"`
+}
+
+// Payload the payload of a variant
+type Payload struct {
+	Type  string `yaml:"type" json:"type"`
+	Value string `yaml:"value" json:"value"`
+}
+
+// definitionFile the content of a feature file
+type definitionFile struct {
+	Features []Definition `yaml:"features"`
+}
+
+// LoadDefinitions reads the feature definitions in the `.yaml` and `.yml` files of the given directory (and its sub-directories).
+// Returns an error if a file cannot be read, or if a definition is invalid or defined more than once.
+func LoadDefinitions(dir string) ([]Definition, error) {
+	definitions := []Definition{}
+	sources := map[string]string{}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || (filepath.Ext(path) != ".yaml" && filepath.Ext(path) != ".yml") {
+			return nil
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return errs.Wrapf(err, "unable to read the feature file '%s'", path)
+		}
+		var f definitionFile
+		if err := yaml.UnmarshalStrict(content, &f); err != nil {
+			return errs.Wrapf(err, "unable to parse the feature file '%s'", path)
+		}
+		for _, d := range f.Features {
+			if err := d.Validate(); err != nil {
+				return errs.Wrapf(err, "invalid feature in '%s'", path)
+			}
+			if source, found := sources[d.Name]; found {
+				return errs.Errorf("feature '%s' is defined in both '%s' and '%s'", d.Name, source, path)
+			}
+			sources[d.Name] = path
+			definitions = append(definitions, d)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(definitions, func(i, j int) bool {
+		return definitions[i].Name < definitions[j].Name
+	})
+	return definitions, nil
+}
+
+// LoadMetadata returns the metadata of the features defined in the files of the given directory, by feature name
+func LoadMetadata(dir string) (map[string]featuretoggles.Metadata, error) {
+	definitions, err := LoadDefinitions(dir)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]featuretoggles.Metadata, len(definitions))
+	for _, d := range definitions {
+		// dates were already validated
+		result[d.Name], _ = d.Metadata()
+	}
+	return result, nil
+}
+
+// Validate returns an error if the definition has no name or a reserved name, an unknown level, no level nor emails, invalid prerequisites,
+// invalid variants, invalid tags or invalid metadata
+func (d Definition) Validate() error {
+	if strings.TrimSpace(d.Name) == "" {
+		return errs.New("missing feature name")
+	}
+	if featuretoggles.IsReservedName(d.Name) {
+		return errs.Errorf("feature '%s': reserved name (%s)", d.Name, strings.Join(featuretoggles.ReservedNames, ", "))
+	}
+	if d.Level == "" && len(d.Emails) == 0 {
+		return errs.Errorf("feature '%s': missing level or emails", d.Name)
+	}
+	if d.Level != "" && !featuretoggles.IsKnownLevel(d.Level) {
+		return errs.Errorf("feature '%s': unknown level '%s' (expected internal|experimental|beta|released)", d.Name, d.Level)
+	}
+	for _, email := range d.Emails {
+		if !strings.Contains(email, "@") {
+			return errs.Errorf("feature '%s': '%s' is not an email address", d.Name, email)
+		}
+	}
+	for _, p := range d.Prerequisites {
+		if strings.TrimSpace(p) == "" || strings.Contains(p, ",") || p == d.Name {
+			return errs.Errorf("feature '%s': invalid prerequisite '%s'", d.Name, p)
+		}
+	}
+	for _, v := range d.Variants {
+		if v.Name == "" || v.Weight < 0 {
+			return errs.Errorf("feature '%s': variants must have a name and a positive weight", d.Name)
+		}
+	}
+	if d.Type != "" && !featuretoggles.IsKnownType(d.Type) {
+		return errs.Errorf("feature '%s': unknown type '%s' (expected release|experiment|ops|permission)", d.Name, d.Type)
+	}
+	for _, tag := range d.Tags {
+		if !featuretoggles.IsValidTag(tag) {
+			return errs.Errorf("feature '%s': invalid tag '%s'", d.Name, tag)
+		}
+	}
+	if _, err := d.Metadata(); err != nil {
+		return errs.Wrapf(err, "feature '%s'", d.Name)
+	}
+	return nil
+}
+
+// Metadata returns the metadata of the feature. Returns an error if a date is not in the `YYYY-MM-DD` format.
+func (d Definition) Metadata() (featuretoggles.Metadata, error) {
+	m := featuretoggles.Metadata{
+		Owner: d.Owner,
+		Type:  d.Type,
+		Tags:  d.Tags,
+	}
+	var err error
+	if d.Created != "" {
+		if m.Created, err = time.Parse(DateFormat, d.Created); err != nil {
+			return featuretoggles.Metadata{}, errs.Errorf("invalid creation date '%s' (expected YYYY-MM-DD)", d.Created)
+		}
+	}
+	if d.Expires != "" {
+		if m.Expires, err = time.Parse(DateFormat, d.Expires); err != nil {
+			return featuretoggles.Metadata{}, errs.Errorf("invalid expiry date '%s' (expected YYYY-MM-DD)", d.Expires)
+		}
+	}
+	return m, nil
+}
+
+// Feature returns the feature in the format of the Unleash admin API
+func (d Definition) Feature() Feature {
+	enabled := true
+	if d.Enabled != nil {
+		enabled = *d.Enabled
+	}
+	f := Feature{
+		Name:        d.Name,
+		Description: d.Description,
+		Enabled:     enabled,
+		Strategies:  []Strategy{},
+		Variants:    d.Variants,
+	}
+	if d.Level != "" {
+		s := Strategy{
+			Name: featuretoggles.EnableByLevelStrategyName,
+			Parameters: map[string]interface{}{
+				featuretoggles.LevelParameter: strings.ToLower(d.Level),
+			},
+		}
+		if d.SelfEnrollment {
+			s.Parameters[featuretoggles.SelfEnrollmentParameter] = strconv.FormatBool(d.SelfEnrollment)
+		}
+		f.Strategies = append(f.Strategies, s)
+	}
+	if len(d.Emails) > 0 {
+		f.Strategies = append(f.Strategies, Strategy{
+			Name: featuretoggles.EnableByEmailsStrategyName,
+			Parameters: map[string]interface{}{
+				featuretoggles.EmailsParameter: strings.Join(d.Emails, ","),
+			},
+		})
+	}
+	if len(d.Prerequisites) > 0 {
+		// the prerequisites apply to the feature, whichever strategy enables it
+		for _, s := range f.Strategies {
+			s.Parameters[featuretoggles.PrerequisitesParameter] = strings.Join(d.Prerequisites, ",")
+		}
+	}
+	return f
+}
@@ -0,0 +1,221 @@
+package gitops_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/gitops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "features")
+	require.NoError(t, err)
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	}
+	return dir
+}
+
+func TestLoadDefinitions(t *testing.T) {
+
+	t.Run("ok", func(t *testing.T) {
+		// given
+		dir := writeFiles(t, map[string]string{
+			"planner.yaml": `
+features:
+- name: planner.board
+  description: Planner board
+  level: Beta
+  selfEnrollment: true
+  prerequisites: [planner]
+  owner: planner-team@foo.com
+  type: release
+  created: 2018-05-01
+  expires: 2018-09-01
+  tags: [planner, ui]
+- name: planner
+  description: Planner
+  level: released
+`,
+			"analyze/analyze.yml": `
+features:
+- name: analyze
+  description: Analyze
+  enabled: false
+  emails: [foo@foo.com, bar@foo.com]
+  variants:
+  - name: blue
+    weight: 500
+  - name: green
+    weight: 500
+    payload:
+      type: string
+      value: green
+`,
+			"README.md": "not a feature file",
+		})
+		defer os.RemoveAll(dir)
+		// when
+		definitions, err := gitops.LoadDefinitions(dir)
+		// then
+		require.NoError(t, err)
+		require.Len(t, definitions, 3)
+		assert.Equal(t, "analyze", definitions[0].Name)
+		assert.Equal(t, "planner", definitions[1].Name)
+		assert.Equal(t, "planner.board", definitions[2].Name)
+		// converted features
+		analyze := definitions[0].Feature()
+		assert.False(t, analyze.Enabled)
+		assert.Equal(t, []gitops.Strategy{
+			{
+				Name:       featuretoggles.EnableByEmailsStrategyName,
+				Parameters: map[string]interface{}{featuretoggles.EmailsParameter: "foo@foo.com,bar@foo.com"},
+			},
+		}, analyze.Strategies)
+		assert.Len(t, analyze.Variants, 2)
+		board := definitions[2].Feature()
+		assert.True(t, board.Enabled)
+		assert.Equal(t, []gitops.Strategy{
+			{
+				Name: featuretoggles.EnableByLevelStrategyName,
+				Parameters: map[string]interface{}{
+					featuretoggles.LevelParameter:          featuretoggles.BetaLevel,
+					featuretoggles.SelfEnrollmentParameter: "true",
+					featuretoggles.PrerequisitesParameter:  "planner",
+				},
+			},
+		}, board.Strategies)
+		// metadata
+		metadata, err := definitions[2].Metadata()
+		require.NoError(t, err)
+		assert.Equal(t, featuretoggles.Metadata{
+			Owner:   "planner-team@foo.com",
+			Type:    featuretoggles.ReleaseType,
+			Created: time.Date(2018, 5, 1, 0, 0, 0, 0, time.UTC),
+			Expires: time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC),
+			Tags:    []string{"planner", "ui"},
+		}, metadata)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		for name, files := range map[string]map[string]string{
+			"reserved name": {
+				"catalog.yaml": "features:\n- name: catalog\n  level: beta\n",
+			},
+			"unknown level": {
+				"planner.yaml": "features:\n- name: planner\n  level: beat\n",
+			},
+			"missing level and emails": {
+				"planner.yaml": "features:\n- name: planner\n",
+			},
+			"unknown field": {
+				"planner.yaml": "features:\n- name: planner\n  level: beta\n  color: blue\n",
+			},
+			"unknown type": {
+				"planner.yaml": "features:\n- name: planner\n  level: beta\n  type: release-toggle\n",
+			},
+			"invalid expiry date": {
+				"planner.yaml": "features:\n- name: planner\n  level: beta\n  expires: 01/09/2018\n",
+			},
+			"feature as its own prerequisite": {
+				"planner.yaml": "features:\n- name: planner\n  level: beta\n  prerequisites: [planner]\n",
+			},
+			"invalid tag": {
+				"planner.yaml": "features:\n- name: planner\n  level: beta\n  tags: [Needs Docs]\n",
+			},
+			"duplicate feature": {
+				"planner.yaml": "features:\n- name: planner\n  level: beta\n",
+				"other.yaml":   "features:\n- name: planner\n  level: released\n",
+			},
+		} {
+			t.Run(name, func(t *testing.T) {
+				// given
+				dir := writeFiles(t, files)
+				defer os.RemoveAll(dir)
+				// when
+				_, err := gitops.LoadDefinitions(dir)
+				// then
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestLoadMetadata(t *testing.T) {
+	// given
+	dir := writeFiles(t, map[string]string{
+		"planner.yaml": `
+features:
+- name: planner
+  level: released
+  owner: planner-team@foo.com
+  expires: 2018-09-01
+- name: analyze
+  level: beta
+`,
+	})
+	defer os.RemoveAll(dir)
+	// when
+	metadata, err := gitops.LoadMetadata(dir)
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, map[string]featuretoggles.Metadata{
+		"planner": {
+			Owner:   "planner-team@foo.com",
+			Expires: time.Date(2018, 9, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"analyze": {},
+	}, metadata)
+}
+
+func TestMetadataStore(t *testing.T) {
+	// given
+	dir := writeFiles(t, map[string]string{
+		"planner.yaml": "features:\n- name: planner\n  level: released\n  tags: [planner]\n",
+	})
+	defer os.RemoveAll(dir)
+	store, err := gitops.NewMetadataStore(dir)
+	require.NoError(t, err)
+
+	t.Run("get", func(t *testing.T) {
+		// when
+		m, found := store.GetMetadata("planner")
+		// then
+		require.True(t, found)
+		assert.Equal(t, []string{"planner"}, m.Tags)
+		_, found = store.GetMetadata("analyze")
+		assert.False(t, found)
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		// given
+		err := ioutil.WriteFile(filepath.Join(dir, "planner.yaml"), []byte("features:\n- name: planner\n  level: released\n  tags: [planner, ui]\n"), 0644)
+		require.NoError(t, err)
+		// when
+		err = store.Reload()
+		// then
+		require.NoError(t, err)
+		m, _ := store.GetMetadata("planner")
+		assert.Equal(t, []string{"planner", "ui"}, m.Tags)
+	})
+
+	t.Run("invalid files retained", func(t *testing.T) {
+		// given
+		err := ioutil.WriteFile(filepath.Join(dir, "planner.yaml"), []byte("features:\n- name: planner\n  level: beat\n"), 0644)
+		require.NoError(t, err)
+		// when
+		err = store.Reload()
+		// then
+		require.Error(t, err)
+		m, _ := store.GetMetadata("planner")
+		assert.Equal(t, []string{"planner", "ui"}, m.Tags)
+	})
+}
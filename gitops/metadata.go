@@ -0,0 +1,46 @@
+package gitops
+
+import (
+	"sync"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+)
+
+// MetadataStore provides the metadata of the features defined in the files of a directory
+type MetadataStore struct {
+	dir      string
+	mu       sync.RWMutex
+	metadata map[string]featuretoggles.Metadata
+}
+
+// NewMetadataStore returns a new store of the metadata of the features defined in the files of the given directory.
+// Returns an error if the feature files cannot be loaded.
+func NewMetadataStore(dir string) (*MetadataStore, error) {
+	s := MetadataStore{
+		dir: dir,
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// Reload loads the feature files again. The current metadata is retained if the files cannot be loaded.
+func (s *MetadataStore) Reload() error {
+	metadata, err := LoadMetadata(s.dir)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.metadata = metadata
+	return nil
+}
+
+// GetMetadata returns the metadata of the feature with the given name, and `false` if the feature is not defined
+func (s *MetadataStore) GetMetadata(name string) (featuretoggles.Metadata, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	m, found := s.metadata[name]
+	return m, found
+}
@@ -0,0 +1,240 @@
+package gitops
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+)
+
+// Feature a feature in the format of the Unleash admin API
+type Feature struct {
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Enabled     bool       `json:"enabled"`
+	Strategies  []Strategy `json:"strategies"`
+	Variants    []Variant  `json:"variants,omitempty"`
+}
+
+// Strategy a strategy of a feature in the format of the Unleash admin API
+type Strategy struct {
+	Name       string                 `json:"name"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+// Action the action to apply on a feature of the Unleash server
+type Action string
+
+const (
+	// Create the feature is defined but does not exist in the Unleash server
+	Create Action = "create"
+	// Update the feature in the Unleash server differs from its definition (it was changed by hand, or its definition was changed)
+	Update Action = "update"
+	// Archive the feature exists in the Unleash server but is not defined (only when pruning)
+	Archive Action = "archive"
+)
+
+// Change a change to apply on a feature of the Unleash server
+type Change struct {
+	Action  Action   `json:"action"`
+	Name    string   `json:"name"`
+	Diff    []string `json:"diff,omitempty"`
+	Feature *Feature `json:"-"`
+	// Current the feature in the Unleash server, if it exists
+	Current *Feature `json:"-"`
+}
+
+// Plan the changes to apply on the Unleash server so that its features match their definitions
+type Plan struct {
+	Changes []Change `json:"changes"`
+	// Unmanaged the features of the Unleash server which are not defined (and which are not archived since pruning is disabled)
+	Unmanaged []string `json:"unmanaged,omitempty"`
+}
+
+// IsEmpty returns `true` if the plan has no change
+func (p Plan) IsEmpty() bool {
+	return len(p.Changes) == 0
+}
+
+// Drift returns the features to update which were changed by hand in the Unleash server, i.e., which differ from the
+// state in which they were last applied (or found in sync with their definitions). The other features to update have
+// pending definitions, which were changed in the files but not applied yet. The features which are unknown in the state
+// are not reported, since a change in Unleash cannot be told from a pending definition.
+func (p Plan) Drift(state AppliedState) []Change {
+	result := []Change{}
+	for _, c := range p.Changes {
+		if c.Action != Update || c.Current == nil {
+			continue
+		}
+		if checksum, found := state[c.Name]; found && checksum != Checksum(*c.Current) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// String returns a human-readable description of the plan, in the style of a diff
+func (p Plan) String() string {
+	if p.IsEmpty() && len(p.Unmanaged) == 0 {
+		return "no change\n"
+	}
+	var b strings.Builder
+	for _, c := range p.Changes {
+		switch c.Action {
+		case Create:
+			fmt.Fprintf(&b, "+ %s\n", c.Name)
+		case Update:
+			fmt.Fprintf(&b, "~ %s\n", c.Name)
+		case Archive:
+			fmt.Fprintf(&b, "- %s\n", c.Name)
+		}
+		for _, d := range c.Diff {
+			fmt.Fprintf(&b, "    %s\n", d)
+		}
+	}
+	for _, name := range p.Unmanaged {
+		fmt.Fprintf(&b, "? %s (not defined, ignored)\n", name)
+	}
+	fmt.Fprintf(&b, "%d to create, %d to update, %d to archive\n", p.count(Create), p.count(Update), p.count(Archive))
+	return b.String()
+}
+
+func (p Plan) count(action Action) int {
+	result := 0
+	for _, c := range p.Changes {
+		if c.Action == action {
+			result++
+		}
+	}
+	return result
+}
+
+// ComputePlan returns the changes to apply on the current features of the Unleash server so that they match
+// the given definitions. The features which are not defined are archived if `prune` is `true`, reported as
+// unmanaged otherwise.
+func ComputePlan(definitions []Definition, current []Feature, prune bool) Plan {
+	desired := make([]Feature, len(definitions))
+	for i, d := range definitions {
+		desired[i] = d.Feature()
+	}
+	return ComputeFeaturePlan(desired, current, prune)
+}
+
+// ComputeFeaturePlan same as `ComputePlan`, with the desired features in the format of the Unleash admin API
+func ComputeFeaturePlan(desired []Feature, current []Feature, prune bool) Plan {
+	plan := Plan{
+		Changes: []Change{},
+	}
+	existing := make(map[string]Feature, len(current))
+	for _, f := range current {
+		existing[f.Name] = f
+	}
+	defined := make(map[string]bool, len(desired))
+	for i := range desired {
+		d := desired[i]
+		defined[d.Name] = true
+		actual, found := existing[d.Name]
+		if !found {
+			plan.Changes = append(plan.Changes, Change{Action: Create, Name: d.Name, Diff: describe(d), Feature: &d})
+			continue
+		}
+		if diff := compare(actual, d); len(diff) > 0 {
+			plan.Changes = append(plan.Changes, Change{Action: Update, Name: d.Name, Diff: diff, Feature: &d, Current: &actual})
+		}
+	}
+	names := make([]string, 0, len(current))
+	for _, f := range current {
+		if !defined[f.Name] {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if prune {
+			plan.Changes = append(plan.Changes, Change{Action: Archive, Name: name})
+		} else {
+			plan.Unmanaged = append(plan.Unmanaged, name)
+		}
+	}
+	return plan
+}
+
+// FromUnleashFeature returns the given feature (as read by the unleash client or in an export) in the format of the Unleash admin API
+func FromUnleashFeature(f unleashapi.Feature) Feature {
+	result := Feature{
+		Name:        f.Name,
+		Description: f.Description,
+		Enabled:     f.Enabled,
+		Strategies:  make([]Strategy, len(f.Strategies)),
+	}
+	for i, s := range f.Strategies {
+		result.Strategies[i] = Strategy{
+			Name:       s.Name,
+			Parameters: s.Parameters,
+		}
+	}
+	return result
+}
+
+// describe returns the attributes of a new feature
+func describe(f Feature) []string {
+	return []string{
+		fmt.Sprintf("description: %q", f.Description),
+		fmt.Sprintf("enabled: %t", f.Enabled),
+		fmt.Sprintf("strategies: %s", formatStrategies(f.Strategies)),
+		fmt.Sprintf("variants: %s", formatVariants(f.Variants)),
+	}
+}
+
+// compare returns the differences between the actual and the desired features
+func compare(actual, desired Feature) []string {
+	diff := []string{}
+	if actual.Description != desired.Description {
+		diff = append(diff, fmt.Sprintf("description: %q -> %q", actual.Description, desired.Description))
+	}
+	if actual.Enabled != desired.Enabled {
+		diff = append(diff, fmt.Sprintf("enabled: %t -> %t", actual.Enabled, desired.Enabled))
+	}
+	if a, d := formatStrategies(actual.Strategies), formatStrategies(desired.Strategies); a != d {
+		diff = append(diff, fmt.Sprintf("strategies: %s -> %s", a, d))
+	}
+	if a, d := formatVariants(actual.Variants), formatVariants(desired.Variants); a != d {
+		diff = append(diff, fmt.Sprintf("variants: %s -> %s", a, d))
+	}
+	return diff
+}
+
+// formatStrategies returns a canonical representation of the given strategies, in which the parameter values are
+// compared as strings (the Unleash server may return a number where the definition has a string)
+func formatStrategies(strategies []Strategy) string {
+	formatted := make([]string, 0, len(strategies))
+	for _, s := range strategies {
+		keys := make([]string, 0, len(s.Parameters))
+		for k := range s.Parameters {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		params := make([]string, 0, len(keys))
+		for _, k := range keys {
+			params = append(params, fmt.Sprintf("%s=%v", k, s.Parameters[k]))
+		}
+		formatted = append(formatted, fmt.Sprintf("%s(%s)", s.Name, strings.Join(params, ",")))
+	}
+	sort.Strings(formatted)
+	return "[" + strings.Join(formatted, " ") + "]"
+}
+
+func formatVariants(variants []Variant) string {
+	if len(variants) == 0 {
+		return "[]"
+	}
+	sorted := make([]Variant, len(variants))
+	copy(sorted, variants)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	b, _ := json.Marshal(sorted)
+	return string(b)
+}
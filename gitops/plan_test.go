@@ -0,0 +1,96 @@
+package gitops_test
+
+import (
+	"testing"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/gitops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputePlan(t *testing.T) {
+	// given
+	definitions := []gitops.Definition{
+		{Name: "analyze", Description: "Analyze", Level: featuretoggles.ReleasedLevel},
+		{Name: "planner", Description: "Planner", Level: featuretoggles.BetaLevel},
+		{Name: "planner.board", Description: "Planner board", Level: featuretoggles.ExperimentalLevel},
+	}
+	current := []gitops.Feature{
+		// unchanged
+		definitions[0].Feature(),
+		// changed by hand
+		{
+			Name:        "planner",
+			Description: "Planner",
+			Enabled:     true,
+			Strategies: []gitops.Strategy{
+				{Name: featuretoggles.EnableByLevelStrategyName, Parameters: map[string]interface{}{featuretoggles.LevelParameter: featuretoggles.ReleasedLevel}},
+			},
+		},
+		// not defined
+		{Name: "deploy", Description: "Deploy", Enabled: true},
+	}
+
+	t.Run("without pruning", func(t *testing.T) {
+		// when
+		plan := gitops.ComputePlan(definitions, current, false)
+		// then
+		require.Len(t, plan.Changes, 2)
+		assert.Equal(t, gitops.Update, plan.Changes[0].Action)
+		assert.Equal(t, "planner", plan.Changes[0].Name)
+		assert.Equal(t, []string{"strategies: [enableByLevel(level=released)] -> [enableByLevel(level=beta)]"}, plan.Changes[0].Diff)
+		assert.Equal(t, gitops.Create, plan.Changes[1].Action)
+		assert.Equal(t, "planner.board", plan.Changes[1].Name)
+		assert.Equal(t, []string{"deploy"}, plan.Unmanaged)
+		assert.Contains(t, plan.String(), "1 to create, 1 to update, 0 to archive")
+	})
+
+	t.Run("with pruning", func(t *testing.T) {
+		// when
+		plan := gitops.ComputePlan(definitions, current, true)
+		// then
+		require.Len(t, plan.Changes, 3)
+		assert.Equal(t, gitops.Archive, plan.Changes[2].Action)
+		assert.Equal(t, "deploy", plan.Changes[2].Name)
+		assert.Empty(t, plan.Unmanaged)
+	})
+
+	t.Run("no change", func(t *testing.T) {
+		// when
+		plan := gitops.ComputePlan(definitions[:1], current[:1], true)
+		// then
+		assert.True(t, plan.IsEmpty())
+		assert.Equal(t, "no change\n", plan.String())
+	})
+
+	t.Run("drift", func(t *testing.T) {
+		plan := gitops.ComputePlan(definitions, current, false)
+
+		t.Run("changed in unleash", func(t *testing.T) {
+			// given the feature was last applied as defined
+			state := gitops.AppliedState{"planner": gitops.Checksum(definitions[1].Feature())}
+			// when
+			drift := plan.Drift(state)
+			// then
+			require.Len(t, drift, 1)
+			assert.Equal(t, "planner", drift[0].Name)
+		})
+
+		t.Run("definition not applied yet", func(t *testing.T) {
+			// given the feature was last applied as it currently is
+			state := gitops.AppliedState{"planner": gitops.Checksum(current[1])}
+			// when
+			drift := plan.Drift(state)
+			// then
+			assert.Empty(t, drift)
+		})
+
+		t.Run("never applied", func(t *testing.T) {
+			// when
+			drift := plan.Drift(gitops.AppliedState{})
+			// then
+			assert.Empty(t, drift)
+		})
+	})
+}
@@ -0,0 +1,89 @@
+package gitops
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	errs "github.com/pkg/errors"
+)
+
+// AppliedState the checksums of the features as they were last applied on the Unleash server (or found in sync with
+// their definitions), by feature name. It tells the features changed by hand in Unleash from the definitions which
+// were changed in the files but not applied yet (see `Plan.Drift`).
+type AppliedState map[string]string
+
+// Checksum returns a checksum of the attributes of the given feature which are managed by the definitions
+func Checksum(f Feature) string {
+	h := sha256.New()
+	for _, attribute := range describe(f) {
+		fmt.Fprintln(h, attribute)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// Record updates the state with the current features of the Unleash server, against which the given plan was computed.
+// The features which match their definitions are recorded as-is, and the changes of the plan are recorded if they were
+// applied. Otherwise, the features to update keep their previous checksum, so that a drift is reported until it is fixed.
+func (s AppliedState) Record(plan Plan, current []Feature, applied bool) {
+	changed := make(map[string]bool, len(plan.Changes))
+	for _, c := range plan.Changes {
+		changed[c.Name] = true
+	}
+	for _, f := range current {
+		if !changed[f.Name] {
+			s[f.Name] = Checksum(f)
+		}
+	}
+	if !applied {
+		return
+	}
+	for _, c := range plan.Changes {
+		switch c.Action {
+		case Create, Update:
+			s[c.Name] = Checksum(*c.Feature)
+		case Archive:
+			delete(s, c.Name)
+		}
+	}
+}
+
+// LoadAppliedState reads the state in the given file. Returns an empty state if the file does not exist yet.
+func LoadAppliedState(path string) (AppliedState, error) {
+	state := AppliedState{}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	} else if err != nil {
+		return nil, errs.Wrapf(err, "unable to read the state file '%s'", path)
+	}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, errs.Wrapf(err, "unable to read the state file '%s'", path)
+	}
+	return state, nil
+}
+
+// Save writes the state in a temporary file which then replaces the given file, so that the file is never left half-written
+func (s AppliedState) Save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return errs.Wrap(err, "unable to save the state")
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return errs.Wrap(err, "unable to save the state")
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return errs.Wrap(err, "unable to save the state")
+	}
+	if err := tmp.Close(); err != nil {
+		return errs.Wrap(err, "unable to save the state")
+	}
+	return errs.Wrap(os.Rename(tmp.Name(), path), "unable to save the state")
+}
@@ -0,0 +1,75 @@
+package gitops_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/gitops"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppliedState(t *testing.T) {
+	// given
+	definitions := []gitops.Definition{
+		{Name: "analyze", Description: "Analyze", Level: featuretoggles.ReleasedLevel},
+		{Name: "planner", Description: "Planner", Level: featuretoggles.BetaLevel},
+		{Name: "planner.board", Description: "Planner board", Level: featuretoggles.ExperimentalLevel},
+	}
+	current := []gitops.Feature{
+		definitions[0].Feature(),
+		{Name: "planner", Description: "Planner", Enabled: true},
+		{Name: "deploy", Description: "Deploy", Enabled: true},
+	}
+	plan := gitops.ComputePlan(definitions, current, true)
+
+	t.Run("record without applying", func(t *testing.T) {
+		// given
+		state := gitops.AppliedState{"planner": "previous"}
+		// when
+		state.Record(plan, current, false)
+		// then
+		assert.Equal(t, gitops.AppliedState{
+			"analyze": gitops.Checksum(current[0]),
+			"planner": "previous",
+		}, state)
+	})
+
+	t.Run("record after applying", func(t *testing.T) {
+		// given
+		state := gitops.AppliedState{"planner": "previous", "deploy": "previous"}
+		// when
+		state.Record(plan, current, true)
+		// then
+		assert.Equal(t, gitops.AppliedState{
+			"analyze":       gitops.Checksum(current[0]),
+			"planner":       gitops.Checksum(definitions[1].Feature()),
+			"planner.board": gitops.Checksum(definitions[2].Feature()),
+		}, state)
+		// and no drift is reported on the next run
+		assert.Empty(t, plan.Drift(state))
+	})
+
+	t.Run("save and load", func(t *testing.T) {
+		// given
+		dir, err := ioutil.TempDir("", "state")
+		require.NoError(t, err)
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "state.json")
+		// when
+		state, err := gitops.LoadAppliedState(path)
+		// then
+		require.NoError(t, err)
+		assert.Empty(t, state)
+		// when
+		state["planner"] = gitops.Checksum(current[1])
+		require.NoError(t, state.Save(path))
+		loaded, err := gitops.LoadAppliedState(path)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, state, loaded)
+	})
+}
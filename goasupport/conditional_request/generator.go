@@ -74,6 +74,8 @@ var ignoredStructs []string
 func init() {
 	structPackages = make(map[string]string)
 	structPackages["UserFeature"] = "featuretoggles"
+	structPackages["FeatureCatalogLevel"] = "featuretoggles"
+	structPackages["FeatureTreeNode"] = "featuretoggles"
 }
 
 // WriteNames creates the names.txt file.
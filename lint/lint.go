@@ -0,0 +1,212 @@
+// Package lint verifies the feature definitions of an Unleash repository, so that the misconfigurations which the
+// toggles service silently ignores (unknown strategies, misspelled levels, malformed email lists, etc.) can be
+// detected before they reach the users, e.g. in a CI pipeline.
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+)
+
+// Rules of the issues reported by the linter
+const (
+	// StrategyRule the strategy is unknown or has invalid parameters
+	StrategyRule = "strategy"
+	// NoStrategyRule the feature has no strategy, hence it is never enabled
+	NoStrategyRule = "no-strategy"
+	// InternalEmailsRule the feature is reserved to internal users, but is also enabled for external email addresses
+	InternalEmailsRule = "internal-emails"
+	// NamingRule the name of the feature breaks the dotted group convention
+	NamingRule = "naming"
+	// PrerequisitesRule the prerequisites of the feature are invalid, unknown or form a cycle, hence the feature is never enabled
+	PrerequisitesRule = "prerequisites"
+)
+
+// Issue a problem found in the definition of a feature
+type Issue struct {
+	Feature  string `json:"feature"`
+	Strategy string `json:"strategy,omitempty"`
+	Rule     string `json:"rule"`
+	Message  string `json:"message"`
+}
+
+// String returns a human-readable description of the issue
+func (i Issue) String() string {
+	if i.Strategy == "" {
+		return fmt.Sprintf("%s: %s [%s]", i.Feature, i.Message, i.Rule)
+	}
+	return fmt.Sprintf("%s (%s): %s [%s]", i.Feature, i.Strategy, i.Message, i.Rule)
+}
+
+// Options the options of the linter
+type Options struct {
+	// InternalEmailDomains the domains of the email addresses of the internal users
+	// (`featuretoggles.DefaultInternalEmailDomains` if empty)
+	InternalEmailDomains []string
+}
+
+// Lint returns the issues found in the given features, sorted by feature name
+func Lint(features []unleashapi.Feature, options Options) []Issue {
+	domains := options.InternalEmailDomains
+	if len(domains) == 0 {
+		domains = featuretoggles.DefaultInternalEmailDomains
+	}
+	issues := []Issue{}
+	for _, f := range features {
+		issues = append(issues, lintFeature(f, domains)...)
+	}
+	for _, p := range featuretoggles.ValidatePrerequisites(features) {
+		issues = append(issues, Issue{
+			Feature:  p.Feature,
+			Strategy: p.Strategy,
+			Rule:     PrerequisitesRule,
+			Message:  p.Message,
+		})
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Feature < issues[j].Feature
+	})
+	return issues
+}
+
+func lintFeature(feature unleashapi.Feature, domains []string) []Issue {
+	issues := []Issue{}
+	// the names of the features must be dot-separated segments, so that `group` and `group.*` select a family of features
+	if featuretoggles.IsReservedName(feature.Name) {
+		issues = append(issues, Issue{
+			Feature: feature.Name,
+			Rule:    NamingRule,
+			Message: fmt.Sprintf("name is reserved by the service endpoints (%s)", strings.Join(featuretoggles.ReservedNames, ", ")),
+		})
+	} else if !featuretoggles.IsValidName(feature.Name) {
+		issues = append(issues, Issue{
+			Feature: feature.Name,
+			Rule:    NamingRule,
+			Message: "name must be dot-separated segments of letters, digits, '-' or '_' (e.g. 'planner.board')",
+		})
+	}
+	if len(feature.Strategies) == 0 {
+		issues = append(issues, Issue{
+			Feature: feature.Name,
+			Rule:    NoStrategyRule,
+			Message: "feature has no strategy, hence it is never enabled",
+		})
+	}
+	for _, p := range featuretoggles.ValidateFeature(feature) {
+		issues = append(issues, Issue{
+			Feature:  p.Feature,
+			Strategy: p.Strategy,
+			Rule:     StrategyRule,
+			Message:  p.Message + suggestLevel(feature, p.Strategy),
+		})
+	}
+	if external := externalEmails(feature, domains); isInternalOnly(feature) && len(external) > 0 {
+		issues = append(issues, Issue{
+			Feature:  feature.Name,
+			Strategy: featuretoggles.EnableByEmailsStrategyName,
+			Rule:     InternalEmailsRule,
+			Message:  fmt.Sprintf("feature is reserved to internal users, but is enabled for external email addresses: %s", strings.Join(external, ", ")),
+		})
+	}
+	return issues
+}
+
+// isInternalOnly returns `true` if all the `enableByLevel` strategies of the given feature (if any) have the `internal` level
+func isInternalOnly(feature unleashapi.Feature) bool {
+	found := false
+	for _, s := range feature.Strategies {
+		if s.Name != featuretoggles.EnableByLevelStrategyName {
+			continue
+		}
+		level, _ := s.Parameters[featuretoggles.LevelParameter].(string)
+		if !strings.EqualFold(strings.TrimSpace(level), featuretoggles.InternalLevel) {
+			return false
+		}
+		found = true
+	}
+	return found
+}
+
+// externalEmails returns the email addresses of the `enableByEmails` strategies of the given feature
+// which do not belong to the internal domains
+func externalEmails(feature unleashapi.Feature, domains []string) []string {
+	result := []string{}
+	for _, s := range feature.Strategies {
+		if s.Name != featuretoggles.EnableByEmailsStrategyName {
+			continue
+		}
+		emails, _ := s.Parameters[featuretoggles.EmailsParameter].(string)
+		for _, email := range strings.Split(emails, ",") {
+			if email = strings.TrimSpace(email); email != "" && !hasDomain(email, domains) {
+				result = append(result, email)
+			}
+		}
+	}
+	return result
+}
+
+func hasDomain(email string, domains []string) bool {
+	for _, domain := range domains {
+		if strings.HasSuffix(strings.ToLower(email), "@"+strings.ToLower(domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// suggestLevel returns a suggestion for a misspelled level in the given strategy of the given feature, if any
+func suggestLevel(feature unleashapi.Feature, strategy string) string {
+	if strategy != featuretoggles.EnableByLevelStrategyName {
+		return ""
+	}
+	for _, s := range feature.Strategies {
+		if s.Name != strategy {
+			continue
+		}
+		level, ok := s.Parameters[featuretoggles.LevelParameter].(string)
+		if !ok || featuretoggles.IsKnownLevel(level) {
+			continue
+		}
+		for _, known := range featuretoggles.Levels() {
+			if distance(strings.ToLower(strings.TrimSpace(level)), known) <= 2 {
+				return fmt.Sprintf(" (did you mean '%s'?)", known)
+			}
+		}
+	}
+	return ""
+}
+
+// distance returns the Levenshtein distance between the given strings
+func distance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minimum(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minimum(values ...int) int {
+	result := values[0]
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+	return result
+}
@@ -0,0 +1,116 @@
+package lint_test
+
+import (
+	"testing"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/lint"
+	"github.com/stretchr/testify/assert"
+)
+
+func levelStrategy(level interface{}) unleashapi.Strategy {
+	return unleashapi.Strategy{
+		Name: featuretoggles.EnableByLevelStrategyName,
+		Parameters: map[string]interface{}{
+			featuretoggles.LevelParameter: level,
+		},
+	}
+}
+
+func emailsStrategy(emails string) unleashapi.Strategy {
+	return unleashapi.Strategy{
+		Name: featuretoggles.EnableByEmailsStrategyName,
+		Parameters: map[string]interface{}{
+			featuretoggles.EmailsParameter: emails,
+		},
+	}
+}
+
+func TestLint(t *testing.T) {
+
+	t.Run("no issue", func(t *testing.T) {
+		// given
+		features := []unleashapi.Feature{
+			{Name: "planner", Strategies: []unleashapi.Strategy{levelStrategy(featuretoggles.ReleasedLevel)}},
+			{Name: "planner.board-view", Strategies: []unleashapi.Strategy{levelStrategy(featuretoggles.BetaLevel), emailsStrategy("foo@foo.com")}},
+			{Name: "planner.internal_tool", Strategies: []unleashapi.Strategy{levelStrategy(featuretoggles.InternalLevel), emailsStrategy("bar@redhat.com")}},
+		}
+		// when
+		issues := lint.Lint(features, lint.Options{})
+		// then
+		assert.Empty(t, issues)
+	})
+
+	t.Run("issues", func(t *testing.T) {
+		// given
+		features := []unleashapi.Feature{
+			{Name: "planner.board", Strategies: []unleashapi.Strategy{levelStrategy("beat")}},
+			{Name: "planner..list", Strategies: []unleashapi.Strategy{levelStrategy(featuretoggles.BetaLevel)}},
+			{Name: "deploy", Strategies: []unleashapi.Strategy{}},
+			{Name: "tree", Strategies: []unleashapi.Strategy{levelStrategy(featuretoggles.ReleasedLevel)}},
+			{Name: "analyze", Strategies: []unleashapi.Strategy{{Name: "enableByPlanet"}}},
+			{Name: "create", Strategies: []unleashapi.Strategy{emailsStrategy("foo@foo.com, bar")}},
+			{Name: "internal", Strategies: []unleashapi.Strategy{levelStrategy(featuretoggles.InternalLevel), emailsStrategy("foo@ibm.com,bar@redhat.com")}},
+			{Name: "planner.board.swimlanes", Strategies: []unleashapi.Strategy{
+				{
+					Name: featuretoggles.EnableByLevelStrategyName,
+					Parameters: map[string]interface{}{
+						featuretoggles.LevelParameter:         featuretoggles.BetaLevel,
+						featuretoggles.PrerequisitesParameter: "planner.boards",
+					},
+				},
+			}},
+		}
+		// when
+		issues := lint.Lint(features, lint.Options{InternalEmailDomains: []string{"redhat.com"}})
+		// then
+		assert.Equal(t, []lint.Issue{
+			{
+				Feature:  "analyze",
+				Strategy: "enableByPlanet",
+				Rule:     lint.StrategyRule,
+				Message:  "unknown strategy",
+			},
+			{
+				Feature:  "create",
+				Strategy: featuretoggles.EnableByEmailsStrategyName,
+				Rule:     lint.StrategyRule,
+				Message:  "invalid 'emails' parameter: 'bar' is not an email address (ignored)",
+			},
+			{
+				Feature: "deploy",
+				Rule:    lint.NoStrategyRule,
+				Message: "feature has no strategy, hence it is never enabled",
+			},
+			{
+				Feature:  "internal",
+				Strategy: featuretoggles.EnableByEmailsStrategyName,
+				Rule:     lint.InternalEmailsRule,
+				Message:  "feature is reserved to internal users, but is enabled for external email addresses: foo@ibm.com",
+			},
+			{
+				Feature: "planner..list",
+				Rule:    lint.NamingRule,
+				Message: "name must be dot-separated segments of letters, digits, '-' or '_' (e.g. 'planner.board')",
+			},
+			{
+				Feature:  "planner.board",
+				Strategy: featuretoggles.EnableByLevelStrategyName,
+				Rule:     lint.StrategyRule,
+				Message:  "invalid 'level' parameter: unknown level 'beat' (expected internal|experimental|beta|released) (did you mean 'beta'?)",
+			},
+			{
+				Feature:  "planner.board.swimlanes",
+				Strategy: featuretoggles.EnableByLevelStrategyName,
+				Rule:     lint.PrerequisitesRule,
+				Message:  "unknown prerequisite 'planner.boards'",
+			},
+			{
+				Feature: "tree",
+				Rule:    lint.NamingRule,
+				Message: "name is reserved by the service endpoints (catalog, export, problems, stale, tree)",
+			},
+		}, issues)
+	})
+}
@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 
 	authmiddleware "github.com/fabric8-services/fabric8-auth/goamiddleware"
 	"github.com/fabric8-services/fabric8-auth/log"
@@ -11,19 +15,36 @@ import (
 	"github.com/fabric8-services/fabric8-toggles-service/auth"
 	"github.com/fabric8-services/fabric8-toggles-service/configuration"
 	"github.com/fabric8-services/fabric8-toggles-service/controller"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/gitops"
 	"github.com/fabric8-services/fabric8-toggles-service/jsonapi"
+	"github.com/fabric8-services/fabric8-toggles-service/metrics"
+	"github.com/fabric8-services/fabric8-toggles-service/tlsconfig"
 	"github.com/fabric8-services/fabric8-toggles-service/token"
+	"github.com/fabric8-services/fabric8-toggles-service/tracing"
 	"github.com/goadesign/goa"
 	goalogrus "github.com/goadesign/goa/logging/logrus"
 	"github.com/goadesign/goa/middleware"
 	"github.com/goadesign/goa/middleware/gzip"
 	goajwt "github.com/goadesign/goa/middleware/security/jwt"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sirupsen/logrus"
 )
 
 func main() {
 
+	var configFilePath string
+	flag.StringVar(&configFilePath, "config", "", "Path to the YAML config file (overrides the F8_CONFIG_FILE_PATH environment variable)")
+	flag.Parse()
+
 	// Initialized configuration
-	config, err := configuration.GetData()
+	var config *configuration.Data
+	var err error
+	if configFilePath != "" {
+		config, err = configuration.NewData(configFilePath)
+	} else {
+		config, err = configuration.GetData()
+	}
 	if err != nil || config == nil {
 		log.Panic(nil, map[string]interface{}{
 			"err": err,
@@ -40,8 +61,41 @@ func main() {
 
 	service.WithLogger(goalogrus.New(log.Logger()))
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init(context.Background(), "fabric8-toggles-service", config)
+	if err != nil {
+		log.Panic(nil, map[string]interface{}{
+			"err": err,
+		}, "failed to initialize tracing")
+	}
+	// resources to release when the service stops, in reverse order
+	hooks := shutdownHooks{}
+	hooks.add("tracing", func(ctx context.Context) error {
+		return shutdownTracing(ctx)
+	})
+
+	// Reload the configuration when the config file changes or on SIGHUP
+	config.OnReload(func(config *configuration.Data) {
+		if level, err := logrus.ParseLevel(config.GetLogLevel()); err == nil {
+			log.Logger().SetLevel(level)
+		}
+	})
+	stopWatching, err := config.Watch()
+	if err != nil {
+		log.Panic(nil, map[string]interface{}{
+			"err": err,
+		}, "failed to watch the configuration")
+	}
+	hooks.add("configuration watcher", func(context.Context) error {
+		stopWatching()
+		return nil
+	})
+
 	// Mount middleware
 	service.Use(middleware.RequestID())
+	service.Use(tlsconfig.Middleware(config))
+	service.Use(tracing.Middleware())
+	service.Use(metrics.Middleware())
 	service.Use(gzip.Middleware(9))
 	service.Use(jsonapi.ErrorHandler(service, true))
 	service.Use(middleware.Recover())
@@ -64,12 +118,57 @@ func main() {
 	app.UseJWTMiddleware(service, goajwt.New(tokenParser.PublicKeys(), nil, app.NewJWTSecurity()))
 	service.Use(log.LogRequest(config.IsDeveloperModeEnabled()))
 
+	enrollmentStore, err := featuretoggles.NewFileEnrollmentStore(config.GetEnrollmentStorePath())
+	if err != nil {
+		log.Panic(nil, map[string]interface{}{
+			"err": err,
+		}, "failed to initialize the enrollment store")
+	}
+	togglesOptions := []featuretoggles.ClientOption{
+		featuretoggles.WithEnrollmentStore(enrollmentStore),
+		featuretoggles.WithInternalUserConfiguration(config),
+		featuretoggles.WithRecorder(metrics.Recorder{}),
+		featuretoggles.WithTracer(tracing.Tracer{}),
+	}
+	var featuresOptions []controller.FeaturesControllerOption
+	// the metadata of the features (e.g., their tags) is read from the feature files, if any
+	if dir := config.GetFeaturesMetadataDir(); dir != "" {
+		metadataStore, err := gitops.NewMetadataStore(dir)
+		if err != nil {
+			log.Panic(nil, map[string]interface{}{
+				"err": err,
+				"dir": dir,
+			}, "failed to load the metadata of the features")
+		}
+		config.OnReload(func(*configuration.Data) {
+			if err := metadataStore.Reload(); err != nil {
+				log.Error(nil, map[string]interface{}{
+					"err": err,
+					"dir": dir,
+				}, "failed to reload the metadata of the features")
+			}
+		})
+		togglesOptions = append(togglesOptions, featuretoggles.WithMetadataProvider(metadataStore))
+		featuresOptions = append(featuresOptions, controller.WithMetadataProvider(metadataStore))
+	}
+	togglesClient, err := featuretoggles.NewDefaultClient("fabric8-toggle-service", config, togglesOptions...)
+	if err != nil {
+		log.Panic(nil, map[string]interface{}{
+			"err": err,
+		}, "failed to create toogle client")
+	}
+	// closing the toggles client stops its background refresh and metrics goroutines
+	hooks.add("toggles client", func(context.Context) error {
+		return togglesClient.Close()
+	})
+
 	// Mount "features" controller
-	featuresCtrl := controller.NewFeaturesController(service, tokenParser, config)
+	featuresOptions = append(featuresOptions, controller.WithTogglesClient(togglesClient))
+	featuresCtrl := controller.NewFeaturesController(service, tokenParser, config, featuresOptions...)
 	app.MountFeaturesController(service, featuresCtrl)
 
 	// Mount "status" controller
-	statusCtrl := controller.NewStatusController(service, config)
+	statusCtrl := controller.NewStatusController(service, config, controller.WithTogglesState(togglesClient), controller.WithTokenParser(tokenParser))
 	app.MountStatusController(service, statusCtrl)
 
 	log.Logger().Infoln("Git Commit SHA: ", controller.Commit)
@@ -78,15 +177,92 @@ func main() {
 	log.Logger().Infoln("Dev mode:       ", config.IsDeveloperModeEnabled())
 
 	http.Handle("/api/", service.Mux)
+	http.Handle("/metrics", promhttp.Handler())
 	http.Handle("/favicon.ico", http.NotFoundHandler())
 
 	// Start http
-	if err := http.ListenAndServe(config.GetHTTPAddress(), nil); err != nil {
+	server := &http.Server{
+		Addr: config.GetHTTPAddress(),
+	}
+	serverErrors := make(chan error, 1)
+	if tlsconfig.IsEnabled(config) {
+		tlsConfig, err := tlsconfig.NewServerConfig(config)
+		if err != nil {
+			log.Panic(nil, map[string]interface{}{
+				"err": err,
+			}, "failed to initialize the TLS configuration")
+		}
+		server.TLSConfig = tlsConfig
+		log.Info(nil, map[string]interface{}{
+			"addr":       config.GetHTTPAddress(),
+			"client_ca":  config.GetTLSClientCAFile(),
+			"tls_min":    config.GetTLSMinVersion(),
+			"cert_file":  config.GetTLSCertFile(),
+			"mutual_tls": tlsConfig.ClientCAs != nil,
+		}, "serving HTTPS")
+		go func() {
+			// the certificate is provided by the TLS config
+			serverErrors <- server.ListenAndServeTLS("", "")
+		}()
+	} else {
+		go func() {
+			serverErrors <- server.ListenAndServe()
+		}()
+	}
+
+	// Wait for a termination signal (or a server failure), then drain the in-flight requests
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	select {
+	case sig := <-signals:
+		log.Info(nil, map[string]interface{}{
+			"signal": sig.String(),
+		}, "received signal, shutting down...")
+	case err := <-serverErrors:
 		log.Error(nil, map[string]interface{}{
 			"addr": config.GetHTTPAddress(),
 			"err":  err,
 		}, "unable to connect to server")
 		service.LogError("startup", "err", err)
 	}
+	// a single deadline for the draining of the requests and the release of the resources, which must be shorter
+	// than the grace period of the pod (see `terminationGracePeriodSeconds` in `openshift/app.yml`)
+	ctx, cancel := context.WithTimeout(context.Background(), config.GetHTTPShutdownTimeout())
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Error(nil, map[string]interface{}{
+			"err": err,
+		}, "unable to drain the in-flight requests before the shutdown timeout")
+	}
+	hooks.run(ctx)
+	log.Info(nil, map[string]interface{}{}, "service stopped")
+	// flush the logs, if written in a file
+	if f, ok := log.Logger().Out.(*os.File); ok {
+		f.Sync()
+	}
+}
+
+// shutdownHook a function to release a resource when the service stops
+type shutdownHook struct {
+	name string
+	run  func(ctx context.Context) error
+}
 
+// shutdownHooks the functions to release the resources when the service stops
+type shutdownHooks []shutdownHook
+
+func (h *shutdownHooks) add(name string, run func(ctx context.Context) error) {
+	*h = append(*h, shutdownHook{name: name, run: run})
+}
+
+// run runs the hooks in the reverse order of their registration, logging (but otherwise ignoring) the errors
+func (h shutdownHooks) run(ctx context.Context) {
+	for i := len(h) - 1; i >= 0; i-- {
+		if err := h[i].run(ctx); err != nil {
+			log.Error(nil, map[string]interface{}{
+				"err":      err,
+				"resource": h[i].name,
+			}, "unable to release resource during shutdown")
+		}
+	}
 }
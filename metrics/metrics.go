@@ -0,0 +1,173 @@
+// Package metrics provides the Prometheus metrics of the service, exposed on the `/metrics` endpoint
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "toggles"
+
+var (
+	evaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "feature_evaluations_total",
+		Help:      "Number of feature evaluations, by feature and result.",
+	}, []string{"feature", "enabled"})
+
+	lastEvaluation = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "feature_last_evaluation_timestamp_seconds",
+		Help:      "Time of the last evaluation of the features, by feature.",
+	}, []string{"feature"})
+
+	// lastEvaluations the time of the last evaluation of the features, by feature
+	lastEvaluations = map[string]time.Time{}
+	// lastEvaluationsMu protects `lastEvaluations`
+	lastEvaluationsMu sync.RWMutex
+	// startTime the time when the evaluations started to be recorded
+	startTime = time.Now()
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Duration of the HTTP requests, by controller, action and response status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"controller", "action", "status"})
+
+	conditionalRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_conditional_requests_total",
+		Help:      "Number of conditional requests (with an 'If-None-Match' header), by result: 'hit' if the response was '304 Not Modified', 'miss' otherwise.",
+	}, []string{"controller", "action", "result"})
+
+	authRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "auth_request_duration_seconds",
+		Help:      "Duration of the requests to the auth service, by operation.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+
+	authRequestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_request_errors_total",
+		Help:      "Number of failed requests to the auth service, by operation and response status ('error' if no response was received).",
+	}, []string{"operation", "status"})
+
+	unleashReady = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "unleash_client_ready",
+		Help:      "Whether the Unleash client is ready (1) or not (0).",
+	})
+
+	// lastRefresh the time of the last successful refresh of the features by the Unleash client, in Unix nanoseconds
+	lastRefresh int64
+
+	unleashSinceLastRefresh = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "unleash_client_seconds_since_last_refresh",
+		Help:      "Time since the last successful refresh of the features by the Unleash client (-1 if the features were never refreshed).",
+	}, func() float64 {
+		last := atomic.LoadInt64(&lastRefresh)
+		if last == 0 {
+			return -1
+		}
+		return time.Since(time.Unix(0, last)).Seconds()
+	})
+
+	unleashEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "unleash_client_events_total",
+		Help:      "Number of errors and warnings reported by the Unleash client, by type.",
+	}, []string{"type"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		evaluations,
+		lastEvaluation,
+		requestDuration,
+		conditionalRequests,
+		authRequestDuration,
+		authRequestErrors,
+		unleashReady,
+		unleashSinceLastRefresh,
+		unleashEvents,
+	)
+}
+
+// RecordEvaluation records the evaluation of the feature with the given name for a user
+func RecordEvaluation(feature string, enabled bool) {
+	evaluations.WithLabelValues(feature, strconv.FormatBool(enabled)).Inc()
+	now := time.Now()
+	lastEvaluation.WithLabelValues(feature).Set(float64(now.Unix()))
+	lastEvaluationsMu.Lock()
+	defer lastEvaluationsMu.Unlock()
+	lastEvaluations[feature] = now
+}
+
+// LastEvaluations returns the time of the last evaluation of the features, by feature, since `EvaluationsSince()`
+func LastEvaluations() map[string]time.Time {
+	lastEvaluationsMu.RLock()
+	defer lastEvaluationsMu.RUnlock()
+	result := make(map[string]time.Time, len(lastEvaluations))
+	for feature, t := range lastEvaluations {
+		result[feature] = t
+	}
+	return result
+}
+
+// EvaluationsSince returns the time since when the evaluations are recorded (i.e., the start time of the service)
+func EvaluationsSince() time.Time {
+	return startTime
+}
+
+// RecordAuthRequest records the duration and the outcome of a request to the auth service which started at the given time.
+// The request is counted as an error if no response was received or if the response status is not `200 OK`.
+func RecordAuthRequest(operation string, start time.Time, res *http.Response, err error) {
+	authRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil || res == nil {
+		authRequestErrors.WithLabelValues(operation, "error").Inc()
+	} else if res.StatusCode != http.StatusOK {
+		authRequestErrors.WithLabelValues(operation, strconv.Itoa(res.StatusCode)).Inc()
+	}
+}
+
+// SetUnleashReady records whether the Unleash client is ready
+func SetUnleashReady(ready bool) {
+	if ready {
+		unleashReady.Set(1)
+	} else {
+		unleashReady.Set(0)
+	}
+}
+
+// RecordUnleashRefresh records a successful refresh of the features by the Unleash client at the given time
+func RecordUnleashRefresh(t time.Time) {
+	atomic.StoreInt64(&lastRefresh, t.UnixNano())
+}
+
+// LastUnleashRefresh returns the time of the last successful refresh of the features by the Unleash client,
+// or the zero time if the features were never refreshed
+func LastUnleashRefresh() time.Time {
+	last := atomic.LoadInt64(&lastRefresh)
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
+
+// RecordUnleashError records an error reported by the Unleash client
+func RecordUnleashError() {
+	unleashEvents.WithLabelValues("error").Inc()
+}
+
+// RecordUnleashWarning records a warning reported by the Unleash client
+func RecordUnleashWarning() {
+	unleashEvents.WithLabelValues("warning").Inc()
+}
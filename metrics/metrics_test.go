@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/goadesign/goa"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func counterValue(t *testing.T, c prometheus.Counter) float64 {
+	m := &dto.Metric{}
+	require.NoError(t, c.Write(m))
+	return m.GetCounter().GetValue()
+}
+
+func TestRecordEvaluation(t *testing.T) {
+	// given
+	before := counterValue(t, evaluations.WithLabelValues("foo", "true"))
+	// when
+	RecordEvaluation("foo", true)
+	RecordEvaluation("foo", false)
+	// then
+	assert.Equal(t, before+1, counterValue(t, evaluations.WithLabelValues("foo", "true")))
+	last, found := LastEvaluations()["foo"]
+	require.True(t, found)
+	assert.False(t, last.Before(EvaluationsSince()))
+}
+
+func TestRecordAuthRequest(t *testing.T) {
+	// given
+	before := counterValue(t, authRequestErrors.WithLabelValues("show_user", "401"))
+	// when
+	RecordAuthRequest("show_user", time.Now(), &http.Response{StatusCode: http.StatusOK}, nil)
+	RecordAuthRequest("show_user", time.Now(), &http.Response{StatusCode: http.StatusUnauthorized}, nil)
+	// then
+	assert.Equal(t, before+1, counterValue(t, authRequestErrors.WithLabelValues("show_user", "401")))
+}
+
+func TestMiddleware(t *testing.T) {
+	// given
+	svc := goa.New("test")
+	ctrl := svc.NewController("FeaturesController")
+	ctrl.Use(Middleware())
+	handler := ctrl.MuxHandler("show", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		if req.Header.Get("If-None-Match") == "foo" {
+			rw.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+		rw.WriteHeader(http.StatusOK)
+		return nil
+	}, nil)
+	hits := conditionalRequests.WithLabelValues("FeaturesController", "show", "hit")
+	misses := conditionalRequests.WithLabelValues("FeaturesController", "show", "miss")
+	hitsBefore, missesBefore := counterValue(t, hits), counterValue(t, misses)
+	// when
+	for _, etag := range []string{"", "foo", "bar"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/features/foo", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		handler(httptest.NewRecorder(), req, url.Values{})
+	}
+	// then
+	assert.Equal(t, hitsBefore+1, counterValue(t, hits))
+	assert.Equal(t, missesBefore+1, counterValue(t, misses))
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goadesign/goa"
+)
+
+// Middleware returns a goa middleware which records the duration of the requests per controller and action,
+// along with the result of the conditional requests. It must be mounted before the error handler middleware,
+// so that the status of the error responses is recorded as well.
+func Middleware() goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			start := time.Now()
+			err := h(ctx, rw, req)
+			controller := goa.ContextController(ctx)
+			action := goa.ContextAction(ctx)
+			status := 0
+			if resp := goa.ContextResponse(ctx); resp != nil {
+				status = resp.Status
+			}
+			requestDuration.WithLabelValues(controller, action, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+			if req.Header.Get("If-None-Match") != "" {
+				result := "miss"
+				if status == http.StatusNotModified {
+					result = "hit"
+				}
+				conditionalRequests.WithLabelValues(controller, action, result).Inc()
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package metrics
+
+import "time"
+
+// Recorder records the metrics of the toggles client in the Prometheus collectors of this package
+// (see `featuretoggles.WithRecorder`)
+type Recorder struct{}
+
+// RecordUnleashRefresh records a successful refresh of the features by the Unleash client at the given time
+func (Recorder) RecordUnleashRefresh(t time.Time) {
+	RecordUnleashRefresh(t)
+}
+
+// SetUnleashReady records whether the Unleash client is ready
+func (Recorder) SetUnleashReady(ready bool) {
+	SetUnleashReady(ready)
+}
+
+// RecordUnleashError records an error reported by the Unleash client
+func (Recorder) RecordUnleashError() {
+	RecordUnleashError()
+}
+
+// RecordUnleashWarning records a warning reported by the Unleash client
+func (Recorder) RecordUnleashWarning() {
+	RecordUnleashWarning()
+}
+
+// RecordEvaluation records the evaluation of the feature with the given name for a user
+func (Recorder) RecordEvaluation(feature string, enabled bool) {
+	RecordEvaluation(feature, enabled)
+}
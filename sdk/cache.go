@@ -0,0 +1,94 @@
+package sdk
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheEntry the features returned by the service for a given request, along with
+// the values of the `ETag` and `Cache-Control` response headers
+type cacheEntry struct {
+	features []Feature
+	etag     string
+	expires  time.Time
+}
+
+// fresh returns `true` if the entry can be used without checking with the service
+func (e cacheEntry) fresh(now time.Time) bool {
+	return now.Before(e.expires)
+}
+
+// cache the local cache of the features, indexed by user and request
+type cache struct {
+	mu         sync.RWMutex
+	entries    map[string]cacheEntry
+	maxEntries int
+}
+
+func newCache(maxEntries int) *cache {
+	return &cache{
+		entries:    make(map[string]cacheEntry),
+		maxEntries: maxEntries,
+	}
+}
+
+func (c *cache) get(key string) (cacheEntry, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	e, found := c.entries[key]
+	return e, found
+}
+
+func (c *cache) put(key string, e cacheEntry, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, found := c.entries[key]; !found && len(c.entries) >= c.maxEntries {
+		// first, evict all entries which need to be revalidated anyways, then an arbitrary one if the cache is still full
+		for k, entry := range c.entries {
+			if !entry.fresh(now) {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = e
+}
+
+func (c *cache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+// cacheDirectives the directives of the `Cache-Control` response header which are relevant for this client
+type cacheDirectives struct {
+	noStore bool
+	noCache bool
+	maxAge  time.Duration
+}
+
+// parseCacheControl parses the given `Cache-Control` response header value
+func parseCacheControl(value string) cacheDirectives {
+	var result cacheDirectives
+	for _, directive := range strings.Split(value, ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store":
+			result.noStore = true
+		case directive == "no-cache":
+			result.noCache = true
+		case strings.HasPrefix(directive, "max-age="):
+			if seconds, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil && seconds > 0 {
+				result.maxAge = time.Duration(seconds) * time.Second
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,252 @@
+// Package sdk provides a client to the toggles service for other Go services. It wraps the client generated from the goa
+// design (see the `sdk/client` package) in a friendly API, forwards the JWT of the incoming request and keeps a local cache
+// of the features which honours the `ETag` and `Cache-Control` response headers returned by the service.
+package sdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/goasupport"
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/fabric8-services/fabric8-toggles-service/sdk/client"
+	goaclient "github.com/goadesign/goa/client"
+	goajwt "github.com/goadesign/goa/middleware/security/jwt"
+	errs "github.com/pkg/errors"
+)
+
+const defaultMaxCacheEntries = 10000
+
+// Feature a feature, evaluated for the user whose JWT was forwarded to the toggles service
+type Feature struct {
+	Name            string
+	Description     string
+	Enabled         bool
+	UserEnabled     bool
+	EnablementLevel string
+}
+
+// Client the client to the toggles service
+type Client struct {
+	serviceURL *url.URL
+	httpClient *http.Client
+	defaults   map[string]bool
+	cache      *cache
+	now        func() time.Time
+}
+
+// Option an option to customize the client during its initialization
+type Option func(*Client)
+
+// WithHTTPClient uses a custom http client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithDefaults configures the features (and whether they are enabled) to fall back to when the toggles
+// service cannot be reached and no cached value is available
+func WithDefaults(defaults map[string]bool) Option {
+	return func(c *Client) {
+		c.defaults = defaults
+	}
+}
+
+// WithMaxCacheEntries configures the maximum number of responses kept in the local cache
+func WithMaxCacheEntries(maxEntries int) Option {
+	return func(c *Client) {
+		c.cache = newCache(maxEntries)
+	}
+}
+
+// New initializes a new client to the toggles service at the given URL (e.g. "https://toggles.openshift.io")
+func New(serviceURL string, options ...Option) (*Client, error) {
+	u, err := url.Parse(serviceURL)
+	if err != nil {
+		return nil, errs.Wrapf(err, "invalid toggles service URL: '%s'", serviceURL)
+	}
+	c := Client{
+		serviceURL: u,
+		httpClient: http.DefaultClient,
+		defaults:   map[string]bool{},
+		cache:      newCache(defaultMaxCacheEntries),
+		now:        time.Now,
+	}
+	for _, opt := range options {
+		opt(&c)
+	}
+	return &c, nil
+}
+
+// IsEnabled returns `true` if the feature with the given name is enabled for the user whose JWT is in the given context.
+// If the service is unavailable and no cached value exists, the configured default applies.
+func (c *Client) IsEnabled(ctx context.Context, name string) bool {
+	f, err := c.Feature(ctx, name)
+	if err != nil {
+		log.Warn(ctx, map[string]interface{}{"err": err.Error(), "feature_name": name}, "unable to check if feature is enabled, using default value")
+		return c.defaults[name]
+	}
+	return f.UserEnabled
+}
+
+// Feature returns the feature with the given name, evaluated for the user whose JWT is in the given context
+func (c *Client) Feature(ctx context.Context, name string) (Feature, error) {
+	features, err := c.fetch(ctx, client.ShowFeaturesPath(name), url.Values{}, func(cl *client.Client, etag *string) (*http.Response, error) {
+		return cl.ShowFeatures(goasupport.ForwardContextRequestID(ctx), client.ShowFeaturesPath(name), nil, nil, nil, nil, etag)
+	}, func(cl *client.Client, res *http.Response) ([]Feature, error) {
+		single, err := cl.DecodeUserFeatureSingle(res)
+		if err != nil {
+			return nil, err
+		}
+		return []Feature{convertFeature(single.Data)}, nil
+	})
+	if err != nil {
+		if enabled, found := c.defaults[name]; found {
+			return Feature{Name: name, Enabled: enabled, UserEnabled: enabled}, nil
+		}
+		return Feature{}, err
+	}
+	return features[0], nil
+}
+
+// Features returns the features of the given group (i.e., the feature with the given name and all the features whose name
+// starts with the given name followed by a dot), evaluated for the user whose JWT is in the given context
+func (c *Client) Features(ctx context.Context, group string) ([]Feature, error) {
+	features, err := c.fetch(ctx, client.ListFeaturesPath(), url.Values{"group": []string{group}}, func(cl *client.Client, etag *string) (*http.Response, error) {
+		return cl.ListFeatures(goasupport.ForwardContextRequestID(ctx), client.ListFeaturesPath(), nil, []string{group}, nil, nil, nil, nil, nil, nil, nil, nil, etag)
+	}, func(cl *client.Client, res *http.Response) ([]Feature, error) {
+		list, err := cl.DecodeUserFeatureList(res)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]Feature, 0, len(list.Data))
+		for _, f := range list.Data {
+			result = append(result, convertFeature(f))
+		}
+		return result, nil
+	})
+	if err != nil {
+		if defaults := c.defaultFeatures(group); len(defaults) > 0 {
+			return defaults, nil
+		}
+		return nil, err
+	}
+	return features, nil
+}
+
+type requestFunc func(cl *client.Client, etag *string) (*http.Response, error)
+
+type decodeFunc func(cl *client.Client, res *http.Response) ([]Feature, error)
+
+// fetch returns the features from the local cache if the cached response is still fresh, or sends a conditional request
+// to the service otherwise. If the service is unavailable, the (stale) cached response is returned, if any.
+func (c *Client) fetch(ctx context.Context, path string, query url.Values, request requestFunc, decode decodeFunc) ([]Feature, error) {
+	key := cacheKey(ctx, path, query)
+	now := c.now()
+	entry, cached := c.cache.get(key)
+	if cached && entry.fresh(now) {
+		return entry.features, nil
+	}
+	var etag *string
+	if cached && entry.etag != "" {
+		etag = &entry.etag
+	}
+	res, err := request(c.newClient(ctx), etag)
+	if err != nil {
+		return c.stale(ctx, entry, cached, errs.Wrap(err, "unable to call the toggles service"))
+	}
+	defer res.Body.Close()
+	directives := parseCacheControl(res.Header.Get("Cache-Control"))
+	switch res.StatusCode {
+	case http.StatusNotModified:
+		entry.expires = now.Add(directives.maxAge)
+		c.cache.put(key, entry, now)
+		return entry.features, nil
+	case http.StatusOK:
+		features, err := decode(c.newClient(ctx), res)
+		if err != nil {
+			return nil, errs.Wrap(err, "unable to decode the response of the toggles service")
+		}
+		if directives.noStore {
+			c.cache.remove(key)
+			return features, nil
+		}
+		e := cacheEntry{
+			features: features,
+			etag:     res.Header.Get("ETag"),
+		}
+		if !directives.noCache {
+			e.expires = now.Add(directives.maxAge)
+		}
+		c.cache.put(key, e, now)
+		return features, nil
+	default:
+		err := errs.Errorf("unexpected response from the toggles service: %s", res.Status)
+		if res.StatusCode >= 500 {
+			return c.stale(ctx, entry, cached, err)
+		}
+		return nil, err
+	}
+}
+
+// stale returns the features of the given cached entry (if any) when the service is unavailable
+func (c *Client) stale(ctx context.Context, entry cacheEntry, cached bool, err error) ([]Feature, error) {
+	if !cached {
+		return nil, err
+	}
+	log.Warn(ctx, map[string]interface{}{"err": err.Error()}, "toggles service unavailable, using stale cached features")
+	return entry.features, nil
+}
+
+// defaultFeatures returns the configured default features which belong to the given group
+func (c *Client) defaultFeatures(group string) []Feature {
+	result := make([]Feature, 0)
+	for name, enabled := range c.defaults {
+		if name == group || strings.HasPrefix(name, group+".") {
+			result = append(result, Feature{Name: name, Enabled: enabled, UserEnabled: enabled})
+		}
+	}
+	return result
+}
+
+// newClient initializes a new generated client which forwards the JWT of the given context, if any
+func (c *Client) newClient(ctx context.Context) *client.Client {
+	cl := client.New(goaclient.HTTPClientDoer(c.httpClient))
+	cl.Host = c.serviceURL.Host
+	cl.Scheme = c.serviceURL.Scheme
+	if goajwt.ContextJWT(ctx) != nil {
+		cl.SetJWTSigner(goasupport.NewForwardSigner(ctx))
+	}
+	return cl
+}
+
+// cacheKey returns the key of the cached response for the user whose JWT is in the given context and the given request
+func cacheKey(ctx context.Context, path string, query url.Values) string {
+	user := "anonymous"
+	if token := goajwt.ContextJWT(ctx); token != nil {
+		hash := sha256.Sum256([]byte(token.Raw))
+		user = base64.StdEncoding.EncodeToString(hash[:])
+	}
+	return user + "|" + path + "?" + query.Encode()
+}
+
+func convertFeature(f *client.UserFeature) Feature {
+	result := Feature{
+		Name: f.ID,
+	}
+	if f.Attributes != nil {
+		result.Description = f.Attributes.Description
+		result.Enabled = f.Attributes.Enabled
+		result.UserEnabled = f.Attributes.UserEnabled
+		if f.Attributes.EnablementLevel != nil {
+			result.EnablementLevel = *f.Attributes.EnablementLevel
+		}
+	}
+	return result
+}
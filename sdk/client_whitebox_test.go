@@ -0,0 +1,117 @@
+package sdk
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const featuresResponse = `{
+  "data": [
+    {
+      "id": "foo.bar",
+      "type": "features",
+      "attributes": {
+        "description": "Foo Bar",
+        "enabled": true,
+        "user-enabled": true,
+        "enablement-level": "beta"
+      }
+    }
+  ]
+}`
+
+func TestFeatures(t *testing.T) {
+	// given
+	var requests, notModified int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", "private,max-age=60")
+		w.Header().Set("ETag", "etag-1")
+		if r.Header.Get("If-None-Match") == "etag-1" {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.api+json")
+		w.Write([]byte(featuresResponse))
+	}))
+	defer server.Close()
+	c, err := New(server.URL, WithDefaults(map[string]bool{"foo.baz": true}))
+	require.NoError(t, err)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	expected := []Feature{
+		{
+			Name:            "foo.bar",
+			Description:     "Foo Bar",
+			Enabled:         true,
+			UserEnabled:     true,
+			EnablementLevel: "beta",
+		},
+	}
+
+	t.Run("first call", func(t *testing.T) {
+		// when
+		features, err := c.Features(context.Background(), "foo")
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, expected, features)
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("fresh cached response", func(t *testing.T) {
+		// when
+		features, err := c.Features(context.Background(), "foo")
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, expected, features)
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("revalidated cached response", func(t *testing.T) {
+		// given
+		now = now.Add(2 * time.Minute)
+		// when
+		features, err := c.Features(context.Background(), "foo")
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, expected, features)
+		assert.Equal(t, 2, requests)
+		assert.Equal(t, 1, notModified)
+	})
+
+	t.Run("service down", func(t *testing.T) {
+		// given
+		server.Close()
+		now = now.Add(2 * time.Minute)
+
+		t.Run("stale cached response", func(t *testing.T) {
+			// when
+			features, err := c.Features(context.Background(), "foo")
+			// then
+			require.NoError(t, err)
+			assert.Equal(t, expected, features)
+		})
+
+		t.Run("defaults", func(t *testing.T) {
+			// when
+			enabled := c.IsEnabled(context.Background(), "foo.baz")
+			// then
+			assert.True(t, enabled)
+			assert.False(t, c.IsEnabled(context.Background(), "foo.unknown"))
+		})
+	})
+}
+
+func TestParseCacheControl(t *testing.T) {
+	assert.Equal(t, cacheDirectives{maxAge: 2 * time.Minute}, parseCacheControl("private,max-age=120"))
+	assert.Equal(t, cacheDirectives{}, parseCacheControl("private,max-age=0"))
+	assert.Equal(t, cacheDirectives{noStore: true}, parseCacheControl("no-store"))
+	assert.Equal(t, cacheDirectives{noCache: true, maxAge: time.Minute}, parseCacheControl("no-cache, max-age=60"))
+}
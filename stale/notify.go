@@ -0,0 +1,103 @@
+package stale
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	errs "github.com/pkg/errors"
+)
+
+// Notification the message sent to the owner of stale features. The `text` attribute makes it compatible with the
+// incoming webhooks of the common chat services.
+type Notification struct {
+	Owner  string  `json:"owner"`
+	Text   string  `json:"text"`
+	Issues []Issue `json:"issues"`
+}
+
+// Notifier sends the notifications to a webhook
+type Notifier struct {
+	url        string
+	httpClient *http.Client
+}
+
+// NotifierOption an option to configure the Notifier
+type NotifierOption func(*Notifier)
+
+// WithNotifierHTTPClient configures the notifier with the given HTTP client
+func WithNotifierHTTPClient(client *http.Client) NotifierOption {
+	return func(n *Notifier) {
+		n.httpClient = client
+	}
+}
+
+// NewNotifier returns a new notifier which posts the notifications to the given webhook URL
+func NewNotifier(url string, options ...NotifierOption) *Notifier {
+	n := &Notifier{
+		url:        url,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range options {
+		opt(n)
+	}
+	return n
+}
+
+// Notify sends a notification to the owner of each feature in the given issues (in the order of the owners), and returns
+// the notified owners. The issues of the features with no owner are ignored.
+func (n *Notifier) Notify(ctx context.Context, issues []Issue) ([]string, error) {
+	byOwner := ByOwner(issues)
+	owners := make([]string, 0, len(byOwner))
+	for owner := range byOwner {
+		owners = append(owners, owner)
+	}
+	sort.Strings(owners)
+	for i, owner := range owners {
+		if err := n.send(ctx, newNotification(owner, byOwner[owner])); err != nil {
+			return owners[:i], errs.Wrapf(err, "unable to notify '%s'", owner)
+		}
+	}
+	return owners, nil
+}
+
+func newNotification(owner string, issues []Issue) Notification {
+	lines := []string{fmt.Sprintf("%s, the following features are stale and should be removed:", owner)}
+	for _, i := range issues {
+		lines = append(lines, fmt.Sprintf("- %s: %s", i.Feature, i.Message))
+	}
+	return Notification{
+		Owner:  owner,
+		Text:   strings.Join(lines, "\n"),
+		Issues: issues,
+	}
+}
+
+func (n *Notifier) send(ctx context.Context, notification Notification) error {
+	b, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	res, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		content, _ := ioutil.ReadAll(io.LimitReader(res.Body, 1024))
+		return errs.Errorf("unexpected status '%s': %s", res.Status, strings.TrimSpace(string(content)))
+	}
+	return nil
+}
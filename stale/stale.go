@@ -0,0 +1,129 @@
+// Package stale reports the features which should be removed from the code and from the Unleash server: the features
+// past their expiry date, the features released to everyone for a long time, and the features which are not evaluated anymore.
+package stale
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+)
+
+// Rules of the stale features
+const (
+	// ExpiredRule the feature is past its expiry date
+	ExpiredRule = "expired"
+	// ReleasedRule the feature has been released to everyone for a long time
+	ReleasedRule = "released"
+	// UnusedRule the feature was not evaluated recently
+	UnusedRule = "unused"
+)
+
+// unusedMessage the message of the unused features: the evaluations are only known by the instance of the service which
+// recorded them, and are lost when it restarts
+const unusedMessage = "not evaluated in the last %d days (the evaluations are recorded per instance, since its last restart)"
+
+// Issue the reason why a feature is stale
+type Issue struct {
+	Feature string `json:"feature"`
+	Owner   string `json:"owner,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// String returns a human-readable description of the issue
+func (i Issue) String() string {
+	if i.Owner == "" {
+		return fmt.Sprintf("%s: %s [%s]", i.Feature, i.Message, i.Rule)
+	}
+	return fmt.Sprintf("%s (owner: %s): %s [%s]", i.Feature, i.Owner, i.Message, i.Rule)
+}
+
+// Options the options of the report
+type Options struct {
+	// Now the time of the report (the current time if zero)
+	Now time.Time
+	// ReleasedFor the duration after which a feature released to everyone is stale (disabled if zero). Since the Unleash
+	// server does not record when a feature was released, the duration is counted from the creation date of the feature.
+	ReleasedFor time.Duration
+	// UnusedFor the duration after which a feature which was not evaluated is stale (disabled if zero)
+	UnusedFor time.Duration
+	// LastEvaluations the time of the last evaluation of the features, by feature
+	LastEvaluations map[string]time.Time
+	// EvaluationsSince the time since when the evaluations are recorded: the features are not reported as unused
+	// if the evaluations were recorded for less than `UnusedFor`
+	EvaluationsSince time.Time
+}
+
+// Report returns the stale features among the given features, with their metadata (if any), sorted by feature and rule
+func Report(features []unleashapi.Feature, metadata map[string]featuretoggles.Metadata, options Options) []Issue {
+	now := options.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	issues := []Issue{}
+	for _, f := range features {
+		m := metadata[f.Name]
+		issue := func(rule, message string) Issue {
+			return Issue{Feature: f.Name, Owner: m.Owner, Type: m.Type, Rule: rule, Message: message}
+		}
+		if !m.Expires.IsZero() && now.After(m.Expires) {
+			issues = append(issues, issue(ExpiredRule, fmt.Sprintf("expired on %s (%d days ago)",
+				m.Expires.Format("2006-01-02"), days(now.Sub(m.Expires)))))
+		}
+		if options.ReleasedFor > 0 && m.Type != featuretoggles.OpsType && m.Type != featuretoggles.PermissionType && isReleased(f) {
+			created := m.Created
+			if created.IsZero() {
+				created = f.CreatedAt
+			}
+			if !created.IsZero() && now.Sub(created) >= options.ReleasedFor {
+				issues = append(issues, issue(ReleasedRule, fmt.Sprintf("released to everyone and created %d days ago", days(now.Sub(created)))))
+			}
+		}
+		if options.UnusedFor > 0 && now.Sub(options.EvaluationsSince) >= options.UnusedFor {
+			if last, found := options.LastEvaluations[f.Name]; !found {
+				issues = append(issues, issue(UnusedRule, fmt.Sprintf(unusedMessage, days(now.Sub(options.EvaluationsSince)))))
+			} else if now.Sub(last) >= options.UnusedFor {
+				issues = append(issues, issue(UnusedRule, fmt.Sprintf(unusedMessage, days(now.Sub(last)))))
+			}
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		return issues[i].Feature < issues[j].Feature
+	})
+	return issues
+}
+
+// ByOwner returns the given issues grouped by owner. The issues of the features with no owner are ignored.
+func ByOwner(issues []Issue) map[string][]Issue {
+	result := map[string][]Issue{}
+	for _, i := range issues {
+		if i.Owner != "" {
+			result[i.Owner] = append(result[i.Owner], i)
+		}
+	}
+	return result
+}
+
+// isReleased returns `true` if the feature is enabled for all the users
+func isReleased(f unleashapi.Feature) bool {
+	if !f.Enabled {
+		return false
+	}
+	for _, s := range f.Strategies {
+		if s.Name == "default" {
+			return true
+		}
+	}
+	return featuretoggles.ComputeEnablementLevel(context.Background(), f, false) == featuretoggles.ReleasedLevel
+}
+
+// days returns the given duration in days (rounded down)
+func days(d time.Duration) int {
+	return int(math.Floor(d.Hours() / 24))
+}
@@ -0,0 +1,120 @@
+package stale_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	unleashapi "github.com/Unleash/unleash-client-go/api"
+	"github.com/fabric8-services/fabric8-toggles-service/featuretoggles"
+	"github.com/fabric8-services/fabric8-toggles-service/stale"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func released(name string, createdAt time.Time) unleashapi.Feature {
+	return unleashapi.Feature{
+		Name:      name,
+		Enabled:   true,
+		CreatedAt: createdAt,
+		Strategies: []unleashapi.Strategy{
+			{
+				Name:       featuretoggles.EnableByLevelStrategyName,
+				Parameters: map[string]interface{}{featuretoggles.LevelParameter: featuretoggles.ReleasedLevel},
+			},
+		},
+	}
+}
+
+func TestReport(t *testing.T) {
+	// given
+	now := time.Date(2018, 9, 1, 12, 0, 0, 0, time.UTC)
+	features := []unleashapi.Feature{
+		released("planner", now.Add(-100*24*time.Hour)),
+		released("planner.board", now.Add(-10*24*time.Hour)),
+		released("kill-switch", now.Add(-100*24*time.Hour)),
+		{
+			Name:    "analyze",
+			Enabled: true,
+			Strategies: []unleashapi.Strategy{
+				{
+					Name:       featuretoggles.EnableByLevelStrategyName,
+					Parameters: map[string]interface{}{featuretoggles.LevelParameter: featuretoggles.BetaLevel},
+				},
+			},
+		},
+	}
+	metadata := map[string]featuretoggles.Metadata{
+		"planner.board": {
+			Owner:   "planner-team@foo.com",
+			Expires: time.Date(2018, 8, 1, 0, 0, 0, 0, time.UTC),
+		},
+		"kill-switch": {
+			Type: featuretoggles.OpsType,
+		},
+	}
+	options := stale.Options{
+		Now:         now,
+		ReleasedFor: 90 * 24 * time.Hour,
+		UnusedFor:   30 * 24 * time.Hour,
+		LastEvaluations: map[string]time.Time{
+			"planner":       now.Add(-time.Hour),
+			"planner.board": now.Add(-time.Hour),
+			"kill-switch":   now.Add(-time.Hour),
+			"analyze":       now.Add(-40 * 24 * time.Hour),
+		},
+		EvaluationsSince: now.Add(-60 * 24 * time.Hour),
+	}
+
+	t.Run("all rules", func(t *testing.T) {
+		// when
+		issues := stale.Report(features, metadata, options)
+		// then
+		assert.Equal(t, []stale.Issue{
+			{Feature: "analyze", Rule: stale.UnusedRule, Message: "not evaluated in the last 40 days (the evaluations are recorded per instance, since its last restart)"},
+			{Feature: "planner", Rule: stale.ReleasedRule, Message: "released to everyone and created 100 days ago"},
+			{Feature: "planner.board", Owner: "planner-team@foo.com", Rule: stale.ExpiredRule, Message: "expired on 2018-08-01 (31 days ago)"},
+		}, issues)
+	})
+
+	t.Run("evaluations not recorded for long enough", func(t *testing.T) {
+		// given
+		options := options
+		options.EvaluationsSince = now.Add(-24 * time.Hour)
+		options.LastEvaluations = map[string]time.Time{}
+		// when
+		issues := stale.Report(features, metadata, options)
+		// then
+		for _, i := range issues {
+			assert.NotEqual(t, stale.UnusedRule, i.Rule)
+		}
+	})
+}
+
+func TestNotify(t *testing.T) {
+	// given
+	notifications := []stale.Notification{}
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		var n stale.Notification
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&n))
+		notifications = append(notifications, n)
+	}))
+	defer server.Close()
+	issues := []stale.Issue{
+		{Feature: "analyze", Rule: stale.UnusedRule, Message: "not evaluated in the last 40 days (the evaluations are recorded per instance, since its last restart)"},
+		{Feature: "planner", Owner: "planner-team@foo.com", Rule: stale.ReleasedRule, Message: "released to everyone and created 100 days ago"},
+		{Feature: "planner.board", Owner: "planner-team@foo.com", Rule: stale.ExpiredRule, Message: "expired on 2018-08-01 (31 days ago)"},
+	}
+	// when
+	owners, err := stale.NewNotifier(server.URL).Notify(context.Background(), issues)
+	// then
+	require.NoError(t, err)
+	assert.Equal(t, []string{"planner-team@foo.com"}, owners)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "planner-team@foo.com", notifications[0].Owner)
+	assert.Len(t, notifications[0].Issues, 2)
+	assert.Contains(t, notifications[0].Text, "- planner.board: expired on 2018-08-01 (31 days ago)")
+}
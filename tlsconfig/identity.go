@@ -0,0 +1,60 @@
+package tlsconfig
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/fabric8-services/fabric8-auth/log"
+	"github.com/goadesign/goa"
+)
+
+// ServiceIdentityConfiguration the configuration of the services which are authenticated by their client certificate
+type ServiceIdentityConfiguration interface {
+	// GetTLSServiceIdentities returns the common names of the client certificates of the trusted services
+	GetTLSServiceIdentities() []string
+}
+
+type serviceIdentityKey struct{}
+
+// ContextWithServiceIdentity returns a new context with the given service identity
+func ContextWithServiceIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, serviceIdentityKey{}, identity)
+}
+
+// ContextServiceIdentity returns the identity of the service which sent the request, if it was authenticated by its certificate
+func ContextServiceIdentity(ctx context.Context) (string, bool) {
+	if ctx == nil {
+		return "", false
+	}
+	identity, ok := ctx.Value(serviceIdentityKey{}).(string)
+	return identity, ok
+}
+
+// Middleware returns a goa middleware which maps the verified client certificate of the request (if any) to a service
+// identity, if its common name belongs to the trusted services
+func Middleware(config ServiceIdentityConfiguration) goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			if identity, ok := serviceIdentity(config, req); ok {
+				log.Debug(ctx, map[string]interface{}{"service_identity": identity}, "request authenticated by client certificate")
+				ctx = ContextWithServiceIdentity(ctx, identity)
+			}
+			return h(ctx, rw, req)
+		}
+	}
+}
+
+// serviceIdentity returns the common name of the verified client certificate of the given request,
+// if it belongs to the trusted services
+func serviceIdentity(config ServiceIdentityConfiguration, req *http.Request) (string, bool) {
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+	cn := req.TLS.VerifiedChains[0][0].Subject.CommonName
+	for _, identity := range config.GetTLSServiceIdentities() {
+		if identity == cn {
+			return cn, true
+		}
+	}
+	return "", false
+}
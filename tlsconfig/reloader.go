@@ -0,0 +1,89 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/log"
+	errs "github.com/pkg/errors"
+)
+
+// checkInterval the minimum duration between 2 checks of the certificate files
+const checkInterval = 10 * time.Second
+
+// CertReloader provides the server certificate, and reloads it when the certificate or the key file changes
+type CertReloader struct {
+	certFile  string
+	keyFile   string
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	lastCheck time.Time
+}
+
+// NewCertReloader returns a new reloader of the certificate in the given files
+func NewCertReloader(certFile, keyFile string) (*CertReloader, error) {
+	r := CertReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+	}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GetCertificate returns the current certificate, after reloading it if its files changed since the last check.
+// The current certificate is retained if the new files cannot be loaded (e.g., while they are being replaced).
+func (r *CertReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	cert, lastCheck, modTime := r.cert, r.lastCheck, r.modTime
+	r.mu.RUnlock()
+	if time.Since(lastCheck) < checkInterval {
+		return cert, nil
+	}
+	if latest, err := r.latestModTime(); err == nil && latest.After(modTime) {
+		if err := r.reload(); err != nil {
+			log.Error(nil, map[string]interface{}{"err": err.Error(), "cert_file": r.certFile}, "unable to reload the server certificate")
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastCheck = time.Now()
+	return r.cert, nil
+}
+
+func (r *CertReloader) reload() error {
+	modTime, err := r.latestModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return errs.Wrapf(err, "unable to load the server certificate '%s' and key '%s'", r.certFile, r.keyFile)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.modTime = modTime
+	r.lastCheck = time.Now()
+	log.Info(nil, map[string]interface{}{"cert_file": r.certFile}, "loaded the server certificate")
+	return nil
+}
+
+// latestModTime returns the latest modification time of the certificate and key files
+func (r *CertReloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+	for _, f := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(f)
+		if err != nil {
+			return time.Time{}, errs.Wrapf(err, "unable to read the file '%s'", f)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
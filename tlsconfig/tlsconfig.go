@@ -0,0 +1,69 @@
+// Package tlsconfig provides the TLS configuration of the HTTP server, with an optional verification of the client
+// certificates (mutual TLS) so that other services can be authenticated by their certificate.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	errs "github.com/pkg/errors"
+)
+
+// Configuration the TLS configuration of the HTTP server
+type Configuration interface {
+	GetTLSCertFile() string
+	GetTLSKeyFile() string
+	GetTLSMinVersion() string
+	GetTLSClientCAFile() string
+}
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+}
+
+// IsEnabled returns `true` if the given configuration has a server certificate and key
+func IsEnabled(config Configuration) bool {
+	return config.GetTLSCertFile() != "" && config.GetTLSKeyFile() != ""
+}
+
+// ParseVersion returns the TLS version matching the given value (`1.0`, `1.1` or `1.2`)
+func ParseVersion(version string) (uint16, error) {
+	if v, found := versions[version]; found {
+		return v, nil
+	}
+	return 0, errs.Errorf("invalid TLS version: '%s' (expected '1.0', '1.1' or '1.2')", version)
+}
+
+// NewServerConfig returns the TLS configuration of the HTTP server, which reloads the server certificate when it changes.
+// If a client CA bundle is configured, the client certificates are verified when they are provided, but they are not
+// required since the users authenticate with a token.
+func NewServerConfig(config Configuration) (*tls.Config, error) {
+	minVersion, err := ParseVersion(config.GetTLSMinVersion())
+	if err != nil {
+		return nil, err
+	}
+	reloader, err := NewCertReloader(config.GetTLSCertFile(), config.GetTLSKeyFile())
+	if err != nil {
+		return nil, err
+	}
+	result := &tls.Config{
+		MinVersion:     minVersion,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if caFile := config.GetTLSClientCAFile(); caFile != "" {
+		pem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, errs.Wrapf(err, "unable to read the client CA bundle '%s'", caFile)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errs.Errorf("no certificate found in the client CA bundle '%s'", caFile)
+		}
+		result.ClientCAs = pool
+		result.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return result, nil
+}
@@ -0,0 +1,186 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fabric8-services/fabric8-toggles-service/tlsconfig"
+	"github.com/goadesign/goa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	certFile     string
+	keyFile      string
+	minVersion   string
+	clientCAFile string
+	identities   []string
+}
+
+func (c testConfig) GetTLSCertFile() string            { return c.certFile }
+func (c testConfig) GetTLSKeyFile() string             { return c.keyFile }
+func (c testConfig) GetTLSMinVersion() string          { return c.minVersion }
+func (c testConfig) GetTLSClientCAFile() string        { return c.clientCAFile }
+func (c testConfig) GetTLSServiceIdentities() []string { return c.identities }
+
+// newCertificate generates a new certificate with the given common name, signed by the given parent (self-signed if nil)
+func newCertificate(t *testing.T, cn string, parent *x509.Certificate, parentKey *rsa.PrivateKey) (*x509.Certificate, *rsa.PrivateKey, []byte, []byte) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-1 * time.Hour),
+		NotAfter:              time.Now().Add(1 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  parent == nil,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           nil,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return cert, key, certPEM, keyPEM
+}
+
+func writeFile(t *testing.T, dir, name string, content []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(path, content, 0600))
+	return path
+}
+
+func TestParseVersion(t *testing.T) {
+	v, err := tlsconfig.ParseVersion("1.2")
+	require.NoError(t, err)
+	assert.Equal(t, uint16(tls.VersionTLS12), v)
+	_, err = tlsconfig.ParseVersion("1.3")
+	assert.Error(t, err)
+}
+
+func TestCertReloader(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	_, _, certPEM, keyPEM := newCertificate(t, "first", nil, nil)
+	certFile := writeFile(t, dir, "tls.crt", certPEM)
+	keyFile := writeFile(t, dir, "tls.key", keyPEM)
+	r, err := tlsconfig.NewCertReloader(certFile, keyFile)
+	require.NoError(t, err)
+	first, err := r.GetCertificate(nil)
+	require.NoError(t, err)
+
+	t.Run("invalid files", func(t *testing.T) {
+		// when
+		_, err := tlsconfig.NewCertReloader(certFile, filepath.Join(dir, "unknown.key"))
+		// then
+		assert.Error(t, err)
+	})
+
+	t.Run("unchanged files", func(t *testing.T) {
+		// when
+		cert, err := r.GetCertificate(nil)
+		// then
+		require.NoError(t, err)
+		assert.Equal(t, first, cert)
+	})
+}
+
+func TestMutualTLS(t *testing.T) {
+	// given
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ca, caKey, caPEM, _ := newCertificate(t, "ca", nil, nil)
+	_, _, serverCertPEM, serverKeyPEM := newCertificate(t, "localhost", ca, caKey)
+	_, _, clientCertPEM, clientKeyPEM := newCertificate(t, "admin-console", ca, caKey)
+	_, _, otherCertPEM, otherKeyPEM := newCertificate(t, "other-service", ca, caKey)
+	config := testConfig{
+		certFile:     writeFile(t, dir, "tls.crt", serverCertPEM),
+		keyFile:      writeFile(t, dir, "tls.key", serverKeyPEM),
+		minVersion:   "1.2",
+		clientCAFile: writeFile(t, dir, "ca.crt", caPEM),
+		identities:   []string{"admin-console"},
+	}
+	serverTLSConfig, err := tlsconfig.NewServerConfig(config)
+	require.NoError(t, err)
+	svc := goa.New("test")
+	ctrl := svc.NewController("test")
+	ctrl.Use(tlsconfig.Middleware(config))
+	handler := ctrl.MuxHandler("show", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		identity, _ := tlsconfig.ContextServiceIdentity(ctx)
+		rw.Write([]byte(identity))
+		return nil
+	}, nil)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		handler(rw, req, url.Values{})
+	}))
+	server.TLS = serverTLSConfig
+	server.StartTLS()
+	defer server.Close()
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	call := func(certPEM, keyPEM []byte) (string, error) {
+		clientTLSConfig := &tls.Config{RootCAs: roots}
+		if certPEM != nil {
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			require.NoError(t, err)
+			clientTLSConfig.Certificates = []tls.Certificate{cert}
+		}
+		client := http.Client{Transport: &http.Transport{TLSClientConfig: clientTLSConfig}}
+		res, err := client.Get(server.URL)
+		if err != nil {
+			return "", err
+		}
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		return string(body), err
+	}
+
+	t.Run("trusted service", func(t *testing.T) {
+		identity, err := call(clientCertPEM, clientKeyPEM)
+		require.NoError(t, err)
+		assert.Equal(t, "admin-console", identity)
+	})
+
+	t.Run("untrusted service", func(t *testing.T) {
+		identity, err := call(otherCertPEM, otherKeyPEM)
+		require.NoError(t, err)
+		assert.Empty(t, identity)
+	})
+
+	t.Run("no client certificate", func(t *testing.T) {
+		identity, err := call(nil, nil)
+		require.NoError(t, err)
+		assert.Empty(t, identity)
+	})
+
+	t.Run("client certificate from unknown CA", func(t *testing.T) {
+		_, _, certPEM, keyPEM := newCertificate(t, "admin-console", nil, nil)
+		_, err := call(certPEM, keyPEM)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,42 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/goadesign/goa"
+	"go.opencensus.io/trace"
+)
+
+// Middleware returns a goa middleware which starts a span for each request, as a child of the span
+// propagated in the W3C trace context headers of the request (if any)
+func Middleware() goa.Middleware {
+	return func(h goa.Handler) goa.Handler {
+		return func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+			name := fmt.Sprintf("%s.%s", goa.ContextController(ctx), goa.ContextAction(ctx))
+			var span *trace.Span
+			if parent, ok := format.SpanContextFromRequest(req); ok {
+				ctx, span = trace.StartSpanWithRemoteParent(ctx, name, parent, trace.WithSpanKind(trace.SpanKindServer))
+			} else {
+				ctx, span = trace.StartSpan(ctx, name, trace.WithSpanKind(trace.SpanKindServer))
+			}
+			defer span.End()
+			span.AddAttributes(
+				trace.StringAttribute("http.method", req.Method),
+				trace.StringAttribute("http.target", req.URL.RequestURI()),
+			)
+			err := h(ctx, rw, req)
+			if resp := goa.ContextResponse(ctx); resp != nil {
+				span.AddAttributes(trace.Int64Attribute("http.status_code", int64(resp.Status)))
+				if resp.Status >= 500 {
+					span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: http.StatusText(resp.Status)})
+				}
+			}
+			if err != nil {
+				span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,167 @@
+// Package tracing provides the OpenCensus tracing of the service: the spans of the incoming requests, of the calls
+// to the auth service and of the feature evaluations, along with the W3C trace context propagation.
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/fabric8-services/fabric8-auth/log"
+	openzipkin "github.com/openzipkin/zipkin-go"
+	zipkinhttp "github.com/openzipkin/zipkin-go/reporter/http"
+	errs "github.com/pkg/errors"
+	"go.opencensus.io/exporter/zipkin"
+	"go.opencensus.io/plugin/ochttp/propagation/tracecontext"
+	"go.opencensus.io/trace"
+)
+
+const (
+	// ExporterNone disables the tracing
+	ExporterNone = "none"
+	// ExporterStdout writes the spans on the standard output
+	ExporterStdout = "stdout"
+	// ExporterFile writes the spans in a local file
+	ExporterFile = "file"
+	// ExporterZipkin sends the spans to a Zipkin collector (or any collector which accepts the Zipkin v2 API, e.g., Jaeger)
+	ExporterZipkin = "zipkin"
+)
+
+// format the W3C trace context propagation format, used for the incoming and outgoing requests
+var format = &tracecontext.HTTPFormat{}
+
+// Configuration the configuration of the tracing
+type Configuration interface {
+	GetTracingExporter() string
+	GetTracingFilePath() string
+	GetTracingZipkinEndpoint() string
+}
+
+// ShutdownFunc a function to flush the pending spans and release the resources of the exporter
+type ShutdownFunc func(ctx context.Context) error
+
+// Init registers the exporter of the given configuration, and samples all the spans.
+// The returned function must be called before the service exits.
+func Init(ctx context.Context, serviceName string, config Configuration) (ShutdownFunc, error) {
+	exporter, closer, err := newExporter(serviceName, config)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		log.Info(ctx, map[string]interface{}{}, "tracing is disabled")
+		return func(context.Context) error { return nil }, nil
+	}
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	trace.RegisterExporter(exporter)
+	log.Info(ctx, map[string]interface{}{"exporter": config.GetTracingExporter()}, "tracing is enabled")
+	return func(context.Context) error {
+		trace.UnregisterExporter(exporter)
+		if closer != nil {
+			return errs.Wrap(closer.Close(), "unable to shutdown the tracing exporter")
+		}
+		return nil
+	}, nil
+}
+
+// newExporter returns the span exporter of the given configuration (or `nil` if the tracing is disabled),
+// along with the resource to close on shutdown (which flushes the pending spans), if any
+func newExporter(serviceName string, config Configuration) (trace.Exporter, io.Closer, error) {
+	switch config.GetTracingExporter() {
+	case ExporterNone, "":
+		return nil, nil, nil
+	case ExporterStdout:
+		return NewJSONExporter(os.Stdout), nil, nil
+	case ExporterFile:
+		f, err := os.OpenFile(config.GetTracingFilePath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, errs.Wrapf(err, "unable to open the tracing file '%s'", config.GetTracingFilePath())
+		}
+		return NewJSONExporter(f), f, nil
+	case ExporterZipkin:
+		endpoint, err := openzipkin.NewEndpoint(serviceName, "")
+		if err != nil {
+			return nil, nil, errs.Wrap(err, "unable to create the local zipkin endpoint")
+		}
+		reporter := zipkinhttp.NewReporter(config.GetTracingZipkinEndpoint())
+		return zipkin.NewExporter(reporter, endpoint), reporter, nil
+	default:
+		return nil, nil, errs.Errorf("unknown tracing exporter: '%s'", config.GetTracingExporter())
+	}
+}
+
+// JSONExporter an exporter which writes the spans in JSON, one per line
+type JSONExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONExporter returns a new exporter which writes the spans in the given writer
+func NewJSONExporter(w io.Writer) *JSONExporter {
+	return &JSONExporter{encoder: json.NewEncoder(w)}
+}
+
+// jsonSpan the JSON representation of a span
+type jsonSpan struct {
+	TraceID    string                 `json:"trace_id"`
+	SpanID     string                 `json:"span_id"`
+	ParentID   string                 `json:"parent_id,omitempty"`
+	Name       string                 `json:"name"`
+	Start      time.Time              `json:"start"`
+	End        time.Time              `json:"end"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Status     *trace.Status          `json:"status,omitempty"`
+}
+
+// ExportSpan writes the given span
+func (e *JSONExporter) ExportSpan(s *trace.SpanData) {
+	span := jsonSpan{
+		TraceID:    hex.EncodeToString(s.TraceID[:]),
+		SpanID:     hex.EncodeToString(s.SpanID[:]),
+		Name:       s.Name,
+		Start:      s.StartTime,
+		End:        s.EndTime,
+		Attributes: s.Attributes,
+	}
+	if s.ParentSpanID != (trace.SpanID{}) {
+		span.ParentID = hex.EncodeToString(s.ParentSpanID[:])
+	}
+	if s.Code != trace.StatusCodeOK {
+		span.Status = &s.Status
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	// the spans are exported when they end, with no way to report an error: the span is lost
+	e.encoder.Encode(span)
+}
+
+// StartSpan starts a new span with the given name and attributes, as a child of the span in the given context (if any)
+func StartSpan(ctx context.Context, name string, attrs ...trace.Attribute) (context.Context, *trace.Span) {
+	ctx, span := trace.StartSpan(ctx, name)
+	span.AddAttributes(attrs...)
+	return ctx, span
+}
+
+// EndSpan records the given error (if any) on the given span, then ends it
+func EndSpan(span *trace.Span, err error) {
+	if err != nil {
+		span.SetStatus(trace.Status{Code: trace.StatusCodeUnknown, Message: err.Error()})
+	}
+	span.End()
+}
+
+// Tracer starts the spans of the toggles client with the registered exporter (see `featuretoggles.WithTracer`)
+type Tracer struct{}
+
+// StartSpan starts a new span with the given name and string attributes, and returns the function which ends it
+func (Tracer) StartSpan(ctx context.Context, name string, attributes map[string]string) (context.Context, func()) {
+	attrs := make([]trace.Attribute, 0, len(attributes))
+	for k, v := range attributes {
+		attrs = append(attrs, trace.StringAttribute(k, v))
+	}
+	ctx, span := StartSpan(ctx, name, attrs...)
+	return ctx, span.End
+}
@@ -0,0 +1,117 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/fabric8-services/fabric8-toggles-service/tracing"
+	"github.com/goadesign/goa"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opencensus.io/trace"
+)
+
+// inMemoryExporter an exporter which keeps the spans in memory, in the order in which they ended
+type inMemoryExporter struct {
+	mu    sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (e *inMemoryExporter) ExportSpan(s *trace.SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, s)
+}
+
+func (e *inMemoryExporter) GetSpans() []*trace.SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.spans
+}
+
+func setupTracing() *inMemoryExporter {
+	exporter := &inMemoryExporter{}
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	trace.RegisterExporter(exporter)
+	return exporter
+}
+
+func TestMiddleware(t *testing.T) {
+	// given
+	exporter := setupTracing()
+	defer trace.UnregisterExporter(exporter)
+	svc := goa.New("test")
+	ctrl := svc.NewController("FeaturesController")
+	ctrl.Use(tracing.Middleware())
+	handler := ctrl.MuxHandler("list", func(ctx context.Context, rw http.ResponseWriter, req *http.Request) error {
+		_, span := tracing.StartSpan(ctx, "featuretoggles.GetFeaturesByPattern")
+		span.End()
+		rw.WriteHeader(http.StatusOK)
+		return nil
+	}, nil)
+	req := httptest.NewRequest(http.MethodGet, "/api/features?group=foo", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	// when
+	handler(httptest.NewRecorder(), req, url.Values{})
+	// then
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "featuretoggles.GetFeaturesByPattern", spans[0].Name)
+	assert.Equal(t, "FeaturesController.list", spans[1].Name)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[1].TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[1].ParentSpanID.String())
+	assert.Equal(t, spans[1].SpanID, spans[0].ParentSpanID)
+}
+
+func TestTransport(t *testing.T) {
+	// given
+	exporter := setupTracing()
+	defer trace.UnregisterExporter(exporter)
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+	ctx, span := tracing.StartSpan(context.Background(), "auth.ShowUser")
+	defer span.End()
+	req, err := http.NewRequest(http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+	// when
+	res, err := tracing.WrapClient(nil).Do(req.WithContext(ctx))
+	// then
+	require.NoError(t, err)
+	res.Body.Close()
+	assert.Contains(t, traceparent, span.SpanContext().TraceID.String())
+	assert.Empty(t, req.Header.Get("traceparent"))
+}
+
+func TestJSONExporter(t *testing.T) {
+	// given
+	buf := &bytes.Buffer{}
+	exporter := tracing.NewJSONExporter(buf)
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+	trace.RegisterExporter(exporter)
+	defer trace.UnregisterExporter(exporter)
+	// when
+	ctx, parent := tracing.StartSpan(context.Background(), "FeaturesController.list")
+	_, child := tracing.StartSpan(ctx, "featuretoggles.GetFeaturesByPattern", trace.StringAttribute("pattern", "planner"))
+	child.End()
+	parent.End()
+	// then
+	decoder := json.NewDecoder(buf)
+	var span map[string]interface{}
+	require.NoError(t, decoder.Decode(&span))
+	assert.Equal(t, "featuretoggles.GetFeaturesByPattern", span["name"])
+	assert.Equal(t, parent.SpanContext().TraceID.String(), span["trace_id"])
+	assert.Equal(t, parent.SpanContext().SpanID.String(), span["parent_id"])
+	assert.Equal(t, map[string]interface{}{"pattern": "planner"}, span["attributes"])
+	require.NoError(t, decoder.Decode(&span))
+	assert.Equal(t, "FeaturesController.list", span["name"])
+}
@@ -0,0 +1,44 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opencensus.io/trace"
+)
+
+// Transport an HTTP transport which propagates the trace context of the requests in the W3C trace context headers
+type Transport struct {
+	Transport http.RoundTripper
+}
+
+// RoundTrip injects the trace context of the given request in its headers, then executes it using the underlying
+// transport (or `http.DefaultTransport` if none was set)
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	// do not modify the given request (see `http.RoundTripper`)
+	r := req.WithContext(req.Context())
+	r.Header = make(http.Header, len(req.Header))
+	for k, v := range req.Header {
+		r.Header[k] = v
+	}
+	if span := trace.FromContext(req.Context()); span != nil {
+		format.SpanContextToRequest(span.SpanContext(), r)
+	}
+	return transport.RoundTrip(r)
+}
+
+// WrapClient returns a copy of the given client whose transport propagates the trace context
+func WrapClient(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if _, ok := client.Transport.(*Transport); ok {
+		return client
+	}
+	c := *client
+	c.Transport = &Transport{Transport: client.Transport}
+	return &c
+}